@@ -0,0 +1,17 @@
+// gxe is a shortcut for gx that opens the generated command in $EDITOR and
+// executes what is saved.
+package main
+
+import (
+	"os"
+
+	"github.com/nealhardesty/gx/internal/cli"
+	"github.com/nealhardesty/gx/internal/version"
+)
+
+func main() {
+	os.Exit(cli.Run(cli.Options{
+		EditBeforeRun: true,
+		Version:       version.Version,
+	}))
+}
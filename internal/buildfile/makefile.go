@@ -0,0 +1,146 @@
+// Package buildfile inspects and extends Makefiles and Taskfiles.
+package buildfile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MakefileNames are the file names make looks for, in its search order.
+var MakefileNames = []string{"GNUmakefile", "makefile", "Makefile"}
+
+// TaskfileNames are the file names go-task looks for.
+var TaskfileNames = []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"}
+
+// Find returns the first of names that exists in the current directory, or "".
+func Find(names []string) string {
+	for _, name := range names {
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			return name
+		}
+	}
+	return ""
+}
+
+// MakeTarget is a rule parsed from Makefile text.
+type MakeTarget struct {
+	Name string
+	Deps []string
+}
+
+// MakeTargets returns the explicit rules in Makefile content. Pattern rules,
+// special targets (.PHONY, .DEFAULT_GOAL, ...) and variable assignments are skipped.
+func MakeTargets(content string) []MakeTarget {
+	var targets []MakeTarget
+	for _, line := range strings.Split(content, "\n") {
+		names, deps, ok := splitRule(line)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			if strings.HasPrefix(name, ".") || strings.ContainsAny(name, "%$") {
+				continue
+			}
+			targets = append(targets, MakeTarget{Name: name, Deps: deps})
+		}
+	}
+	return targets
+}
+
+// PhonyTargets returns the names declared in .PHONY lines.
+func PhonyTargets(content string) []string {
+	var phony []string
+	for _, line := range strings.Split(content, "\n") {
+		names, deps, ok := splitRule(line)
+		if ok && len(names) == 1 && names[0] == ".PHONY" {
+			phony = append(phony, deps...)
+		}
+	}
+	return phony
+}
+
+// splitRule splits a "targets: prerequisites" line. Recipe lines, comments,
+// and variable assignments (=, :=, ::=, ?=, +=) are not rules.
+func splitRule(line string) (names, deps []string, ok bool) {
+	if line == "" || line[0] == '\t' || line[0] == ' ' || line[0] == '#' {
+		return nil, nil, false
+	}
+	i := strings.Index(line, ":")
+	if i <= 0 || strings.Contains(line[:i], "=") {
+		return nil, nil, false
+	}
+	rest := strings.TrimLeft(line[i+1:], ":")
+	if strings.HasPrefix(rest, "=") {
+		return nil, nil, false
+	}
+	// Drop order-only separators and inline recipes ("target: ; cmd")
+	if j := strings.Index(rest, ";"); j >= 0 {
+		rest = rest[:j]
+	}
+	if j := strings.Index(rest, "#"); j >= 0 {
+		rest = rest[:j]
+	}
+	for _, dep := range strings.Fields(rest) {
+		if dep != "|" {
+			deps = append(deps, dep)
+		}
+	}
+	return strings.Fields(line[:i]), deps, true
+}
+
+// NormalizeMakeSnippet fixes common problems in a generated Makefile snippet:
+// recipe lines indented with spaces are re-indented with a tab, and targets
+// with a recipe but no .PHONY declaration get one. It returns the fixed
+// snippet and the names of the targets it defines.
+func NormalizeMakeSnippet(snippet string) (string, []string, error) {
+	var lines []string
+	var defined []string
+	inRule := false
+	for _, line := range strings.Split(strings.TrimSpace(snippet), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case trimmed == "":
+			inRule = false
+		case line[0] == ' ' || line[0] == '\t':
+			if !inRule {
+				return "", nil, fmt.Errorf("indented line outside a rule: %q", trimmed)
+			}
+			line = "\t" + trimmed
+		default:
+			names, _, ok := splitRule(line)
+			inRule = ok
+			if ok && !strings.HasPrefix(names[0], ".") {
+				defined = append(defined, names...)
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(defined) == 0 {
+		return "", nil, fmt.Errorf("no make target found")
+	}
+
+	fixed := strings.Join(lines, "\n")
+	declared := make(map[string]bool)
+	for _, name := range PhonyTargets(fixed) {
+		declared[name] = true
+	}
+	var missing []string
+	for _, name := range defined {
+		if !declared[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		fixed = ".PHONY: " + strings.Join(missing, " ") + "\n" + fixed
+	}
+	return fixed + "\n", defined, nil
+}
+
+// AppendMake appends a snippet to Makefile content, separated by a blank line.
+func AppendMake(content, snippet string) string {
+	if strings.TrimSpace(content) == "" {
+		return snippet
+	}
+	return strings.TrimRight(content, "\n") + "\n\n" + snippet
+}
@@ -0,0 +1,147 @@
+package buildfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewTaskfileHeader starts a Taskfile created from scratch.
+const NewTaskfileHeader = "version: '3'\n\ntasks:\n"
+
+// defaultTaskIndent is the indentation of task names under "tasks:".
+const defaultTaskIndent = "  "
+
+// TaskNames returns the task names defined under the top-level "tasks:" key.
+func TaskNames(content string) []string {
+	lines := strings.Split(content, "\n")
+	start, end, indent := tasksBlock(lines)
+	if start < 0 {
+		return nil
+	}
+	var names []string
+	for _, line := range lines[start+1 : end] {
+		if !strings.HasPrefix(line, indent) || len(line) == len(indent) {
+			continue
+		}
+		rest := line[len(indent):]
+		if rest[0] == ' ' || rest[0] == '#' || rest[0] == '-' {
+			continue
+		}
+		if name, _, ok := strings.Cut(rest, ":"); ok {
+			names = append(names, strings.Trim(strings.TrimSpace(name), `"'`))
+		}
+	}
+	return names
+}
+
+// tasksBlock locates the top-level "tasks:" key. It returns the index of the
+// key's line, the index just past its block, and the indentation of its
+// children; start is -1 if there is no tasks key.
+func tasksBlock(lines []string) (start, end int, indent string) {
+	start = -1
+	for i, line := range lines {
+		if start < 0 {
+			if strings.TrimRight(line, " ") == "tasks:" {
+				start = i
+			}
+			continue
+		}
+		if line == "" || line[0] == ' ' || line[0] == '#' {
+			if indent == "" && strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+				indent = line[:len(line)-len(strings.TrimLeft(line, " "))]
+			}
+			continue
+		}
+		return start, i, orDefault(indent)
+	}
+	if start < 0 {
+		return -1, -1, ""
+	}
+	return start, len(lines), orDefault(indent)
+}
+
+// orDefault returns indent, or the default task indentation when empty.
+func orDefault(indent string) string {
+	if indent == "" {
+		return defaultTaskIndent
+	}
+	return indent
+}
+
+// NormalizeTaskSnippet checks a generated task definition (a "name:" line
+// followed by indented keys including cmds) and returns it unindented along
+// with the task name.
+func NormalizeTaskSnippet(snippet string) (string, string, error) {
+	lines := strings.Split(strings.TrimRight(snippet, "\n\t "), "\n")
+	// Drop a leading "tasks:" wrapper if the model included one
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "tasks:" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return "", "", fmt.Errorf("no task found")
+	}
+
+	// Remove the common indentation of the task name line
+	first := lines[0]
+	prefix := first[:len(first)-len(strings.TrimLeft(first, " "))]
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+			continue
+		}
+		if !strings.HasPrefix(line, prefix) {
+			return "", "", fmt.Errorf("inconsistent indentation in task definition")
+		}
+		lines[i] = line[len(prefix):]
+	}
+
+	name, rest, ok := strings.Cut(lines[0], ":")
+	name = strings.Trim(strings.TrimSpace(name), `"'`)
+	if !ok || name == "" || strings.ContainsAny(name, " \t") || strings.TrimSpace(rest) != "" {
+		return "", "", fmt.Errorf("expected the task to start with a \"name:\" line, got %q", lines[0])
+	}
+	hasCmds := false
+	for _, line := range lines[1:] {
+		if line != "" && line[0] != ' ' {
+			return "", "", fmt.Errorf("expected exactly one task, found another top-level key: %q", line)
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "cmds:") {
+			hasCmds = true
+		}
+	}
+	if !hasCmds {
+		return "", "", fmt.Errorf("task %q has no cmds", name)
+	}
+	return strings.Join(lines, "\n") + "\n", name, nil
+}
+
+// AppendTask adds a task (as returned by NormalizeTaskSnippet) to Taskfile
+// content. The tasks key must be the last top-level key so the task can be
+// appended without rewriting the YAML.
+func AppendTask(content, task string) (string, error) {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	start, end, indent := tasksBlock(lines)
+	if start < 0 {
+		return "", fmt.Errorf("no top-level tasks: key found")
+	}
+	if end != len(lines) {
+		return "", fmt.Errorf("tasks: is not the last top-level key; add the task by hand")
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.Join(lines, "\n"))
+	b.WriteString("\n")
+	if end > start+1 {
+		b.WriteString("\n")
+	}
+	for _, line := range strings.Split(strings.TrimRight(task, "\n"), "\n") {
+		if line != "" {
+			b.WriteString(indent + line)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
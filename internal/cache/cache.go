@@ -0,0 +1,125 @@
+// Package cache provides a local TTL cache of generated commands keyed by request.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCacheFile is the default path for the cache file.
+	DefaultCacheFile = ".gxcache"
+	// DefaultTTL is the default lifetime of a cached response.
+	DefaultTTL = 24 * time.Hour
+)
+
+// Entry represents a single cached response.
+type Entry struct {
+	Response string    `json:"response"`
+	Created  time.Time `json:"created"`
+}
+
+// Store handles reading and writing the response cache.
+type Store struct {
+	path string
+	ttl  time.Duration
+}
+
+// NewStore creates a new cache store.
+// The TTL can be overridden with GX_CACHE_TTL (a Go duration such as "1h" or "30m").
+func NewStore() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	ttl := DefaultTTL
+	if envTTL := os.Getenv("GX_CACHE_TTL"); envTTL != "" {
+		if d, err := time.ParseDuration(envTTL); err == nil && d > 0 {
+			ttl = d
+		}
+	}
+
+	return &Store{
+		path: filepath.Join(homeDir, DefaultCacheFile),
+		ttl:  ttl,
+	}, nil
+}
+
+// Key builds a cache key from the parts that identify a request
+// (prompt, history context, model, and any settings that change the output).
+func Key(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		// Length-prefix each part so ("ab", "c") and ("a", "bc") differ
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// load reads the cache from disk, dropping expired entries.
+func (s *Store) load() map[string]Entry {
+	entries := make(map[string]Entry)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return entries
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// If the file is corrupted, start fresh
+		return make(map[string]Entry)
+	}
+
+	now := time.Now()
+	for key, entry := range entries {
+		if now.Sub(entry.Created) > s.ttl {
+			delete(entries, key)
+		}
+	}
+
+	return entries
+}
+
+// Get returns the cached response for key, if present and not expired.
+func (s *Store) Get(key string) (string, bool) {
+	entry, ok := s.load()[key]
+	if !ok || strings.TrimSpace(entry.Response) == "" {
+		return "", false
+	}
+	return entry.Response, true
+}
+
+// Put stores a response under key and saves the cache.
+func (s *Store) Put(key, response string) error {
+	entries := s.load()
+	entries[key] = Entry{
+		Response: response,
+		Created:  time.Now(),
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	return nil
+}
+
+// Clear removes the cache file.
+func (s *Store) Clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// maxAgentSteps bounds an agent run so a confused model can't loop forever.
+const maxAgentSteps = 25
+
+// runAgent implements `gx -a <goal>`: the model proposes one command at a
+// time, the user confirms it, and its exit status and output are fed back
+// until the model reports the goal complete.
+func runAgent(ctx context.Context, goal string, cfg gemini.Config, histMgr *history.Manager, opts execOptions) int {
+	cfg.Mode = gemini.ModeAgent
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return exitGenerationFailed
+	}
+	defer client.Close()
+
+	session, step, err := client.StartAgent(ctx, goal)
+	output := &tailBuffer{max: maxFeedbackOutput}
+	opts.stdoutTail = output
+	opts.stderrTail = output
+
+	for n := 1; ; n++ {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return generationStatus(err)
+		}
+		if step.Done {
+			fmt.Fprintf(os.Stderr, "\n--- Done ---\n%s\n", step.Summary)
+			return 0
+		}
+		if n > maxAgentSteps {
+			fmt.Fprintf(os.Stderr, "\n--- Stopped after %d steps without finishing ---\n", maxAgentSteps)
+			return 1
+		}
+
+		if n == 1 && step.Plan != "" {
+			fmt.Fprintf(os.Stderr, "Plan:\n%s\n", indentContinuation("  "+step.Plan, "  "))
+		}
+		fmt.Fprintf(os.Stderr, "\n--- Step %d: %s ---\n", n, step.Explanation)
+		fmt.Println(step.Command)
+		if step.Risk == gemini.RiskHigh {
+			fmt.Fprintln(os.Stderr, "Warning: the model rates this step high risk; review it before running")
+		}
+
+		// Ctrl-C or end of input ends the session rather than declining the
+		// step, which would only prompt the model for another one
+		answer, err := readPhrase("Run this step? [y/N/s(kip)/q] ")
+		if errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return exitInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		var feedback string
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			// A destructive step needs the typed confirmation, as with -y
+			if err := confirmDestructive(step.Command, step.Risk, opts.force); err != nil {
+				if errors.Is(err, errCancelled) {
+					fmt.Fprintln(os.Stderr, "Cancelled.")
+					return exitInterrupted
+				}
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				feedback = "The user declined this step; it was not run. Propose a different approach, or finish if there is none."
+				break
+			}
+			output.buf = nil
+			exitCode, err := executeCommand(ctx, step.Command, opts)
+			if errors.Is(err, errCancelled) {
+				fmt.Fprintln(os.Stderr, "Cancelled.")
+				return exitInterrupted
+			}
+			if err != nil {
+				feedback = fmt.Sprintf("The command could not be started: %v", err)
+			} else {
+				if exitCode == exitInterrupted {
+					fmt.Fprintln(os.Stderr, "Cancelled.")
+					return exitInterrupted
+				}
+				feedback = fmt.Sprintf("Exit status %d. Output:\n%s", exitCode, output.String())
+			}
+			if err := histMgr.AppendEntry(history.Entry{Prompt: goal, Response: step.Command}); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", err)
+			}
+		case "s", "skip":
+			feedback = "The user skipped this step; it was not run."
+		case "q", "quit":
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return exitInterrupted
+		default:
+			feedback = "The user declined this step; it was not run. Propose a different approach, or finish if there is none."
+		}
+
+		step, err = session.Next(ctx, feedback)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/ignore"
+	"github.com/nealhardesty/gx/internal/tools"
+)
+
+// spokenPrompt is the prompt for a request made entirely by --audio.
+const spokenPrompt = "The attached recording describes the task. Transcribe the spoken request and answer it."
+
+// attachPolicy limits which files @path may send: not those the
+// repository's .gxignore excludes or that fall outside its [tools] allow
+// list, and only with confirmation outside root.
+type attachPolicy struct {
+	// root is the repository root, or the working directory outside one
+	root   string
+	allow  []string
+	ignore *ignore.Matcher
+}
+
+// referencedFiles attaches the files a prompt names with @path, e.g.
+// "why does @Makefile rebuild everything". Only words naming an existing
+// file count, so "@daily" or "user@host" stay plain text; trailing
+// punctuation is ignored. Each file is attached once. Callers pass only the
+// user's own prompt, never piped input, which could name files to leak.
+func referencedFiles(prompt string, policy attachPolicy) ([]gemini.Attachment, error) {
+	var attachments []gemini.Attachment
+	seen := map[string]bool{}
+	for _, word := range strings.Fields(prompt) {
+		path, ok := strings.CutPrefix(word, "@")
+		if !ok {
+			continue
+		}
+		path = strings.TrimRight(path, ",.;:!?)\"'")
+		if path == "" || seen[path] {
+			continue
+		}
+		file := path
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				continue
+			}
+			file = filepath.Join(home, rest)
+		}
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+		seen[path] = true
+		if !policy.permits(path, file) {
+			continue
+		}
+		a, err := gemini.LoadFile(file, path)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// permits checks file, named in the prompt as path, against the policy,
+// explaining on stderr why a file is left out.
+func (p attachPolicy) permits(path, file string) bool {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return false
+	}
+	resolved := abs
+	if r, err := filepath.EvalSymlinks(abs); err == nil {
+		resolved = r
+	}
+	if p.ignore.Ignored(abs, false) || p.ignore.Ignored(resolved, false) {
+		fmt.Fprintf(os.Stderr, "Not attaching @%s: excluded by %s\n", path, ignore.FileName)
+		return false
+	}
+	if p.allow != nil && !tools.PathAllowed(abs, p.allow) {
+		fmt.Fprintf(os.Stderr, "Not attaching @%s: outside the paths this project allows\n", path)
+		return false
+	}
+	if p.root != "" && tools.PathAllowed(abs, []string{p.root}) {
+		return true
+	}
+	err = confirm(fmt.Sprintf("Send %s, which is outside %s, to the model? [y/N] ", resolved, p.root))
+	if err != nil && !errors.Is(err, errCancelled) {
+		fmt.Fprintf(os.Stderr, "Not attaching @%s: %v\n", path, err)
+	}
+	return err == nil
+}
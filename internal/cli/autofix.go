@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// maxFeedbackOutput is how much of an executed command's output is sent back
+// to the model; the end is kept since that's where errors are.
+const maxFeedbackOutput = 4 * 1024
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
+
+// runWithAutoFix executes command and, while it fails, asks the model for a
+// corrected command using the failure output and runs that instead, up to
+// attempts times. Each attempt is staged and saved to history.
+func runWithAutoFix(ctx context.Context, prompt, command string, cfg gemini.Config, histMgr *history.Manager, opts execOptions, attempts int) (int, error) {
+	stderr := &tailBuffer{max: maxFeedbackOutput}
+	opts.stderrTail = stderr
+
+	exitCode, err := executeCommand(ctx, command, opts)
+	if err != nil || exitCode == 0 {
+		return exitCode, err
+	}
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		return exitCode, fmt.Errorf("auto-fix: failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	// Earlier attempts stay in the context so the model doesn't repeat them
+	histContext := []history.Entry{{Prompt: prompt, Response: command}}
+	for attempt := 1; attempt <= attempts && exitCode != 0; attempt++ {
+		if exitCode == exitInterrupted {
+			// The user stopped it; that's not something to fix
+			return exitCode, nil
+		}
+
+		fmt.Fprintf(os.Stderr, "\n--- Auto-fix %d/%d: exit status %d ---\n", attempt, attempts, exitCode)
+		fixPrompt := fmt.Sprintf("That command failed with exit status %d. Its error output was:\n%s\nReturn a corrected command that accomplishes the original request: %s",
+			exitCode, strings.TrimSpace(stderr.String()), prompt)
+		result, err := client.GenerateResult(ctx, fixPrompt, histContext)
+		if err != nil {
+			return exitCode, fmt.Errorf("auto-fix: %w", err)
+		}
+		if result.Command == command {
+			fmt.Fprintln(os.Stderr, "The model returned the same command; giving up.")
+			return exitCode, nil
+		}
+		command = result.Command
+		fmt.Println(command)
+		if result.NeedsConfirmation {
+			fmt.Fprintf(os.Stderr, "Warning: the model rates this command %s risk; review it before running\n", riskLabel(result.Risk))
+		}
+
+		if err := histMgr.Stage(history.Staged{Command: command, Risk: result.Risk, Shell: opts.shell}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to stage command: %v\n", err)
+		}
+		if err := histMgr.AppendEntry(history.Entry{Prompt: prompt, Response: command, Undo: result.Undo, Risk: result.Risk, Tools: result.Tools}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", err)
+		}
+		histContext = append(histContext, history.Entry{Prompt: fixPrompt, Response: command})
+
+		if err := confirmDestructive(command, result.Risk, opts.force); err != nil {
+			return exitCode, err
+		}
+		if opts.preview {
+			if err := previewCommand(command, opts); err != nil {
+				return exitCode, err
+			}
+		}
+		fmt.Fprintln(os.Stderr, "--- Executing ---")
+		stderr.buf = nil
+		if exitCode, err = executeCommand(ctx, command, opts); err != nil {
+			return exitCode, err
+		}
+	}
+
+	if exitCode != 0 {
+		fmt.Fprintf(os.Stderr, "\n--- Auto-fix gave up after %d attempts (exit status %d) ---\n", attempts, exitCode)
+	}
+	return exitCode, nil
+}
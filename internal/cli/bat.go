@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/diff"
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// runBat implements `gx bat [-o file] <description>`: generate a cmd.exe
+// batch file with REM comments and %ERRORLEVEL% checks, with CRLF line
+// endings, and write it after confirmation.
+func runBat(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("bat", flag.ContinueOnError)
+	outputFlag := fs.String("o", "", "Write the script to this file (.bat or .cmd)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx bat [-o file] <description>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	description := strings.Join(fs.Args(), " ")
+	if description == "" {
+		fs.Usage()
+		return 1
+	}
+	path := *outputFlag
+	if ext := strings.ToLower(filepath.Ext(path)); path != "" && ext != ".bat" && ext != ".cmd" {
+		fmt.Fprintf(os.Stderr, "Error: %s must end in .bat or .cmd for cmd.exe to run it\n", path)
+		return 1
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = gemini.ModeBatchScript
+	cfg.NoTools = true
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	script, raw, err := generateChecked(ctx, env, client, description, nil, checkBatchScript)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if raw != "" {
+			fmt.Fprintln(os.Stderr, indentContinuation("  "+raw, "  "))
+		}
+		return 1
+	}
+
+	if path == "" {
+		fmt.Print(script)
+		return 0
+	}
+	return writeScript(path, script, 0644)
+}
+
+// checkBatchScript normalizes a generated batch file to CRLF line endings,
+// which cmd.exe needs to parse labels and blocks reliably, and rejects
+// scripts written for another shell.
+func checkBatchScript(result string) (string, error) {
+	script := strings.ReplaceAll(strings.TrimSpace(result), "\r\n", "\n")
+	if strings.HasPrefix(script, "#!") {
+		return "", fmt.Errorf("starts with a #! line; write a cmd.exe batch file")
+	}
+	for i, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			return "", fmt.Errorf("line %d: uses # for a comment; batch files use REM", i+1)
+		}
+	}
+	return strings.ReplaceAll(script, "\n", "\r\n") + "\r\n", nil
+}
+
+// writeScript shows a diff against an existing file at path and asks before
+// overwriting it, then writes script with the given permissions.
+func writeScript(path, script string, perm os.FileMode) int {
+	if existing, err := os.ReadFile(path); err == nil {
+		fmt.Fprint(os.Stderr, diff.Unified("a/"+path, "b/"+path, string(existing), script))
+		if err := confirm(fmt.Sprintf("Overwrite %s? [y/N] ", path)); err != nil {
+			if errors.Is(err, errCancelled) {
+				return 0
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if err := os.WriteFile(path, []byte(script), perm); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	return 0
+}
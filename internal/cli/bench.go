@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/ratelimit"
+)
+
+// benchResult is one model's results over the prompt set.
+type benchResult struct {
+	model     string
+	passed    int
+	latencies []time.Duration
+	usage     gemini.Usage
+	setupErr  error
+}
+
+// runBench implements `gx bench -f prompts.txt [-m model1,model2]`: run a
+// prompt set against each model and compare latency, token usage, and how
+// often the answer passed validation, to help choose GX_MODEL.
+func runBench(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fileFlag := fs.String("f", "", "File of prompts, one per line (# comments and blank lines are skipped; - for stdin)")
+	modelsFlag := fs.String("m", "", "Comma-separated models or aliases to compare (default: the configured model)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx bench -f prompts.txt [-m model1,model2]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *fileFlag == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 1
+	}
+
+	prompts, err := readPrompts(*fileFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(prompts) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no prompts in %s\n", *fileFlag)
+		return 1
+	}
+
+	var models []string
+	for _, m := range strings.Split(*modelsFlag, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, gemini.ResolveModel(m))
+		}
+	}
+	if len(models) == 0 {
+		models = []string{gemini.ResolveModel(env.clientCfg.Model)}
+	}
+
+	// Ctrl-C stops the run but still reports the prompts that finished
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	var results []benchResult
+	for _, model := range models {
+		results = append(results, benchModel(ctx, env, model, prompts))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	fmt.Fprintln(os.Stderr)
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "Cancelled; partial results:")
+	}
+	printBenchTable(results)
+	if ctx.Err() != nil {
+		return exitInterrupted
+	}
+	return 0
+}
+
+// readPrompts reads one prompt per line, skipping blank lines and # comments.
+func readPrompts(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open prompt file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	var prompts []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			prompts = append(prompts, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	return prompts, nil
+}
+
+// benchModel runs every prompt against one model. Escalation and clarifying
+// questions are off so each answer is the model's own, unattended.
+func benchModel(ctx context.Context, env *runEnv, model string, prompts []string) benchResult {
+	result := benchResult{model: model}
+
+	cfg := env.clientCfg
+	cfg.Model = model
+	cfg.EscalateTo = "off"
+	cfg.Clarify = nil
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[%s] Error: failed to create client: %v\n", model, err)
+		result.setupErr = err
+		return result
+	}
+	defer client.Close()
+
+	for i, prompt := range prompts {
+		start := time.Now()
+		res, err := client.GenerateResult(ctx, prompt, nil)
+		// Wait out the local rate limit rather than failing the prompt
+		var exceeded *ratelimit.ExceededError
+		for errors.As(err, &exceeded) && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "[%s] local rate limit reached, waiting %s\n", model, exceeded.RetryAfter.Round(time.Second))
+			select {
+			case <-ctx.Done():
+			case <-time.After(exceeded.RetryAfter):
+			}
+			start = time.Now()
+			res, err = client.GenerateResult(ctx, prompt, nil)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		elapsed := time.Since(start)
+		result.latencies = append(result.latencies, elapsed)
+
+		status := "ok"
+		detail := res.Command
+		if err != nil {
+			status, detail = "FAIL", err.Error()
+		} else {
+			result.passed++
+		}
+		fmt.Fprintf(os.Stderr, "[%s] %d/%d %5.1fs %-4s %s\n", model, i+1, len(prompts), elapsed.Seconds(), status, firstLineOf(detail))
+	}
+	result.usage = client.Usage()
+	return result
+}
+
+// printBenchTable prints one row per model: pass rate, median and slowest
+// latency, average tokens per prompt, and the requests made (tool calls and
+// corrections add requests).
+func printBenchTable(results []benchResult) {
+	width := len("MODEL")
+	for _, r := range results {
+		width = max(width, len(r.model))
+	}
+	fmt.Printf("%-*s  %-12s  %8s  %8s  %10s  %10s  %s\n", width, "MODEL", "PASSED", "MEDIAN", "MAX", "TOKENS", "REQUESTS", "HINT")
+	for _, r := range results {
+		if r.setupErr != nil {
+			fmt.Printf("%-*s  error: %v\n", width, r.model, r.setupErr)
+			continue
+		}
+		// A cancelled run is scored on the prompts that finished
+		prompts := len(r.latencies)
+		if prompts == 0 {
+			fmt.Printf("%-*s  no prompts finished\n", width, r.model)
+			continue
+		}
+		median, slowest := latencyStats(r.latencies)
+		passed := fmt.Sprintf("%d/%d (%d%%)", r.passed, prompts, 100*r.passed/prompts)
+		fmt.Printf("%-*s  %-12s  %7.1fs  %7.1fs  %10d  %10d  %s\n", width, r.model, passed,
+			median.Seconds(), slowest.Seconds(), r.usage.TotalTokens/prompts, r.usage.Requests, gemini.ModelHint(r.model))
+	}
+}
+
+// latencyStats returns the median and maximum of the latencies.
+func latencyStats(latencies []time.Duration) (median, slowest time.Duration) {
+	if len(latencies) == 0 {
+		return 0, 0
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2], sorted[len(sorted)-1]
+}
+
+// firstLineOf returns the first line of s, marking that more followed.
+func firstLineOf(s string) string {
+	line, rest, found := strings.Cut(strings.TrimSpace(s), "\n")
+	if found && strings.TrimSpace(rest) != "" {
+		return line + " ..."
+	}
+	return line
+}
@@ -3,42 +3,141 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/term"
+
+	"github.com/nealhardesty/gx/internal/cache"
+	"github.com/nealhardesty/gx/internal/envctx"
+	"github.com/nealhardesty/gx/internal/envset"
 	"github.com/nealhardesty/gx/internal/gemini"
 	"github.com/nealhardesty/gx/internal/history"
+	"github.com/nealhardesty/gx/internal/hosts"
+	"github.com/nealhardesty/gx/internal/ignore"
+	"github.com/nealhardesty/gx/internal/logging"
+	"github.com/nealhardesty/gx/internal/project"
+	"github.com/nealhardesty/gx/internal/ratelimit"
+	"github.com/nealhardesty/gx/internal/shell"
+	"github.com/nealhardesty/gx/internal/telemetry"
+	"github.com/nealhardesty/gx/internal/update"
+	"github.com/nealhardesty/gx/internal/version"
 )
 
 // Options configures the CLI behavior.
 type Options struct {
 	// ForceYolo automatically sets the -y flag to true
 	ForceYolo bool
+	// EditBeforeRun opens the generated command in $EDITOR and executes
+	// the saved result (gxe)
+	EditBeforeRun bool
 	// Version is the application version string
 	Version string
 }
 
-// Run executes the CLI with the given options and returns the exit code.
-func Run(opts Options) int {
+// Run executes the CLI with the given options and returns the exit code. A
+// panic is saved as a crash report rather than printed as a stack trace.
+func Run(opts Options) (code int) {
+	defer recoverCrash(opts.Version, &code)
+	return run(opts)
+}
+
+// run is Run without crash recovery.
+func run(opts Options) int {
 	// Define flags
 	executeFlag := flag.Bool("x", false, "Execute the staged command from ~/.gx")
-	yoloFlag := flag.Bool("y", opts.ForceYolo, "YOLO mode - generate and execute immediately")
-	verboseFlag := flag.Bool("v", false, "Verbose mode - include detailed comments")
+	yoloFlag := flag.Bool("y", opts.ForceYolo || opts.EditBeforeRun, "YOLO mode - generate and execute immediately")
+	verboseFlag := flag.Bool("v", false, "Verbose mode - trace tool calls to stderr")
+	commentsFlag := flag.Bool("comments", false, "Include explanatory comments in the generated command")
 	clearFlag := flag.Bool("c", false, "Clear history and staged commands")
+	dirFlag := flag.String("C", "", "Run as if gx was started in this directory: the model, its tools, and -x/-y execution all use it")
+	modelFlag := flag.String("m", "", "Model to use, or an alias: fast, smart (default: GX_MODEL or gemini-2.5-flash-lite)")
+	escalateFlag := flag.String("escalate-to", "", "Model or alias to retry once on when an answer fails validation, or off (default: GX_ESCALATE_TO or off)")
 	noToolsFlag := flag.Bool("n", false, "Disable LLM tools (no file system access)")
-	printPromptFlag := flag.Bool("p", false, "Print the prompt that would be sent to the LLM (don't send it)")
+	readOnlyToolsFlag := flag.Bool("tools-ro", false, "Only offer LLM tools that read local state (pwd, ls, stat, cat, ps, ...); no mutating or network tools")
+	printPromptFlag := flag.Bool("p", false, "Print the prompt and tool definitions that would be sent to the LLM (don't send it)")
+	noCacheFlag := flag.Bool("no-cache", false, "Bypass the response cache (~/.gxcache)")
+	noLocalFlag := flag.Bool("no-local", false, "Don't offer a matching command from history or built-in snippets while waiting for the model (or GX_NO_LOCAL_MATCH)")
+	debugFlag := flag.Bool("debug", false, "Debug logging to stderr (overrides GX_LOG_LEVEL)")
+	temperatureFlag := flag.Float64("temperature", -1, "Sampling temperature (default 0.1, or GX_TEMPERATURE)")
+	topPFlag := flag.Float64("top-p", -1, "Nucleus sampling threshold (default 0.95, or GX_TOP_P)")
+	topKFlag := flag.Int("top-k", 0, "Top-k sampling (default: model default, or GX_TOP_K)")
+	candidatesFlag := flag.Int("candidates", 0, "Generate N temperature-varied candidates and choose one (default 1, or GX_CANDIDATES)")
+	maxTokensFlag := flag.Int("max-tokens", 0, "Maximum output tokens (default: model default, or GX_MAX_OUTPUT_TOKENS)")
+	oneLinerFlag := flag.Bool("one-liner", false, "Require a single-line command (re-prompts if the model returns a script)")
+	altFlag := flag.Int("alt", 0, "Show N distinct approaches with tradeoff notes and choose one")
+	whyFlag := flag.Bool("why", false, "Print a one-sentence rationale for the command to stderr")
+	undoFlag := flag.Bool("undo", false, "Also generate an undo hint for the command (retrieve it later with gx undo)")
+	langFlag := flag.String("lang", "", "Language for comments and explanations, e.g. de (or GX_LANG); commands stay standard")
+	previewFlag := flag.Bool("preview", false, "Before -x/-y execution, show a diff of files the command would edit and ask to confirm")
+	interactiveFlag := flag.Bool("i", false, "Run -x/-y commands in an interactive shell so rc-file aliases and functions work (or GX_INTERACTIVE_SHELL)")
+	shellFlag := flag.String("shell", "", "Generate for this shell instead of the detected one: "+strings.Join(gemini.TargetShells, ", "))
+	osFlag := flag.String("os", "", "Generate for another operating system than this one: "+strings.Join(gemini.TargetOSes, ", ")+" (the command is not run)")
+	archFlag := flag.String("arch", "", "Generate for another CPU architecture, e.g. arm64 or amd64")
+	targetFlag := flag.String("target", "", "Generate for a host profile from ~/.gxhosts: its OS, shell, package manager, and tools (the command is not run)")
+	posixFlag := flag.Bool("posix", false, "Generate POSIX sh only (no bashisms), checked with dash when installed, and run -x/-y commands with /bin/sh")
+	aliasesFlag := flag.Bool("aliases", false, "Tell the model your shell's aliases and functions so commands can use them (or GX_ALIASES)")
+	diffFlag := flag.Bool("diff", false, "For commit and PR requests, send the staged diff along with its --stat summary (or GX_DIFF)")
+	awsFlag := flag.Bool("aws", false, "Tell the model the active AWS profile's account and region, from a cached aws sts get-caller-identity (or GX_AWS)")
 	versionFlag := flag.Bool("version", false, "Show version information")
+	agentFlag := flag.Bool("a", false, "Agent mode - work toward the goal one confirmed command at a time, feeding each result back to the model")
+	noClarifyFlag := flag.Bool("no-clarify", false, "Don't let the model ask a clarifying question about an ambiguous prompt; it assumes instead")
+	forceFlag := flag.Bool("force", false, "Run high-risk -x/-y commands without typing a confirmation phrase")
+	planFlag := flag.Bool("plan", false, "Print a numbered plan of every command the request needs, with risk levels, without staging or running any")
+	autoFixFlag := flag.Int("auto-fix", 0, "With -y, when the command fails, send its error output to the model and run the corrected command, up to N times")
+	execTimeoutFlag := flag.Duration("exec-timeout", 0, "Kill -x/-y commands that run longer than this, e.g. 30s (or GX_EXEC_TIMEOUT; default: no limit)")
+	exitOffsetFlag := flag.Int("exit-offset", 0, "Add N to a failing -x/-y command's exit status so scripts can tell it from gx's own exit codes (or GX_EXIT_OFFSET)")
+	var envFlag, envSetFlag, imageFlag, audioFlag stringList
+	flag.Var(&envFlag, "env", "Set KEY=VALUE in the environment of -x/-y commands (repeatable)")
+	flag.Var(&envSetFlag, "env-set", "Apply the named [set] from ~/.gxenv to -x/-y commands (repeatable)")
+	flag.Var(&imageFlag, "image", "Send an image (PNG, JPEG, WebP, HEIC) with the prompt, e.g. a screenshot of an error (repeatable)")
+	flag.Var(&audioFlag, "audio", "Send a short recording (WAV, MP3, M4A, ...) with the prompt, e.g. a voice memo describing the task; it can replace the prompt (repeatable)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "gx - Convert natural language to shell commands\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: gx [options] [prompt] [-]\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: gx [options] [prompt] [-]\n")
+		fmt.Fprintf(os.Stderr, "       gx [options]              (no prompt: write it in $VISUAL/$EDITOR)\n")
+		fmt.Fprintf(os.Stderr, "       gx [options] <subcommand> [args]\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nSubcommands:\n")
+		fmt.Fprintf(os.Stderr, "  bat [-o file] <description>\n")
+		fmt.Fprintf(os.Stderr, "                  Generate a cmd.exe batch file (REM comments, error checks, CRLF)\n")
+		fmt.Fprintf(os.Stderr, "  bench -f prompts.txt [-m model1,model2]\n")
+		fmt.Fprintf(os.Stderr, "                  Compare models' pass rate, latency, and tokens on a prompt set\n")
+		fmt.Fprintf(os.Stderr, "  cron [--systemd] [--install] <description>\n")
+		fmt.Fprintf(os.Stderr, "                  Generate a validated crontab line (or systemd timer)\n")
+		fmt.Fprintf(os.Stderr, "  docker [--compose] [-o file] <description>\n")
+		fmt.Fprintf(os.Stderr, "                  Generate a Dockerfile (or compose file) for this project\n")
+		fmt.Fprintf(os.Stderr, "  doctor          Check credentials, project, network, model, shell, and history\n")
+		fmt.Fprintf(os.Stderr, "  expand [-o file] [-]\n")
+		fmt.Fprintf(os.Stderr, "                  Rewrite the staged command (or stdin) as a documented script\n")
+		fmt.Fprintf(os.Stderr, "  init [--key chord] powershell\n")
+		fmt.Fprintf(os.Stderr, "                  Print a PowerShell module (Invoke-Gx, Ctrl+G) to load from $PROFILE\n")
+		fmt.Fprintf(os.Stderr, "  last [n]        Print the nth most recent generated command (default: the latest)\n")
+		fmt.Fprintf(os.Stderr, "  man <command>   Summarize a man page (or --help output) with examples\n")
+		fmt.Fprintf(os.Stderr, "  models          List Gemini models available in the project/region\n")
+		fmt.Fprintf(os.Stderr, "  replay <logfile> Resend a request from the prompt log and diff the new response\n")
+		fmt.Fprintf(os.Stderr, "  target [--taskfile] [-f file] <description>\n")
+		fmt.Fprintf(os.Stderr, "                  Generate a Makefile target (or Taskfile task) and append it\n")
+		fmt.Fprintf(os.Stderr, "  trust           Review this repository's .gxrc and trust it so its settings apply\n")
+		fmt.Fprintf(os.Stderr, "  undo            Stage the undo hint saved with the last --undo command\n")
+		fmt.Fprintf(os.Stderr, "  why - [question] Explain or diagnose piped input (logs, stack traces, diffs)\n")
 		fmt.Fprintf(os.Stderr, "\nStdin Support:\n")
 		fmt.Fprintf(os.Stderr, "  -               Read additional input from stdin and append to prompt\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -46,12 +145,41 @@ func Run(opts Options) int {
 		fmt.Fprintf(os.Stderr, "  gx -x                    # Execute staged command\n")
 		fmt.Fprintf(os.Stderr, "  gx -y \"list docker containers\"\n")
 		fmt.Fprintf(os.Stderr, "  gx -p \"list files\"       # Print prompt without sending\n")
+		fmt.Fprintf(os.Stderr, "  gx -m smart \"...\"        # Use the stronger model for a hard prompt\n")
+		fmt.Fprintf(os.Stderr, "  gx -a \"free up disk space\"  # Agent: confirm and run one step at a time\n")
+		fmt.Fprintf(os.Stderr, "  gx --plan \"migrate postgres 14 to 16\"  # Review every step first\n")
+		fmt.Fprintf(os.Stderr, "  gx -C ~/src/app -y \"run the tests\"  # Work in another directory\n")
+		fmt.Fprintf(os.Stderr, "  gx --alt 3 \"find go files\"  # Compare three approaches\n")
 		fmt.Fprintf(os.Stderr, "  cat error.log | gx - \"explain this error\"   # Read from stdin\n")
+		fmt.Fprintf(os.Stderr, "  go test ./... 2>&1 | gx why -   # Explain instead of generating a command\n")
+		fmt.Fprintf(os.Stderr, "  gx --image dialog.png \"fix this error\"  # Send a screenshot with the prompt\n")
+		fmt.Fprintf(os.Stderr, "  gx --audio memo.m4a      # Speak the request instead of typing it\n")
 		fmt.Fprintf(os.Stderr, "  docker ps | gx -         # Use only stdin as prompt\n")
 		fmt.Fprintf(os.Stderr, "\nEnvironment:\n")
 		fmt.Fprintf(os.Stderr, "  GX_MODEL        Gemini model to use (default: gemini-2.5-flash-lite)\n")
+		fmt.Fprintf(os.Stderr, "  GX_MODEL_FAST, GX_MODEL_SMART  Models the -m fast / -m smart aliases map to\n")
+		fmt.Fprintf(os.Stderr, "  GX_ESCALATE_TO  Model retried once when an answer fails validation or has low confidence (default: off)\n")
 		fmt.Fprintf(os.Stderr, "  GX_HISTORY      Max history entries (default: 10)\n")
+		fmt.Fprintf(os.Stderr, "  GX_LANG         Language for comments and explanations (e.g. de; default: English)\n")
 		fmt.Fprintf(os.Stderr, "  GX_PROMPT_OUTPUT  Path to write prompt logs (default: ~/.gxprompt)\n")
+		fmt.Fprintf(os.Stderr, "  GX_LOG_LEVEL    Log level: debug, info, warn, error (default: warn, info with -v)\n")
+		fmt.Fprintf(os.Stderr, "  GX_TEMPERATURE, GX_TOP_P, GX_TOP_K, GX_CANDIDATES  Sampling defaults\n")
+		fmt.Fprintf(os.Stderr, "  GX_MAX_OUTPUT_TOKENS  Maximum output tokens (default: model default)\n")
+		fmt.Fprintf(os.Stderr, "  GX_RATE_LIMIT   Max model requests per minute across all gx processes (default: 30, 0 = off)\n")
+		fmt.Fprintf(os.Stderr, "  GX_CACHE_TTL    Lifetime of cached responses (default: 24h)\n")
+		fmt.Fprintf(os.Stderr, "  GX_NO_LOCAL_MATCH  Set to 1 to never offer local matches while waiting for the model (same as --no-local)\n")
+		fmt.Fprintf(os.Stderr, "  GX_NO_UPDATE_CHECK  Set to 1 to skip the daily check for a newer gx release\n")
+		fmt.Fprintf(os.Stderr, "  GX_INTERACTIVE_SHELL  Set to 1 to always execute with $SHELL -ic (same as -i)\n")
+		fmt.Fprintf(os.Stderr, "  GX_ALIASES      Set to 1 to always send your aliases and functions (same as --aliases)\n")
+		fmt.Fprintf(os.Stderr, "  GX_DIFF         Set to 1 to always send the staged diff with commit and PR requests (same as --diff)\n")
+		fmt.Fprintf(os.Stderr, "  GX_AWS          Set to 1 to always send the active AWS account and region (same as --aws)\n")
+		fmt.Fprintf(os.Stderr, "  GX_ABORT_WINDOW How long gxx warns before running a risky command (default: 2s, 0 = don't wait)\n")
+		fmt.Fprintf(os.Stderr, "  GX_EXIT_OFFSET  Added to a failing -x/-y command's exit status (same as --exit-offset)\n")
+		fmt.Fprintf(os.Stderr, "  GX_EXEC_TIMEOUT Kill -x/-y commands after this duration, e.g. 30s (same as --exec-timeout)\n")
+		fmt.Fprintf(os.Stderr, "  GX_LOCATION     Vertex AI location (default: us-central1)\n")
+		fmt.Fprintf(os.Stderr, "  GX_ENDPOINT     Custom Vertex AI endpoint host:port (e.g. Private Service Connect)\n")
+		fmt.Fprintf(os.Stderr, "  GX_TRANSPORT    grpc (default) or rest; rest works behind most HTTP proxies\n")
+		fmt.Fprintf(os.Stderr, "  HTTPS_PROXY     Proxy for API traffic (NO_PROXY is honored)\n")
 		fmt.Fprintf(os.Stderr, "\nGCP Setup (required):\n")
 		fmt.Fprintf(os.Stderr, "  gcloud auth application-default login\n")
 		fmt.Fprintf(os.Stderr, "  gcloud config set project PROJECT_ID\n")
@@ -61,10 +189,89 @@ func Run(opts Options) int {
 
 	// Handle version flag
 	if *versionFlag {
-		fmt.Printf("gx version %s\n", opts.Version)
+		printVersion(opts.Version)
 		return 0
 	}
 
+	// Project config (.gxrc) from the repository gx works in. Its
+	// instructions and flags only apply once the user has trusted the file,
+	// and flags the command line sets win.
+	proj, err := project.Find(*dirFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if proj != nil && (proj.Instructions != "" || len(proj.Flags) > 0) && !proj.Trusted() {
+		if flag.Arg(0) != "trust" {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring the instructions and flags in %s until you review it and run gx trust\n", proj.Path)
+		}
+		proj.Instructions, proj.Flags = "", nil
+	}
+	if proj != nil && len(proj.Flags) > 0 {
+		if err := applyProjectFlags(proj.Flags); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", proj.Path, err)
+			return 1
+		}
+	}
+
+	logger := logging.New(logging.Options{
+		Verbose: *verboseFlag,
+		Debug:   *debugFlag,
+	}).With("request_id", logging.NewRequestID())
+	if proj != nil {
+		logger.Debug("project config", "path", proj.Path, "flags", proj.Flags, "allow_paths", proj.AllowPaths)
+	}
+
+	// New-version notice from the last daily check; the next check runs
+	// alongside this request and is shown on a later run
+	if !envEnabled("GX_NO_UPDATE_CHECK") && isTerminal(os.Stderr) {
+		if checker, err := update.New(opts.Version); err == nil {
+			if notice := checker.Notice(); notice != "" {
+				fmt.Fprintln(os.Stderr, notice)
+			}
+			go func() {
+				if err := checker.Refresh(context.Background()); err != nil {
+					logger.Debug("update check failed", "error", err)
+				}
+			}()
+		}
+	}
+
+	if *autoFixFlag > 0 && !*yoloFlag {
+		fmt.Fprintln(os.Stderr, "Error: --auto-fix requires -y")
+		return 1
+	}
+	if *planFlag && (*yoloFlag || *agentFlag) {
+		fmt.Fprintln(os.Stderr, "Error: --plan can't be combined with -y or -a")
+		return 1
+	}
+
+	// Like git -C: change directory first so tools, the cache key, and
+	// executed commands all see it
+	workDir := ""
+	if *dirFlag != "" {
+		dir, err := changeDir(*dirFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		workDir = dir
+	}
+
+	// Set up opt-in tracing; spans are no-ops unless GX_OTEL or OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := telemetry.Setup(context.Background(), opts.Version)
+	if err != nil {
+		logger.Warn("tracing disabled", "error", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Warn("failed to flush traces", "error", err)
+		}
+	}()
+
+	ctx, span := telemetry.Start(context.Background(), "gx.run")
+	defer span.End()
+
 	// Initialize history manager
 	histMgr, err := history.NewManager()
 	if err != nil {
@@ -72,29 +279,200 @@ func Run(opts Options) int {
 		return 1
 	}
 
+	// Initialize response cache
+	cacheStore, err := cache.NewStore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	// Handle clear flag
 	if *clearFlag {
 		if err := histMgr.Clear(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error clearing: %v\n", err)
 			return 1
 		}
-		fmt.Println("History and staged commands cleared.")
+		if err := cacheStore.Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing: %v\n", err)
+			return 1
+		}
+		fmt.Println("History, staged commands, and cache cleared.")
 		return 0
 	}
 
+	// Limit requests per minute so scripts calling gx in a loop can't burn quota
+	limiter, err := ratelimit.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	execEnv, err := executionEnv(envSetFlag, envFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(execEnv) > 0 {
+		logger.Info("environment for executed commands", "keys", envset.Keys(execEnv))
+	}
+
+	if *shellFlag != "" && !slices.Contains(gemini.TargetShells, *shellFlag) {
+		fmt.Fprintf(os.Stderr, "Error: unknown shell %q for --shell (use one of %s)\n", *shellFlag, strings.Join(gemini.TargetShells, ", "))
+		return 1
+	}
+
+	if *osFlag != "" && !slices.Contains(gemini.TargetOSes, *osFlag) {
+		fmt.Fprintf(os.Stderr, "Error: unknown OS %q for --os (use one of %s)\n", *osFlag, strings.Join(gemini.TargetOSes, ", "))
+		return 1
+	}
+	// A host profile fills in whatever --os, --arch, and --shell leave unset
+	targetOS, targetArch, targetShell := *osFlag, *archFlag, *shellFlag
+	var target *hosts.Profile
+	if *targetFlag != "" {
+		target, err = loadTarget(*targetFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if targetOS == "" {
+			targetOS = target.OS
+		}
+		if targetArch == "" {
+			targetArch = target.Arch
+		}
+		if targetShell == "" {
+			targetShell = target.Shell
+		}
+	}
+	// Commands for another OS, architecture, or a target host are meant for
+	// another machine. machine names it, for messages and the history entry
+	// that keeps -x from running the command here.
+	machine := ""
+	switch {
+	case target != nil:
+		machine = "--target " + target.Name
+	case targetOS != "" && targetOS != runtime.GOOS:
+		machine = "--os " + targetOS
+	case targetArch != "" && targetArch != runtime.GOARCH:
+		machine = "--arch " + targetArch
+	}
+	remote := machine != ""
+	if remote && (*yoloFlag || *agentFlag) {
+		fmt.Fprintf(os.Stderr, "Error: %s commands are for another machine; drop -y and -a (or use gx instead of gxx)\n", machine)
+		return 1
+	}
+	if *yoloFlag || *agentFlag {
+		if err := checkShell(*shellFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v; drop -y and -a (or use gx instead of gxx)\n", err)
+			return 1
+		}
+	}
+
+	execOpts := execOptions{
+		interactive: *interactiveFlag || envEnabled("GX_INTERACTIVE_SHELL"),
+		posix:       *posixFlag,
+		shell:       *shellFlag,
+		preview:     *previewFlag,
+		env:         execEnv,
+		timeout:     resolveExecTimeout(*execTimeoutFlag),
+		force:       *forceFlag,
+	}
+	exitOffset := resolveExitOffset(*exitOffsetFlag)
+
 	// Handle execute flag
 	if *executeFlag {
-		exitCode, err := executeStaged(histMgr)
+		exitCode, err := executeStaged(ctx, histMgr, execOpts)
+		if errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return exitInterrupted
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if errors.Is(err, errRefused) {
+				return exitRefused
+			}
+			return exitError
+		}
+		return commandStatus(exitCode, exitOffset)
+	}
+
+	clientCfg := gemini.Config{
+		Model:         *modelFlag,
+		Comments:      *commentsFlag,
+		NoTools:       *noToolsFlag,
+		ReadOnlyTools: *readOnlyToolsFlag,
+		Logger:        logger,
+		Sampling:      samplingFromFlags(*temperatureFlag, *topPFlag, *topKFlag, *candidatesFlag, *maxTokensFlag),
+		OneLiner:      *oneLinerFlag,
+		Why:           *whyFlag,
+		Undo:          *undoFlag,
+		Language:      *langFlag,
+		RateLimiter:   limiter,
+		EscalateTo:    *escalateFlag,
+		WorkDir:       workDir,
+		POSIX:         *posixFlag,
+		Shell:         targetShell,
+		OS:            targetOS,
+		Arch:          targetArch,
+		AWS:           *awsFlag || envEnabled("GX_AWS"),
+	}
+	if target != nil {
+		clientCfg.Host = target.Context()
+	}
+	if remote && !clientCfg.NoTools {
+		// Tools would inspect this machine, not the target
+		logger.Info("tools disabled for another machine", "os", targetOS, "target", *targetFlag)
+		clientCfg.NoTools = true
+	}
+	if proj != nil {
+		clientCfg.Instructions = proj.Instructions
+		clientCfg.AllowPaths = proj.AllowPaths
+	}
+	for _, path := range imageFlag {
+		image, err := gemini.LoadImage(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		clientCfg.Attachments = append(clientCfg.Attachments, image)
+	}
+	for _, path := range audioFlag {
+		audio, err := gemini.LoadAudio(path)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			return 1
 		}
-		return exitCode
+		clientCfg.Attachments = append(clientCfg.Attachments, audio)
+	}
+	// .gxignore at the repository root keeps secrets and vendored trees out of tool results
+	if root, ok := project.Root("."); ok {
+		if clientCfg.Ignore, err = ignore.Load(filepath.Join(root, ignore.FileName)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if *altFlag >= 2 {
+		clientCfg.Alternatives = *altFlag
+	}
+	if !*noClarifyFlag {
+		clientCfg.Clarify = askClarification
 	}
 
 	// Get prompt from arguments
 	args := flag.Args()
-	
+
+	// Dispatch subcommands (gx man ...)
+	if len(args) > 0 {
+		if sub, ok := lookupSubcommand(args); ok {
+			return sub(ctx, &runEnv{
+				histMgr:    histMgr,
+				cacheStore: cacheStore,
+				logger:     logger,
+				clientCfg:  clientCfg,
+			}, args[1:])
+		}
+	}
+
 	// Check if "-" is in the arguments to read from stdin
 	hasStdinFlag := false
 	promptArgs := []string{}
@@ -105,10 +483,12 @@ func Run(opts Options) int {
 			promptArgs = append(promptArgs, arg)
 		}
 	}
-	
-	// Build the prompt from non-"-" arguments
-	prompt := strings.Join(promptArgs, " ")
-	
+
+	// Build the prompt from non-"-" arguments. userPrompt stays the user's
+	// own words, without piped input or staged changes.
+	prompt := translatePaths(logger, strings.Join(promptArgs, " "), remote)
+	userPrompt := prompt
+
 	// Read from stdin if "-" was specified
 	if hasStdinFlag {
 		stdinBytes, err := io.ReadAll(os.Stdin)
@@ -117,7 +497,7 @@ func Run(opts Options) int {
 			return 1
 		}
 		stdinContent := strings.TrimSpace(string(stdinBytes))
-		
+
 		// Append stdin content to the prompt
 		if prompt == "" {
 			prompt = stdinContent
@@ -125,19 +505,76 @@ func Run(opts Options) int {
 			prompt = prompt + "\n\n---\n\n" + stdinContent
 		}
 	}
-	
+
+	// A recording can be the whole request
+	if prompt == "" && len(audioFlag) > 0 {
+		prompt = spokenPrompt
+	}
+
+	// Bare `gx` on a terminal composes a (possibly long) request instead
+	if prompt == "" && !hasStdinFlag && isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		composed, err := composePrompt()
+		if errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Empty request; nothing to do.")
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		prompt = translatePaths(logger, composed, remote)
+		userPrompt = prompt
+	}
+
 	if prompt == "" {
 		flag.Usage()
 		return 1
 	}
 
+	// Files named with @path go to the model as their own parts. Only the
+	// user's words count, so piped input can't name files to send.
+	policy := attachPolicy{allow: clientCfg.AllowPaths, ignore: clientCfg.Ignore}
+	policy.root, _ = os.Getwd()
+	if root, ok := project.Root("."); ok {
+		policy.root = root
+	}
+	referenced, err := referencedFiles(userPrompt, policy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	clientCfg.Attachments = append(clientCfg.Attachments, referenced...)
+
+	// A commit message or PR description needs to know what changed
+	stagedChanges := ""
+	if !remote && envctx.MentionsCommit(prompt) {
+		stagedChanges = envctx.StagedChanges(ctx, workDir, *diffFlag || envEnabled("GX_DIFF"), clientCfg.Ignore)
+	}
+	if stagedChanges != "" {
+		logger.Info("added staged changes to the prompt", "bytes", len(stagedChanges))
+		prompt = prompt + "\n\n---\n\n" + stagedChanges
+	}
+
+	// Aliases belong to the local shell, not a --shell, --os, or --target one
+	if (*aliasesFlag || envEnabled("GX_ALIASES")) && *shellFlag == "" && !remote {
+		clientCfg.Customizations = loadCustomizations(ctx, logger)
+		clientCfg.InteractiveShell = execOpts.interactive
+	}
+
+	// The selected cluster, accounts, and runtimes shape the answer, so they
+	// are collected before the cache key is built from clientCfg
+	clientCfg.Infra = gemini.CollectInfra(ctx, clientCfg)
+
+	if *agentFlag {
+		clientCfg.Mode = gemini.ModeAgent
+	}
+	if *planFlag {
+		clientCfg.Mode = gemini.ModePlan
+	}
+
 	// Handle print prompt flag
 	if *printPromptFlag {
-		ctx := context.Background()
-		client, err := gemini.NewClient(ctx, gemini.Config{
-			Verbose: *verboseFlag,
-			NoTools: *noToolsFlag,
-		})
+		client, err := gemini.NewClient(ctx, clientCfg)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			return 1
@@ -157,43 +594,169 @@ func Run(opts Options) int {
 		return 0
 	}
 
-	// Generate command
-	ctx := context.Background()
-	command, err := generateCommand(ctx, prompt, *verboseFlag, *noToolsFlag, histMgr)
+	// Agent mode works toward the goal one confirmed step at a time
+	if *agentFlag {
+		return runAgent(ctx, prompt, clientCfg, histMgr, execOpts)
+	}
+
+	// Plan mode prints the whole approach without staging or running it
+	if *planFlag {
+		return runPlan(ctx, prompt, clientCfg, histMgr)
+	}
+
+	if opts.EditBeforeRun && !isTerminal(os.Stdin) {
+		fmt.Fprintln(os.Stderr, "Error: gxe needs a terminal to edit the command (use gx -y to run it unattended)")
+		return exitError
+	}
+
+	// Generate command; Ctrl-C cancels the in-flight API call
+	generate := generateCommand
+	// Piped input and staged changes make the request unique, so history
+	// and snippets won't fit it
+	if !*noLocalFlag && !envEnabled("GX_NO_LOCAL_MATCH") && !hasStdinFlag && stagedChanges == "" {
+		generate = generateWithSuggestion
+	}
+	genCtx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	result, err := generate(genCtx, prompt, clientCfg, *noCacheFlag, histMgr, cacheStore)
+	// Checked before stopSignals, which cancels genCtx itself
+	interrupted := errors.Is(genCtx.Err(), context.Canceled)
+	stopSignals()
 	if err != nil {
+		if interrupted || errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return exitInterrupted
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		return 1
+		return generationStatus(err)
+	}
+	if len(result.Options) >= 2 {
+		if result, err = chooseOption(result); err != nil {
+			if errors.Is(err, errCancelled) {
+				fmt.Fprintln(os.Stderr, "Cancelled.")
+				return exitInterrupted
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
 	}
 
-	// Output the command
+	// Output the command; gxe lets the user edit it first, and what is saved
+	// is what gets staged and run
+	command := result.Command
+	if opts.EditBeforeRun {
+		edited, err := editCommand(command)
+		if errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Empty command; nothing to run.")
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		if edited != command {
+			// The model's rating, undo hint, and quoting check were for its
+			// command, so the edited one is rated locally
+			command, result.Undo, result.QuotingIssue = edited, "", ""
+			result.Risk = localRisk(command)
+			result.NeedsConfirmation = result.Risk == gemini.RiskHigh
+		}
+	}
 	fmt.Println(command)
+	if result.NeedsConfirmation {
+		fmt.Fprintf(os.Stderr, "Warning: the model rates this command %s risk; review it before running\n", riskLabel(result.Risk))
+	}
+	if result.QuotingIssue != "" {
+		fmt.Fprintf(os.Stderr, "Warning: possible quoting problem: %s; review it before running\n", result.QuotingIssue)
+	}
 
 	// Stage the command
-	if err := histMgr.StageCommand(command); err != nil {
+	if err := histMgr.Stage(history.Staged{Command: command, Risk: result.Risk, Machine: machine, Shell: *shellFlag}); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to stage command: %v\n", err)
 	}
 
 	// Save to history
-	if err := histMgr.Append(prompt, command); err != nil {
+	if err := histMgr.AppendEntry(history.Entry{Prompt: prompt, Response: command, Undo: result.Undo, Risk: result.Risk, Tools: result.Tools, Machine: machine}); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", err)
 	}
 
 	// YOLO mode - execute immediately
 	if *yoloFlag {
+		// Confirm before the preview, since the rehearsal runs the command
+		// gxx runs without a second look, so risky commands it won't ask
+		// about get a banner and a moment to abort
+		if opts.ForceYolo && !needsTypedConfirmation(command, result.Risk, execOpts.force) {
+			if err := riskBanner(ctx, command, result.Risk); err != nil {
+				fmt.Fprintln(os.Stderr, "Cancelled.")
+				return exitInterrupted
+			}
+		}
+		if err := confirmDestructive(command, result.Risk, execOpts.force); err != nil {
+			if errors.Is(err, errCancelled) {
+				fmt.Fprintln(os.Stderr, "Cancelled.")
+				return exitInterrupted
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitRefused
+		}
+		if execOpts.preview {
+			fmt.Fprintln(os.Stderr)
+			if err := previewCommand(command, execOpts); err != nil {
+				if errors.Is(err, errCancelled) {
+					fmt.Fprintln(os.Stderr, "Cancelled.")
+					return exitInterrupted
+				}
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+		}
 		fmt.Fprintln(os.Stderr, "\n--- Executing ---")
-		exitCode, err := executeCommand(command)
+		var exitCode int
+		if *autoFixFlag > 0 {
+			exitCode, err = runWithAutoFix(ctx, prompt, command, clientCfg, histMgr, execOpts, *autoFixFlag)
+		} else {
+			exitCode, err = executeCommand(ctx, command, execOpts)
+		}
+		if errors.Is(err, errCancelled) {
+			fmt.Fprintln(os.Stderr, "Cancelled.")
+			return exitInterrupted
+		}
+		if errors.Is(err, errRefused) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitRefused
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Execution error: %v\n", err)
-			return 1
+			return exitError
 		}
-		return exitCode
+		return commandStatus(exitCode, exitOffset)
 	}
 
 	return 0
 }
 
-// generateCommand uses Gemini to generate a shell command from the prompt.
-func generateCommand(ctx context.Context, prompt string, verbose, noTools bool, histMgr *history.Manager) (string, error) {
+// translatePaths rewrites Windows paths the user typed (C:\Users\me) as
+// WSL paths (/mnt/c/Users/me) when gx runs under WSL and the command is for
+// this machine, so the command works as is. Only the user's own words are
+// translated; piped input and staged changes reach the model unchanged.
+func translatePaths(logger *slog.Logger, prompt string, remote bool) string {
+	if remote || !shell.IsWSL() {
+		return prompt
+	}
+	translated := shell.WSLPaths(prompt, shell.WSLMountRoot())
+	if translated != prompt {
+		logger.Debug("translated Windows paths for WSL")
+	}
+	return translated
+}
+
+// generateCommand uses Gemini to generate a shell command from the prompt,
+// along with its risk and, when cfg.Undo is set, its undo hint.
+// Commands are served from and saved to the cache unless noCache is set;
+// cached commands carry no risk or undo information.
+func generateCommand(ctx context.Context, prompt string, cfg gemini.Config, noCache bool, histMgr *history.Manager, cacheStore *cache.Store) (gemini.Result, error) {
+	ctx, span := telemetry.Start(ctx, "generate")
+	defer span.End()
+
 	// Get recent history for context
 	histContext, err := histMgr.GetRecentContext(3)
 	if err != nil {
@@ -201,60 +764,600 @@ func generateCommand(ctx context.Context, prompt string, verbose, noTools bool,
 		histContext = nil
 	}
 
+	// Multiple candidates and alternatives always go through the chooser, and
+	// rationales and undo hints aren't cached, so these bypass the cache
+	candidates := int(*gemini.ResolveSampling(cfg.Sampling).CandidateCount)
+	if candidates > 1 || cfg.Alternatives >= 2 || cfg.Why || cfg.Undo {
+		noCache = true
+	}
+
+	cacheKey := buildCacheKey(prompt, cfg, histContext)
+	if !noCache {
+		if command, ok := cacheStore.Get(cacheKey); ok {
+			cfg.Logger.Debug("cache hit", "key", cacheKey[:12])
+			span.SetAttributes(attribute.Bool("cache_hit", true))
+			return gemini.Result{Command: command}, nil
+		}
+	}
+
 	// Create Gemini client
-	client, err := gemini.NewClient(ctx, gemini.Config{
-		Verbose: verbose,
-		NoTools: noTools,
-	})
+	client, err := gemini.NewClient(ctx, cfg)
 	if err != nil {
-		return "", fmt.Errorf("failed to create client: %w", err)
+		return gemini.Result{}, fmt.Errorf("failed to create client: %w", err)
 	}
 	defer client.Close()
 
 	// Generate the command
-	return client.Generate(ctx, prompt, histContext)
+	if cfg.Alternatives >= 2 {
+		alternatives, err := client.GenerateAlternatives(ctx, prompt, histContext)
+		if err != nil {
+			return gemini.Result{}, err
+		}
+		choices := make([]choice, len(alternatives))
+		for i, alt := range alternatives {
+			choices[i] = choice{Command: alt.Command, Note: alt.Tradeoff}
+		}
+		command, err := chooseCommand(choices)
+		return gemini.Result{Command: command}, err
+	}
+
+	if candidates > 1 {
+		results, err := client.GenerateCandidates(ctx, prompt, histContext, candidates)
+		if err != nil {
+			return gemini.Result{}, err
+		}
+		choices := make([]choice, len(results))
+		for i, result := range results {
+			choices[i] = choice{Command: result}
+		}
+		command, err := chooseCommand(choices)
+		return gemini.Result{Command: command}, err
+	}
+
+	result, err := client.GenerateResult(ctx, prompt, histContext)
+	if err != nil {
+		return gemini.Result{}, err
+	}
+	// Several ways to do it are chosen from by the caller (see chooseOption),
+	// and not cached so the next run offers them again
+	if len(result.Options) >= 2 {
+		return result, nil
+	}
+	if result.Rationale != "" {
+		fmt.Fprintf(os.Stderr, "Why: %s\n", result.Rationale)
+	}
+	if result.Undo != "" {
+		fmt.Fprintf(os.Stderr, "Undo: %s\n", result.Undo)
+	}
+
+	// A command shaped by the user's answers isn't reusable for the bare prompt
+	if !noCache && !result.Clarified {
+		if err := cacheStore.Put(cacheKey, result.Command); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to cache response: %v\n", err)
+		}
+	}
+
+	return result, nil
+}
+
+// chooseOption lets the user pick one of the independent commands the model
+// listed when asked for several ways to do something. The rationale and undo
+// hint described the first option, so they are dropped.
+func chooseOption(result gemini.Result) (gemini.Result, error) {
+	choices := make([]choice, len(result.Options))
+	for i, o := range result.Options {
+		choices[i] = choice{Command: o.Command, Note: o.Tradeoff}
+	}
+	command, err := chooseCommand(choices)
+	if err != nil {
+		return gemini.Result{}, err
+	}
+	for _, o := range result.Options {
+		if o.Command == command && o.Risk != "" {
+			result.Risk = o.Risk
+			result.NeedsConfirmation = o.Risk == gemini.RiskHigh
+		}
+	}
+	result.Command, result.Undo, result.Rationale, result.Options = command, "", "", nil
+	return result, nil
+}
+
+// riskLabel returns the model's risk rating for display, or "elevated" when
+// it asked for confirmation without giving one.
+func riskLabel(risk string) string {
+	if risk == "" {
+		return "elevated"
+	}
+	return risk
+}
+
+// loadTarget looks up a --target host profile in ~/.gxhosts.
+func loadTarget(name string) (*hosts.Profile, error) {
+	profiles, err := hosts.Load()
+	if err != nil {
+		return nil, err
+	}
+	p, ok := profiles[name]
+	if !ok {
+		if len(profiles) == 0 {
+			return nil, fmt.Errorf("unknown host %q for --target (define host profiles in ~/%s)", name, hosts.DefaultFile)
+		}
+		return nil, fmt.Errorf("unknown host %q for --target (use one of %s)", name, strings.Join(hosts.Names(profiles), ", "))
+	}
+	return &p, nil
+}
+
+// loadCustomizations lists the aliases and functions the user's rc file
+// defines (--aliases). Failures only cost the context, so they are warnings.
+func loadCustomizations(ctx context.Context, logger *slog.Logger) shell.Customizations {
+	shellPath := os.Getenv("SHELL")
+	if m, ok := shell.DetectMSYS(); ok {
+		shellPath = m.Shell
+	}
+	if shellPath == "" {
+		fmt.Fprintln(os.Stderr, "Warning: --aliases needs $SHELL set to bash or zsh")
+		return shell.Customizations{}
+	}
+	customizations, err := shell.LoadCustomizations(ctx, shellPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return shell.Customizations{}
+	}
+	logger.Info("loaded shell customizations", "aliases", len(customizations.Aliases), "functions", len(customizations.Functions))
+	return customizations
+}
+
+// buildCacheKey derives the cache key from the prompt, history context,
+// provider, model, and the settings and environment (including the infrastructure
+// context) that influence the generated command.
+func buildCacheKey(prompt string, cfg gemini.Config, histContext []history.Entry) string {
+	cwd, _ := os.Getwd()
+	// Canned mock answers must not be served to real requests, or vice versa
+	provider, _ := gemini.ResolveProvider(cfg.Provider)
+	parts := []string{
+		provider,
+		gemini.ResolveModel(cfg.Model),
+		prompt,
+		fmt.Sprintf("comments=%t", cfg.Comments),
+		fmt.Sprintf("notools=%t", cfg.NoTools),
+		fmt.Sprintf("readonlytools=%t", cfg.ReadOnlyTools),
+		fmt.Sprintf("oneliner=%t", cfg.OneLiner),
+		"lang=" + gemini.ResolveLanguage(cfg.Language),
+		"instructions=" + cfg.Instructions,
+		fmt.Sprintf("allowpaths=%q", cfg.AllowPaths),
+		fmt.Sprintf("ignore=%q", cfg.Ignore.Patterns()),
+		"customizations=" + cfg.Customizations.String(),
+		fmt.Sprintf("interactive=%t", cfg.InteractiveShell),
+		fmt.Sprintf("posix=%t", cfg.POSIX),
+		"shell=" + cfg.Shell,
+		"os=" + cfg.OS,
+		"arch=" + cfg.Arch,
+		"host=" + cfg.Host,
+		fmt.Sprintf("aws=%t", cfg.AWS),
+		"infra=" + strings.Join(cfg.Infra, "\n"),
+		gemini.ResolveSampling(cfg.Sampling).String(),
+		runtime.GOOS,
+		os.Getenv("SHELL"),
+		cwd,
+	}
+	for _, a := range cfg.Attachments {
+		parts = append(parts, "attachment="+a.Digest())
+	}
+	for _, entry := range histContext {
+		parts = append(parts, entry.Prompt, entry.Response)
+	}
+	return cache.Key(parts...)
+}
+
+// samplingFromFlags converts sampling flags to overrides; unset flags
+// (negative or zero) are left nil so env vars and defaults apply.
+func samplingFromFlags(temperature, topP float64, topK, candidates, maxTokens int) gemini.Sampling {
+	var s gemini.Sampling
+	if temperature >= 0 {
+		t := float32(temperature)
+		s.Temperature = &t
+	}
+	if topP >= 0 {
+		p := float32(topP)
+		s.TopP = &p
+	}
+	if topK > 0 {
+		k := int32(topK)
+		s.TopK = &k
+	}
+	if candidates > 0 {
+		n := int32(candidates)
+		s.CandidateCount = &n
+	}
+	if maxTokens > 0 {
+		m := int32(maxTokens)
+		s.MaxOutputTokens = &m
+	}
+	return s
+}
+
+// Exit codes. A command gx executes exits with its own status, which is
+// passed through (see --exit-offset); the rest are gx's own.
+const (
+	// exitError covers usage, configuration, and other local errors
+	exitError = 1
+	// exitCrashed is reported after a recovered panic; it differs from the
+	// flag package's 2 for invalid flags (EX_SOFTWARE in sysexits.h)
+	exitCrashed = 70
+	// exitGenerationFailed is reported when no answer could be had from the
+	// model: credentials, network, quota, or a declined request
+	exitGenerationFailed = 3
+	// exitValidationFailed is reported when the model answered but the
+	// answer still failed validation after corrective re-prompts
+	exitValidationFailed = 4
+	// exitRefused is reported when the safety check stopped a high-risk
+	// command (confirmation mismatch, or no terminal to confirm on)
+	exitRefused = 5
+	// exitTimedOut is reported when --exec-timeout kills a command,
+	// matching timeout(1)
+	exitTimedOut = 124
+	// exitInterrupted is reported when gx is cancelled with Ctrl-C
+	// (128 + SIGINT)
+	exitInterrupted = 130
+)
+
+// generationStatus returns the exit code for a failed generation.
+func generationStatus(err error) int {
+	if gemini.IsInvalid(err) {
+		return exitValidationFailed
+	}
+	return exitGenerationFailed
+}
+
+// commandStatus returns the exit code for an executed command's status:
+// passed through, or with offset added to a failure so scripts can tell it
+// from gx's own codes. gx's reserved codes, such as the timeout's 124 or
+// 130 for Ctrl-C, keep their meaning and are never shifted. Exit statuses
+// are capped at 255.
+func commandStatus(code, offset int) int {
+	switch code {
+	case 0, exitCrashed, exitTimedOut, exitInterrupted:
+		return code
+	}
+	if offset == 0 {
+		return code
+	}
+	return min(code+offset, 255)
+}
+
+// killGracePeriod is how long a timed-out command gets to exit after SIGTERM
+// before it is killed outright.
+const killGracePeriod = 5 * time.Second
+
+// errCancelled is returned when the user declines an interactive prompt.
+var errCancelled = errors.New("cancelled")
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// changeDir changes the working directory to dir and updates PWD to match,
+// returning the absolute path.
+func changeDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory %s: %w", dir, err)
+	}
+	if err := os.Chdir(abs); err != nil {
+		return "", err
+	}
+	// Child shells and the prompt's environment read PWD
+	os.Setenv("PWD", abs)
+	return abs, nil
+}
+
+// executionEnv combines the ~/.gxenv defaults, the selected sets, and --env
+// assignments, in that order so later ones win.
+func executionEnv(sets, assignments []string) ([]string, error) {
+	file, err := envset.Load()
+	if err != nil {
+		return nil, err
+	}
+	env, err := file.Env(sets)
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range assignments {
+		kv, err := envset.Parse(assignment)
+		if err != nil {
+			return nil, fmt.Errorf("--env: %w", err)
+		}
+		env = append(env, kv)
+	}
+	return env, nil
+}
+
+// commandEnv returns the environment for an executed command, or nil to
+// inherit gx's own when nothing is added.
+func commandEnv(opts execOptions) []string {
+	if len(opts.env) == 0 {
+		return nil
+	}
+	// exec uses the last value for duplicate keys, so these override
+	return append(os.Environ(), opts.env...)
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// resolveExecTimeout returns the --exec-timeout value, falling back to
+// GX_EXEC_TIMEOUT. Zero means no limit.
+func resolveExecTimeout(timeout time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	if d, err := time.ParseDuration(os.Getenv("GX_EXEC_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// resolveExitOffset returns the --exit-offset value, falling back to
+// GX_EXIT_OFFSET.
+func resolveExitOffset(offset int) int {
+	if offset > 0 {
+		return offset
+	}
+	if n, err := strconv.Atoi(os.Getenv("GX_EXIT_OFFSET")); err == nil && n > 0 {
+		return n
+	}
+	return 0
+}
+
+// printVersion prints the version and, for bug reports, how the binary was
+// built and which model a request would use.
+func printVersion(v string) {
+	build := version.Build()
+	commit := orUnknown(build.Commit)
+	if build.Modified {
+		commit += " (modified)"
+	}
+	fmt.Printf("gx version %s\n", v)
+	fmt.Printf("  commit:   %s\n", commit)
+	fmt.Printf("  built:    %s\n", orUnknown(build.BuildDate))
+	fmt.Printf("  go:       %s %s\n", build.GoVersion, build.Platform)
+	fmt.Printf("  model:    %s\n", gemini.ResolveModel(""))
+}
+
+// orUnknown returns s, or "unknown" if it is empty.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+// envEnabled reports whether a boolean environment variable is set to a true value.
+func envEnabled(key string) bool {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv(key)))
+	return v != "" && v != "0" && v != "false" && v != "no"
+}
+
+// execOptions controls how staged commands are executed.
+type execOptions struct {
+	// interactive runs the command in an interactive shell ($SHELL -ic) so
+	// aliases, functions, and PATH changes from the user's rc file apply
+	interactive bool
+	// posix runs the command with /bin/sh instead of $SHELL (--posix)
+	posix bool
+	// shell runs the command with this shell (--shell) instead of the
+	// user's ("" = the user's)
+	shell string
+	// preview rehearses file-editing commands against temporary copies and
+	// shows a diff before asking to run them for real
+	preview bool
+	// env holds KEY=VALUE pairs added to the command's environment
+	env []string
+	// timeout kills the command's process group when exceeded (0 = no limit)
+	timeout time.Duration
+	// stdoutTail and stderrTail, when set, receive a copy of the command's
+	// stdout and stderr
+	stdoutTail *tailBuffer
+	stderrTail *tailBuffer
+	// force skips the typed confirmation for high-risk commands
+	force bool
 }
 
 // executeStaged executes the command staged in ~/.gx.
-func executeStaged(histMgr *history.Manager) (int, error) {
-	command, err := histMgr.GetStagedCommand()
+func executeStaged(ctx context.Context, histMgr *history.Manager, opts execOptions) (int, error) {
+	staged, err := histMgr.GetStaged()
 	if err != nil {
 		return 1, err
 	}
+	command := staged.Command
+
+	// The model's risk rating, and the machine and shell the command was
+	// written for, are staged with it
+	if staged.Machine != "" {
+		return 1, fmt.Errorf("the staged command was generated with %s, for another machine; copy it there to run it", staged.Machine)
+	}
+	if err := checkShell(staged.Shell); err != nil {
+		return 1, fmt.Errorf("the staged command was generated with --shell %s, but %v; copy it to a machine that has it to run it", staged.Shell, err)
+	}
+	opts.shell = staged.Shell
+	if err := confirmDestructive(command, staged.Risk, opts.force); err != nil {
+		return 1, err
+	}
+
+	if opts.preview {
+		if err := previewCommand(command, opts); err != nil {
+			return 1, err
+		}
+	}
 
 	fmt.Printf("Executing: %s\n", command)
 	fmt.Println("---")
 
-	return executeCommand(command)
+	return executeCommand(ctx, command, opts)
 }
 
-// executeCommand executes a shell command and returns the exit code from the subprocess.
-// stdout and stderr are streamed directly to the parent process.
-func executeCommand(command string) (int, error) {
-	var cmd *exec.Cmd
+// checkShell returns an error when a command written for the --shell value
+// name can't run here: cmd and Windows PowerShell only exist on Windows,
+// and any other shell must be installed. An empty name means the user's
+// own shell, which always can.
+func checkShell(name string) error {
+	if name == "" {
+		return nil
+	}
+	if (name == "cmd" || name == "powershell") && runtime.GOOS != "windows" {
+		return fmt.Errorf("%s only runs on Windows", name)
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s isn't installed here", name)
+	}
+	return nil
+}
 
+// shellArgv returns the argument vector that runs command in the user's shell.
+func shellArgv(command string, opts execOptions) []string {
+	switch {
+	case opts.shell == "cmd":
+		return []string{"cmd", "/C", command}
+	case shell.IsPowerShell(opts.shell):
+		return []string{opts.shell, "-Command", command}
+	case opts.shell != "":
+		return []string{opts.shell, "-c", command}
+	}
 	switch runtime.GOOS {
 	case "windows":
-		// Try PowerShell first, fall back to cmd
+		// MSYS2, Git Bash, and Cygwin commands are written for their bash
+		if m, ok := shell.DetectMSYS(); ok && m.Shell != "" {
+			if opts.interactive {
+				return []string{m.Shell, "-ic", command}
+			}
+			return []string{m.Shell, "-c", command}
+		}
+		// Otherwise try PowerShell first (pwsh 7+ when installed), fall back
+		// to cmd. PowerShell already loads the user's profile with -Command,
+		// and cmd has no rc file, so opts.interactive needs no special
+		// handling here
 		if os.Getenv("PSModulePath") != "" {
-			cmd = exec.Command("powershell", "-Command", command)
-		} else {
-			cmd = exec.Command("cmd", "/C", command)
+			return []string{shell.DetectPowerShell().Executable, "-Command", command}
 		}
+		return []string{"cmd", "/C", command}
 	default:
 		// Unix-like systems
-		shell := os.Getenv("SHELL")
-		if shell == "" {
-			shell = "/bin/sh"
+		sh := os.Getenv("SHELL")
+		if sh == "" || opts.posix {
+			// --posix commands run where they were meant to
+			sh = "/bin/sh"
+		}
+		shellFlag := "-c"
+		if opts.interactive {
+			// -i makes the shell source its rc file (~/.bashrc, ~/.zshrc, ...)
+			// before running the command, so aliases and functions resolve
+			shellFlag = "-ic"
 		}
-		cmd = exec.Command(shell, "-c", command)
+		return []string{sh, shellFlag, command}
 	}
+}
 
+// executeCommand executes a shell command and returns the exit code from the subprocess.
+// stdout and stderr are streamed directly to the parent process.
+func executeCommand(ctx context.Context, command string, opts execOptions) (exitCode int, err error) {
+	_, span := telemetry.Start(ctx, "execute")
+	span.SetAttributes(attribute.Bool("interactive", opts.interactive))
+	defer func() {
+		span.SetAttributes(attribute.Int("exit_code", exitCode))
+		telemetry.End(span, err)
+	}()
+
+	if err := checkShell(opts.shell); err != nil {
+		return 1, err
+	}
+	argv, err := elevate(command, shellArgv(command, opts), opts)
+	if err != nil {
+		return 1, err
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Env = commandEnv(opts)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if opts.stdoutTail != nil {
+		cmd.Stdout = io.MultiWriter(os.Stdout, opts.stdoutTail)
+	}
+	if opts.stderrTail != nil {
+		cmd.Stderr = io.MultiWriter(os.Stderr, opts.stderrTail)
+	}
+	// The command gets its own process group, so the timeout and forwarded
+	// signals reach everything it starts; on a terminal that group takes
+	// the foreground while it runs
+	configureProcessGroup(cmd, isTerminal(os.Stdin))
+
+	// Trap signals while the child runs so gx isn't killed first and the
+	// command isn't orphaned; forward them to the child instead
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
 
-	err := cmd.Run()
+	if err = cmd.Start(); err != nil {
+		return 1, err
+	}
+	restoreTerminal := foregroundJob(cmd)
+
+	// The timeout sends SIGTERM, then SIGKILL after killGracePeriod; both
+	// timers are stopped once the command exits
+	var timeout, kill <-chan time.Time
+	if opts.timeout > 0 {
+		timer := time.NewTimer(opts.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var timedOut atomic.Bool
+	done := make(chan struct{})
+	forwarded := make(chan os.Signal, 1)
+	go func() {
+		var lastSignal os.Signal
+		var killTimer *time.Timer
+		defer func() {
+			if killTimer != nil {
+				killTimer.Stop()
+			}
+			forwarded <- lastSignal
+		}()
+		for {
+			select {
+			case sig := <-sigCh:
+				lastSignal = sig
+				forwardSignal(cmd, sig)
+			case <-timeout:
+				timedOut.Store(true)
+				forwardSignal(cmd, syscall.SIGTERM)
+				killTimer = time.NewTimer(killGracePeriod)
+				kill = killTimer.C
+			case <-kill:
+				forwardSignal(cmd, syscall.SIGKILL)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	restoreTerminal()
+	close(done)
+	lastSignal := <-forwarded
+	if timedOut.Load() {
+		fmt.Fprintf(os.Stderr, "gx: command timed out after %s and was killed\n", opts.timeout)
+		return exitTimedOut, nil
+	}
 	if err == nil {
 		// Command succeeded
 		return 0, nil
@@ -262,7 +1365,16 @@ func executeCommand(command string) (int, error) {
 
 	// Check if it's an ExitError (command ran but failed)
 	if exitError, ok := err.(*exec.ExitError); ok {
-		return exitError.ExitCode(), nil
+		code := exitError.ExitCode()
+		// Killed by a signal: report it the way shells do (128 + signal
+		// number). On a terminal Ctrl-C reaches the command directly, so
+		// the signal comes from its status rather than from gx
+		if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			code = 128 + int(status.Signal())
+		} else if s, ok := lastSignal.(syscall.Signal); ok && code == -1 {
+			code = 128 + int(s)
+		}
+		return code, nil
 	}
 
 	// Some other error occurred (couldn't start command, etc.)
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/nealhardesty/gx/internal/cache"
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// runEnv carries the state shared by the main flow and subcommands.
+type runEnv struct {
+	histMgr    *history.Manager
+	cacheStore *cache.Store
+	logger     *slog.Logger
+	clientCfg  gemini.Config
+}
+
+// subcommand handles `gx <name> [args...]` and returns the exit code.
+type subcommand func(ctx context.Context, env *runEnv, args []string) int
+
+// subcommands maps the first positional argument to its handler.
+// Anything else is treated as a natural-language prompt.
+var subcommands = map[string]subcommand{
+	"bat":    runBat,
+	"bench":  runBench,
+	"cron":   runCron,
+	"docker": runDocker,
+	"doctor": runDoctor,
+	"expand": runExpand,
+	"init":   runInit,
+	"last":   runLast,
+	"man":    runMan,
+	"models": runModels,
+	"replay": runReplay,
+	"target": runTarget,
+	"trust":  runTrust,
+	"undo":   runUndo,
+	"why":    runWhy,
+}
+
+// lookupSubcommand returns the handler for args[0]. "last" and "trust" often
+// start a prompt ("last 10 commits", "trust the new CA certificate"), so
+// they are only subcommands with the arguments they accept.
+func lookupSubcommand(args []string) (subcommand, bool) {
+	sub, ok := subcommands[args[0]]
+	switch {
+	case !ok:
+		return nil, false
+	case args[0] == "last":
+		ok = isLastCommand(args[1:])
+	case args[0] == "trust":
+		ok = len(args) == 1
+	}
+	return sub, ok
+}
+
+// errDeclined is returned by a generateChecked check when the model explained
+// (usually in a comment) that it can't do what was asked; it is not retried.
+var errDeclined = errors.New("request declined by the model")
+
+// generateChecked generates a response and passes it through check, which
+// validates and may rewrite it. When check fails, it re-prompts once with the
+// rejection reason, on the escalation model if one is configured, so a
+// request makes at most three generations even when Generate escalates too.
+// The raw response is returned alongside for error reporting.
+func generateChecked(ctx context.Context, env *runEnv, client *gemini.Client, prompt string, histContext []history.Entry, check func(string) (string, error)) (checked, raw string, err error) {
+	raw, err = client.Generate(ctx, prompt, histContext)
+	if err != nil {
+		return "", raw, err
+	}
+	checked, err = check(raw)
+	if err == nil || errors.Is(err, errDeclined) {
+		return checked, raw, err
+	}
+
+	// One fresh attempt with the validation error usually fixes it
+	env.logger.Info("generated output is invalid, re-prompting", "error", err)
+	retry := fmt.Sprintf("%s\n\nA previous answer was rejected (%v):\n%s\nReturn a corrected version.", prompt, err, raw)
+	if client.CanEscalate() {
+		raw, err = client.GenerateEscalated(ctx, retry, histContext)
+	} else {
+		raw, err = client.Generate(ctx, retry, histContext)
+	}
+	if err != nil {
+		return "", raw, err
+	}
+	checked, err = check(raw)
+	return checked, raw, err
+}
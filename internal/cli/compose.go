@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// composeTemplate is shown below the empty first line in the editor.
+const composeTemplate = `
+# Describe what you want a command for; several paragraphs are fine.
+# Lines starting with # are ignored, and an empty request cancels.
+`
+
+// composePrompt lets the user write the request when gx runs with no
+// arguments on a terminal: in $VISUAL or $EDITOR when one is set, otherwise
+// by typing lines until end of input. An empty request is errCancelled.
+func composePrompt() (string, error) {
+	editor := userEditor()
+
+	var prompt string
+	var err error
+	if editor != "" {
+		prompt, err = composeInEditor(editor)
+	} else {
+		prompt, err = composeInTerminal()
+	}
+	if err != nil {
+		return "", err
+	}
+	if prompt == "" {
+		return "", errCancelled
+	}
+	return prompt, nil
+}
+
+// userEditor returns $VISUAL, then $EDITOR, or "" when neither is set.
+func userEditor() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	return os.Getenv("EDITOR")
+}
+
+// composeInEditor opens a temporary file in the editor and returns what was
+// written, without comment lines.
+func composeInEditor(editor string) (string, error) {
+	text, err := editText(editor, "gx-prompt-*.txt", composeTemplate)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// editText opens content in the editor as a temporary file named after
+// pattern (see os.CreateTemp) and returns the saved text. The editor may
+// include arguments, e.g. "code --wait".
+func editText(editor, pattern, content string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", args[0], err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read temporary file: %w", err)
+	}
+	return string(data), nil
+}
+
+// editCommand opens a generated command in the user's editor (vi, or
+// Notepad on Windows, when neither $VISUAL nor $EDITOR is set) and returns
+// the saved command. Saving an empty file is errCancelled.
+func editCommand(command string) (string, error) {
+	editor := userEditor()
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	pattern := "gx-command-*.sh"
+	if runtime.GOOS == "windows" {
+		pattern = "gx-command-*.ps1"
+	}
+	edited, err := editText(editor, pattern, command+"\n")
+	if err != nil {
+		return "", err
+	}
+	edited = strings.TrimSpace(strings.ReplaceAll(edited, "\r\n", "\n"))
+	if edited == "" {
+		return "", errCancelled
+	}
+	return edited, nil
+}
+
+// composeInTerminal reads a multi-line request from the terminal until end
+// of input.
+func composeInTerminal() (string, error) {
+	eof := "Ctrl-D"
+	if runtime.GOOS == "windows" {
+		eof = "Ctrl-Z then Enter"
+	}
+	fmt.Fprintf(os.Stderr, "Describe what you want a command for; finish with %s on an empty line (set $EDITOR to use an editor).\n", eof)
+	data, err := io.ReadAll(bufio.NewReader(os.Stdin))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/shell"
+)
+
+// errRefused is returned when the safety check stops a high-risk command.
+var errRefused = errors.New("high-risk command not run")
+
+// confirmDestructive makes the user type a phrase before a high-risk command
+// runs, like terraform destroy: the affected path or resource when there is
+// one, otherwise "yes". A command is high risk when the model rated it so or
+// it deletes or overwrites data (see shell.DestructiveAction). A mismatch
+// or no terminal to ask on returns errRefused; Ctrl-C or end of input
+// returns errCancelled; force skips the check.
+func confirmDestructive(command, risk string, force bool) error {
+	d, destructive := shell.DestructiveAction(command)
+	if force || (!destructive && risk != gemini.RiskHigh) {
+		return nil
+	}
+
+	reason := "the model rates it high risk"
+	if destructive {
+		reason = d.Reason
+	}
+	phrase := d.Target
+	if phrase == "" {
+		phrase = "yes"
+	}
+
+	fmt.Fprintf(os.Stderr, "\nThis command is destructive (%s).\n", reason)
+	answer, err := readPhrase(fmt.Sprintf("Type %q to run it: ", phrase))
+	if errors.Is(err, errCancelled) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v (use --force to run high-risk commands unattended)", errRefused, err)
+	}
+	if answer != phrase {
+		fmt.Fprintln(os.Stderr, "Confirmation didn't match.")
+		return errRefused
+	}
+	return nil
+}
+
+// readPhrase is readLine for answers that decide whether a command runs,
+// such as the typed confirmation, except that Ctrl-C or end of input with
+// nothing typed returns errCancelled instead of ending gx or counting as a
+// refusal.
+func readPhrase(prompt string) (string, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return "", err
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	type result struct {
+		line string
+		err  error
+	}
+	read := make(chan result, 1)
+	fmt.Fprint(os.Stderr, prompt)
+	go func() {
+		line, err := bufio.NewReader(tty).ReadString('\n')
+		read <- result{line, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr)
+		return "", errCancelled
+	case r := <-read:
+		if r.err == io.EOF && r.line == "" {
+			fmt.Fprintln(os.Stderr)
+			return "", errCancelled
+		}
+		if r.err != nil && r.err != io.EOF {
+			return "", fmt.Errorf("failed to read input: %w", r.err)
+		}
+		return strings.TrimSpace(r.line), nil
+	}
+}
+
+// defaultAbortWindow is how long gxx shows its risk banner before running a
+// risky command.
+const defaultAbortWindow = 2 * time.Second
+
+// needsTypedConfirmation reports whether confirmDestructive will ask for a
+// phrase before running command.
+func needsTypedConfirmation(command, risk string, force bool) bool {
+	_, destructive := shell.DestructiveAction(command)
+	return !force && (destructive || risk == gemini.RiskHigh)
+}
+
+// riskReason classifies command for gxx's risk banner: why it is risky, or
+// "" for a benign command. Cached answers have no model rating, so the
+// local checks matter even when risk is empty.
+func riskReason(command, risk string) string {
+	if d, ok := shell.DestructiveAction(command); ok {
+		return d.Reason
+	}
+	switch {
+	case risk == gemini.RiskHigh:
+		return "the model rates it high risk"
+	case shell.StripSudo(command) != command:
+		return "it runs as root"
+	case shell.ElevationReason(command) != "":
+		return shell.ElevationReason(command) + " needs root"
+	case risk == gemini.RiskMedium:
+		return "the model rates it medium risk"
+	}
+	return ""
+}
+
+// localRisk rates a command without the model, for a command the user
+// edited after the model rated it: high when it deletes or overwrites data,
+// medium when it runs as root, otherwise low.
+func localRisk(command string) string {
+	if _, ok := shell.DestructiveAction(command); ok {
+		return gemini.RiskHigh
+	}
+	if shell.StripSudo(command) != command || shell.ElevationReason(command) != "" {
+		return gemini.RiskMedium
+	}
+	return gemini.RiskLow
+}
+
+// riskBanner is gxx's pause before a risky command that would otherwise run
+// unattended: a prominent warning, then window to press Ctrl-C, which
+// returns errCancelled. Benign commands run without delay. GX_ABORT_WINDOW
+// overrides the window (0 warns without waiting); without a terminal there
+// is no one to abort, so it only warns.
+func riskBanner(ctx context.Context, command, risk string) error {
+	reason := riskReason(command, risk)
+	if reason == "" {
+		return nil
+	}
+	window := defaultAbortWindow
+	if d, err := time.ParseDuration(os.Getenv("GX_ABORT_WINDOW")); err == nil && d >= 0 {
+		window = d
+	}
+	if !isTerminal(os.Stderr) {
+		window = 0
+	}
+
+	banner := fmt.Sprintf("!!! RISKY COMMAND: %s !!!", reason)
+	if isTerminal(os.Stderr) && os.Getenv("NO_COLOR") == "" {
+		// Bold white on red
+		banner = "\033[1;97;41m " + banner + " \033[0m"
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", banner)
+	if window == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Running in %s; press Ctrl-C to abort.\n", window)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case <-time.After(window):
+		return nil
+	case <-ctx.Done():
+		return errCancelled
+	}
+}
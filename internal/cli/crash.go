@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/version"
+)
+
+// secretAssignment matches NAME=value pairs whose name suggests a
+// credential, in flags or in the prompt text.
+var secretAssignment = regexp.MustCompile(`(?i)\b([A-Z0-9_]*(?:TOKEN|SECRET|PASSWORD|PASSWD|API_?KEY|PRIVATE_KEY|CREDENTIALS?)[A-Z0-9_]*)=\S+`)
+
+// recoverCrash turns a panic in Run into a crash report and a short message
+// instead of a goroutine dump. It must be deferred directly by Run.
+func recoverCrash(v string, code *int) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*code = exitCrashed
+	stack := debug.Stack()
+	path, err := writeCrashReport(v, r, stack)
+	fmt.Fprintf(os.Stderr, "\ngx crashed unexpectedly: %v\n", r)
+	if err != nil {
+		// Without a report, the stack is the only record
+		fmt.Fprintf(os.Stderr, "Failed to save a crash report (%v); stack trace:\n%s", err, stack)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "A crash report was saved to %s\n", path)
+	fmt.Fprintln(os.Stderr, "Please attach it to an issue at https://github.com/nealhardesty/gx/issues")
+}
+
+// writeCrashReport saves the panic, stack, version, and sanitized arguments
+// to a new file in the crash directory and returns its path.
+func writeCrashReport(v string, r any, stack []byte) (string, error) {
+	dir, err := crashDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %w", err)
+	}
+
+	now := time.Now()
+	build := version.Build()
+	var b strings.Builder
+	fmt.Fprintf(&b, "gx crash report\n\n")
+	fmt.Fprintf(&b, "time:     %s\n", now.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "version:  %s\n", v)
+	fmt.Fprintf(&b, "commit:   %s\n", orUnknown(build.Commit))
+	fmt.Fprintf(&b, "go:       %s %s\n", build.GoVersion, build.Platform)
+	fmt.Fprintf(&b, "args:     %q\n", sanitizeArgs(os.Args[1:]))
+	fmt.Fprintf(&b, "panic:    %v\n\n", r)
+	b.Write(stack)
+
+	path := filepath.Join(dir, fmt.Sprintf("gx-%s-%d.txt", now.Format("20060102-150405"), os.Getpid()))
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// crashDir returns $XDG_STATE_HOME/gx/crash, by default
+// ~/.local/state/gx/crash.
+func crashDir() (string, error) {
+	state := os.Getenv("XDG_STATE_HOME")
+	if state == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		state = filepath.Join(homeDir, ".local", "state")
+	}
+	return filepath.Join(state, "gx", "crash"), nil
+}
+
+// sanitizeArgs redacts --env values, which often carry credentials, and
+// credential-like NAME=value pairs anywhere in the arguments.
+func sanitizeArgs(args []string) []string {
+	sanitized := make([]string, len(args))
+	redactNext := false
+	for i, arg := range args {
+		switch {
+		case redactNext:
+			arg = redactValue(arg)
+			redactNext = false
+		case arg == "-env" || arg == "--env":
+			redactNext = true
+		case strings.HasPrefix(arg, "-env=") || strings.HasPrefix(arg, "--env="):
+			flagName, value, _ := strings.Cut(arg, "=")
+			arg = flagName + "=" + redactValue(value)
+		default:
+			arg = secretAssignment.ReplaceAllString(arg, "$1=REDACTED")
+		}
+		sanitized[i] = arg
+	}
+	return sanitized
+}
+
+// redactValue keeps the name of a KEY=VALUE pair and drops the value.
+func redactValue(pair string) string {
+	name, _, _ := strings.Cut(pair, "=")
+	return name + "=REDACTED"
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/cron"
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// cronPreviewRuns is how many upcoming run times are shown for a crontab line.
+const cronPreviewRuns = 3
+
+// runCron implements `gx cron [--systemd] [--install] <description>`:
+// generate a validated crontab line (or systemd timer) and optionally install it.
+func runCron(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("cron", flag.ContinueOnError)
+	systemdFlag := fs.Bool("systemd", false, "Generate a systemd user timer instead of a crontab line")
+	installFlag := fs.Bool("install", false, "Install the result after confirmation")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx cron [--systemd] [--install] <description>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	description := strings.Join(fs.Args(), " ")
+	if description == "" {
+		fs.Usage()
+		return 1
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = gemini.ModeCron
+	validate := validateCrontab
+	if *systemdFlag {
+		cfg.Mode = gemini.ModeSystemdTimer
+		validate = validateTimer
+	}
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	result, raw, err := generateChecked(ctx, env, client, description, nil, func(result string) (string, error) {
+		return result, validate(ctx, result)
+	})
+	if errors.Is(err, errDeclined) {
+		fmt.Fprintln(os.Stderr, raw)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if raw != "" {
+			fmt.Fprintln(os.Stderr, indentContinuation("  "+raw, "  "))
+		}
+		return 1
+	}
+
+	fmt.Println(result)
+	if !*systemdFlag {
+		printNextRuns(result)
+	}
+
+	if !*installFlag {
+		return 0
+	}
+
+	if *systemdFlag {
+		err = installTimer(ctx, result)
+	} else {
+		err = installCrontab(ctx, result)
+	}
+	if errors.Is(err, errCancelled) {
+		fmt.Fprintln(os.Stderr, "Cancelled.")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// validateCrontab checks that result is a single well-formed crontab line.
+func validateCrontab(_ context.Context, result string) error {
+	if strings.HasPrefix(result, "#") && !strings.Contains(result, "\n") {
+		return errDeclined
+	}
+	if strings.Contains(result, "\n") {
+		return fmt.Errorf("expected one crontab line, got %d lines", strings.Count(result, "\n")+1)
+	}
+	_, _, err := cron.ParseLine(result)
+	return err
+}
+
+// validateTimer checks the unit structure and, when systemd-analyze is
+// installed, each OnCalendar expression.
+func validateTimer(ctx context.Context, result string) error {
+	timer, err := cron.ParseTimer(result)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("systemd-analyze"); err != nil {
+		return nil
+	}
+	for _, expr := range timer.OnCalendar {
+		out, err := exec.CommandContext(ctx, "systemd-analyze", "calendar", expr).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("invalid OnCalendar=%s: %s", expr, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// printNextRuns prints the upcoming run times of a crontab line to stderr.
+func printNextRuns(line string) {
+	schedule, _, err := cron.ParseLine(line)
+	if err != nil {
+		return
+	}
+	if schedule.Reboot() {
+		fmt.Fprintln(os.Stderr, "Runs: at every boot")
+		return
+	}
+	t := time.Now()
+	var runs []string
+	for i := 0; i < cronPreviewRuns; i++ {
+		if t = schedule.Next(t); t.IsZero() {
+			break
+		}
+		runs = append(runs, t.Format("Mon Jan 2 15:04"))
+	}
+	if len(runs) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: this schedule never runs")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Next runs: %s\n", strings.Join(runs, ", "))
+}
+
+// installCrontab appends line to the user's crontab after confirmation.
+func installCrontab(ctx context.Context, line string) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("crontab is not available on Windows")
+	}
+
+	// crontab -l fails when the user has no crontab yet; treat that as empty
+	current, _ := exec.CommandContext(ctx, "crontab", "-l").Output()
+	for _, existing := range strings.Split(string(current), "\n") {
+		if strings.TrimSpace(existing) == line {
+			fmt.Fprintln(os.Stderr, "Already in your crontab.")
+			return nil
+		}
+	}
+
+	if err := confirm("Add this line to your crontab? [y/N] "); err != nil {
+		return err
+	}
+
+	updated := string(current)
+	if updated != "" && !strings.HasSuffix(updated, "\n") {
+		updated += "\n"
+	}
+	updated += line + "\n"
+
+	cmd := exec.CommandContext(ctx, "crontab", "-")
+	cmd.Stdin = strings.NewReader(updated)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("crontab rejected the entry: %s", strings.TrimSpace(stderr.String()))
+	}
+	fmt.Fprintln(os.Stderr, "Installed. Review with: crontab -l")
+	return nil
+}
+
+// installTimer writes the units to ~/.config/systemd/user and enables the
+// timer after confirmation. Existing unit files are never overwritten.
+func installTimer(ctx context.Context, result string) error {
+	timer, err := cron.ParseTimer(result)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("systemctl"); err != nil {
+		return fmt.Errorf("systemctl not found; systemd timers need a systemd-based system")
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	unitDir := filepath.Join(configDir, "systemd", "user")
+	for _, u := range []cron.Unit{timer.Service, timer.Timer} {
+		if _, err := os.Stat(filepath.Join(unitDir, u.Name)); err == nil {
+			return fmt.Errorf("%s already exists in %s", u.Name, unitDir)
+		}
+	}
+
+	if err := confirm(fmt.Sprintf("Install %s and %s to %s and enable the timer? [y/N] ", timer.Service.Name, timer.Timer.Name, unitDir)); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", unitDir, err)
+	}
+	for _, u := range []cron.Unit{timer.Service, timer.Timer} {
+		if err := os.WriteFile(filepath.Join(unitDir, u.Name), []byte(u.Content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", u.Name, err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--user", "daemon-reload"},
+		{"--user", "enable", "--now", timer.Timer.Name},
+	} {
+		if out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput(); err != nil {
+			return fmt.Errorf("systemctl %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Installed. Check with: systemctl --user list-timers %s\n", timer.Timer.Name)
+	return nil
+}
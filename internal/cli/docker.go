@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/buildfile"
+	"github.com/nealhardesty/gx/internal/diff"
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// composeNames are the compose file names docker compose looks for, in order.
+var composeNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// dockerInstructions are the Dockerfile instructions accepted by BuildKit.
+var dockerInstructions = map[string]bool{
+	"ADD": true, "ARG": true, "CMD": true, "COPY": true, "ENTRYPOINT": true,
+	"ENV": true, "EXPOSE": true, "FROM": true, "HEALTHCHECK": true, "LABEL": true,
+	"MAINTAINER": true, "ONBUILD": true, "RUN": true, "SHELL": true,
+	"STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+}
+
+// runDocker implements `gx docker [--compose] [-o file] <description>`:
+// inspect the project with the read-only tools, generate a Dockerfile (or
+// compose file), show a diff against the existing file, and write it after
+// confirmation.
+func runDocker(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("docker", flag.ContinueOnError)
+	composeFlag := fs.Bool("compose", false, "Generate a docker-compose file instead of a Dockerfile")
+	outputFlag := fs.String("o", "", "File to write (default: Dockerfile, or the existing compose file)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx docker [--compose] [-o file] <description>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	description := strings.Join(fs.Args(), " ")
+	if description == "" {
+		fs.Usage()
+		return 1
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = gemini.ModeDockerfile
+	check := checkDockerfile
+	path := "Dockerfile"
+	if *composeFlag {
+		cfg.Mode = gemini.ModeCompose
+		check = checkCompose
+		if path = buildfile.Find(composeNames); path == "" {
+			path = composeNames[0]
+		}
+	}
+	if *outputFlag != "" {
+		path = *outputFlag
+	}
+	if cfg.NoTools {
+		fmt.Fprintln(os.Stderr, "Warning: tools are disabled (-n), so the project can't be inspected")
+	}
+
+	var existing string
+	if data, err := os.ReadFile(path); err == nil {
+		existing = string(data)
+	} else if !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	prompt := description
+	if existing != "" {
+		prompt += fmt.Sprintf("\n\nThe current %s is below; keep what still applies:\n%s", path, existing)
+	}
+
+	content, raw, err := generateChecked(ctx, env, client, prompt, nil, func(result string) (string, error) {
+		return strings.TrimSpace(result) + "\n", check(result)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if raw != "" {
+			fmt.Fprintln(os.Stderr, indentContinuation("  "+raw, "  "))
+		}
+		return 1
+	}
+
+	// Piped or redirected: just emit the file
+	if !isTerminal(os.Stdout) {
+		fmt.Print(content)
+		return 0
+	}
+
+	d := diff.Unified("a/"+path, "b/"+path, existing, content)
+	if d == "" {
+		fmt.Fprintf(os.Stderr, "%s is already up to date\n", path)
+		return 0
+	}
+	fmt.Fprint(os.Stderr, d)
+
+	if err := confirm(fmt.Sprintf("Write %s? [y/N] ", path)); err != nil {
+		if errors.Is(err, errCancelled) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	return 0
+}
+
+// checkDockerfile checks that every instruction is known and that the first
+// one is FROM (or an ARG used by FROM).
+func checkDockerfile(content string) error {
+	sawFrom := false
+	continued := false
+	heredoc := ""
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case heredoc != "":
+			if trimmed == heredoc {
+				heredoc = ""
+			}
+			continue
+		case continued:
+			continued = strings.HasSuffix(trimmed, "\\")
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		}
+		continued = strings.HasSuffix(trimmed, "\\")
+
+		keyword := strings.ToUpper(strings.Fields(trimmed)[0])
+		if !dockerInstructions[keyword] {
+			return fmt.Errorf("line %d: unknown instruction %q", i+1, strings.Fields(trimmed)[0])
+		}
+		if !sawFrom && keyword != "FROM" && keyword != "ARG" {
+			return fmt.Errorf("line %d: %s before the first FROM", i+1, keyword)
+		}
+		if keyword == "FROM" {
+			sawFrom = true
+		}
+		if j := strings.Index(trimmed, "<<"); j >= 0 {
+			heredoc = strings.Trim(strings.Fields(trimmed[j+2:] + " x")[0], `-"'`)
+		}
+	}
+	if !sawFrom {
+		return fmt.Errorf("no FROM instruction")
+	}
+	return nil
+}
+
+// checkCompose checks that content looks like a compose file: a top-level
+// services key with at least one service, indented with spaces.
+func checkCompose(content string) error {
+	lines := strings.Split(content, "\n")
+	services := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return fmt.Errorf("line %d: YAML does not allow tab indentation", i+1)
+		}
+		if strings.TrimRight(line, " ") == "services:" {
+			services = i
+		}
+	}
+	if services < 0 {
+		return fmt.Errorf("no top-level services key")
+	}
+	for _, line := range lines[services+1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if line[0] != ' ' {
+			break
+		}
+		return nil
+	}
+	return fmt.Errorf("services is empty")
+}
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/shell"
+)
+
+// doctorTimeout bounds each network check.
+const doctorTimeout = 10 * time.Second
+
+// checkStatus is the outcome of one gx doctor check.
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "skip"
+)
+
+// doctorReport prints check results and remembers whether any failed.
+type doctorReport struct {
+	failed bool
+}
+
+// report prints one check result, with a fix on the next line when given.
+func (r *doctorReport) report(status checkStatus, name, detail, fix string) {
+	if status == checkFail {
+		r.failed = true
+	}
+	fmt.Printf("%-7s %-12s %s\n", "["+string(status)+"]", name, detail)
+	if fix != "" {
+		fmt.Printf("%-7s %-12s fix: %s\n", "", "", fix)
+	}
+}
+
+// runDoctor implements `gx doctor`: check credentials, project, network,
+// model availability, shell detection, and local files, printing fixes.
+func runDoctor(ctx context.Context, env *runEnv, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: gx doctor")
+		return 1
+	}
+
+	r := &doctorReport{}
+	cfg := env.clientCfg
+	location := gemini.ResolveLocation(cfg.Location)
+	model := gemini.ResolveModel(cfg.Model)
+
+	// Credentials, including a token refresh to prove they still work
+	credsOK := false
+	creds, err := gemini.FindCredentials(ctx)
+	if err != nil {
+		r.report(checkFail, "credentials", err.Error(), "gcloud auth application-default login")
+	} else if _, err := creds.TokenSource.Token(); err != nil {
+		r.report(checkFail, "credentials", fmt.Sprintf("%s: cannot get a token: %v", gemini.CredentialsSource(), err), "gcloud auth application-default login")
+	} else {
+		credsOK = true
+		r.report(checkOK, "credentials", gemini.CredentialsSource(), "")
+	}
+
+	// Project
+	project := ""
+	if !credsOK {
+		r.report(checkSkip, "project", "needs credentials", "")
+	} else if project, err = gemini.ResolveProject(creds); err != nil {
+		r.report(checkFail, "project", err.Error(), "gcloud config set project PROJECT_ID (or export GOOGLE_CLOUD_PROJECT)")
+	} else {
+		r.report(checkOK, "project", project, "")
+	}
+
+	// Network reachability of the endpoint (or the proxy in front of it)
+	endpoint := gemini.ResolveEndpoint(location)
+	networkOK := checkNetwork(r, endpoint)
+
+	// Model availability in the region
+	switch {
+	case project == "" || !networkOK:
+		r.report(checkSkip, "model", fmt.Sprintf("%s in %s needs a project and network access", model, location), "")
+	default:
+		r.report(checkModel(ctx, cfg, model, location))
+	}
+
+	// Shell detection
+	shellName := gemini.DetectShell()
+	detail := fmt.Sprintf("%s on %s", shellName, gemini.DetectPlatform())
+	switch {
+	case shell.IsPowerShell(shellName):
+		if ps := shell.DetectPowerShell(); ps.Major > 0 {
+			detail += fmt.Sprintf(" (PowerShell %d)", ps.Major)
+		}
+		r.report(checkOK, "shell", detail, "")
+	case runtime.GOOS != "windows" && os.Getenv("SHELL") == "":
+		r.report(checkWarn, "shell", detail+" (SHELL is unset, assuming bash)", "export SHELL=$(command -v bash) in your profile, or your actual shell")
+	default:
+		if v, ok := shell.DetectVersion(shellName); ok {
+			detail = fmt.Sprintf("%s %s on %s", shellName, v.Full, gemini.DetectPlatform())
+		}
+		r.report(checkOK, "shell", detail, "")
+	}
+
+	// History and staging files
+	if err := env.histMgr.Check(); err != nil {
+		r.report(checkWarn, "history", err.Error(), "gx -c (clears history, staged commands, and cache)")
+	} else {
+		r.report(checkOK, "history", env.histMgr.HistoryPath(), "")
+	}
+
+	if r.failed {
+		return 1
+	}
+	return 0
+}
+
+// checkNetwork reports whether a TCP connection to the endpoint, or to the
+// HTTPS proxy that would carry its traffic, can be opened.
+func checkNetwork(r *doctorReport, endpoint string) bool {
+	target := endpoint
+	via := ""
+	req, _ := http.NewRequest(http.MethodGet, "https://"+endpoint, nil)
+	if proxyURL, err := http.ProxyFromEnvironment(req); err == nil && proxyURL != nil {
+		target = proxyURL.Host
+		if proxyURL.Port() == "" {
+			target = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+		via = " via proxy " + proxyURL.Host
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", target, doctorTimeout)
+	if err != nil {
+		fix := "check your connection, DNS, and firewall"
+		if via != "" {
+			fix = "check HTTPS_PROXY / NO_PROXY"
+		}
+		r.report(checkFail, "network", fmt.Sprintf("cannot reach %s%s: %v", endpoint, via, err), fix)
+		return false
+	}
+	conn.Close()
+	r.report(checkOK, "network", fmt.Sprintf("%s%s (%s)", endpoint, via, time.Since(start).Round(time.Millisecond)), "")
+	return true
+}
+
+// checkModel asks the model to count the tokens of a tiny prompt, which
+// proves the model exists in the region and the project may call it.
+func checkModel(ctx context.Context, cfg gemini.Config, model, location string) (checkStatus, string, string, string) {
+	name := fmt.Sprintf("%s in %s", model, location)
+	cfg.NoTools = true
+	cfg.RateLimiter = nil
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		return checkFail, "model", fmt.Sprintf("%s: %v", name, err), "re-run with --debug for details"
+	}
+	defer client.Close()
+
+	if err := client.Ping(ctx); err != nil {
+		return checkFail, "model", fmt.Sprintf("%s: %v", name, err), client.PingRemedy(err)
+	}
+	return checkOK, "model", name, ""
+}
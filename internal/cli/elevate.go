@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/shell"
+)
+
+// elevate warns when command looks like it needs root and, where sudo is
+// available, offers to run it through sudo. It returns the argv to execute:
+// argv itself, or argv wrapped in sudo. Answering q returns errCancelled.
+func elevate(command string, argv []string, opts execOptions) ([]string, error) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		return argv, nil
+	}
+	reason := shell.ElevationReason(command)
+	if reason == "" {
+		return argv, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: this command needs root privileges (%s)\n", reason)
+	sudo, err := exec.LookPath("sudo")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sudo is not installed; running as is")
+		return argv, nil
+	}
+
+	answer, err := readLine("Run it with sudo? [y/N/q] ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v; running as is\n", err)
+		return argv, nil
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return sudoArgv(sudo, argv, opts), nil
+	case "q", "quit":
+		return nil, errCancelled
+	}
+	return argv, nil
+}
+
+// sudoArgv wraps argv in sudo. sudo resets the environment, so variables
+// from --env and ~/.gxenv are named in --preserve-env; their values come from
+// cmd.Env (see commandEnv) rather than argv, where ps would show them.
+func sudoArgv(sudo string, argv []string, opts execOptions) []string {
+	wrapped := []string{sudo}
+	if len(opts.env) > 0 {
+		keys := make([]string, 0, len(opts.env))
+		seen := make(map[string]bool)
+		for _, kv := range opts.env {
+			key, _, _ := strings.Cut(kv, "=")
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		wrapped = append(wrapped, "--preserve-env="+strings.Join(keys, ","))
+	}
+	wrapped = append(wrapped, "--")
+	return append(wrapped, argv...)
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// syntaxCheckShells are interpreters whose -n flag parses a script without running it.
+var syntaxCheckShells = map[string]bool{"sh": true, "bash": true, "dash": true, "ksh": true, "zsh": true}
+
+// runExpand implements `gx expand [-o file] [-]`: rewrite the staged
+// one-liner (or stdin) as a readable script with variables, error handling,
+// and comments.
+func runExpand(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("expand", flag.ContinueOnError)
+	outputFlag := fs.String("o", "", "Write the script to this file (made executable, except batch files)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx expand [-o file] [-]")
+		fmt.Fprintln(os.Stderr, "Expands the staged command, or stdin with -")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	var command string
+	switch {
+	case fs.NArg() == 0:
+		staged, err := env.histMgr.GetStagedCommand()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		command = staged
+	case fs.NArg() == 1 && fs.Arg(0) == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			return 1
+		}
+		command = string(data)
+	default:
+		fs.Usage()
+		return 1
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		fmt.Fprintln(os.Stderr, "Error: nothing to expand")
+		return 1
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = gemini.ModeExpandScript
+	cfg.NoTools = true
+	check := func(result string) (string, error) {
+		result = strings.TrimSpace(result) + "\n"
+		return result, checkScriptSyntax(ctx, result)
+	}
+	perm := os.FileMode(0755)
+	if gemini.ResolveShell(cfg.Shell) == "cmd" {
+		// cmd.exe one-liners expand into batch files
+		cfg.Mode = gemini.ModeBatchScript
+		check = checkBatchScript
+		perm = 0644
+	}
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	prompt := fmt.Sprintf("Expand this command into a script:\n\n%s", command)
+	script, raw, err := generateChecked(ctx, env, client, prompt, nil, check)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if raw != "" {
+			fmt.Fprintln(os.Stderr, indentContinuation("  "+raw, "  "))
+		}
+		return 1
+	}
+
+	if *outputFlag == "" {
+		fmt.Print(script)
+		return 0
+	}
+	return writeScript(*outputFlag, script, perm)
+}
+
+// checkScriptSyntax parses a script with `<shell> -n` when its shebang names
+// a POSIX-style shell that is installed. Other scripts are not checked.
+func checkScriptSyntax(ctx context.Context, script string) error {
+	interpreter := shebangInterpreter(script)
+	if !syntaxCheckShells[interpreter] {
+		return nil
+	}
+	if _, err := exec.LookPath(interpreter); err != nil {
+		return nil
+	}
+
+	f, err := os.CreateTemp("", "gx-expand-*.sh")
+	if err != nil {
+		return nil
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(script)
+	f.Close()
+	if err != nil {
+		return nil
+	}
+
+	out, err := exec.CommandContext(ctx, interpreter, "-n", f.Name()).CombinedOutput()
+	if err != nil {
+		msg := strings.ReplaceAll(strings.TrimSpace(string(out)), f.Name(), "script")
+		return fmt.Errorf("%s syntax error: %s", interpreter, msg)
+	}
+	return nil
+}
+
+// shebangInterpreter returns the interpreter named on a script's #! line
+// ("bash" for both "#!/bin/bash" and "#!/usr/bin/env bash"), or "".
+func shebangInterpreter(script string) string {
+	first, _, _ := strings.Cut(script, "\n")
+	if !strings.HasPrefix(first, "#!") {
+		return ""
+	}
+	fields := strings.Fields(strings.TrimPrefix(first, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env options such as -S
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				return filepath.Base(f)
+			}
+		}
+		return ""
+	}
+	return interpreter
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultPowerShellKey is the PSReadLine chord that turns the request on the
+// command line into a command.
+const defaultPowerShellKey = "Ctrl+g"
+
+// powerShellModule is the script `gx init powershell` prints. It loads as a
+// dynamic module so Invoke-Gx and its alias can be removed with
+// Remove-Module gx. %[1]s is the key handler's chord.
+//
+// Outside a key handler, PSReadLine only accepts input while it reads the
+// next line, so Invoke-Gx inserts the command from a one-shot OnIdle event,
+// which fires once the prompt is waiting.
+const powerShellModule = `# gx integration for PowerShell. Load it from $PROFILE with:
+#   Invoke-Expression (& gx init powershell | Out-String)
+New-Module -Name gx -ScriptBlock {
+    function Invoke-Gx {
+        <#
+        .SYNOPSIS
+        Generate a command with gx and put it on the next prompt line to review and run.
+        .EXAMPLE
+        Invoke-Gx find files over 100MB
+        #>
+        param([Parameter(ValueFromRemainingArguments = $true)][string[]]$Request)
+        # -x and -y run the command, so its output isn't a command to insert
+        if ($Request -contains '-x' -or $Request -contains '-y') {
+            & gx @Request
+            return
+        }
+        $command = (& gx @Request | Out-String).Trim()
+        if ($LASTEXITCODE -ne 0 -or -not $command) { return }
+        if (-not (Get-Module PSReadLine)) { return $command }
+        $null = Register-EngineEvent -SourceIdentifier PowerShell.OnIdle -MaxTriggerCount 1 -MessageData $command -Action {
+            [Microsoft.PowerShell.PSConsoleReadLine]::Insert($Event.MessageData)
+        }
+    }
+    Set-Alias -Name gxi -Value Invoke-Gx
+
+    if (Get-Module PSReadLine) {
+        Set-PSReadLineKeyHandler -Chord '%[1]s' -BriefDescription GxGenerate -Description 'Replace the request on the command line with the command gx generates' -ScriptBlock {
+            $line = $null
+            $cursor = $null
+            [Microsoft.PowerShell.PSConsoleReadLine]::GetBufferState([ref]$line, [ref]$cursor)
+            if (-not $line.Trim()) { return }
+            # Keep gx's messages below the request instead of over it
+            Write-Host ''
+            $command = (& gx $line | Out-String).Trim()
+            [Microsoft.PowerShell.PSConsoleReadLine]::InvokePrompt()
+            if ($LASTEXITCODE -eq 0 -and $command) {
+                [Microsoft.PowerShell.PSConsoleReadLine]::RevertLine()
+                [Microsoft.PowerShell.PSConsoleReadLine]::Insert($command)
+            }
+        }
+    }
+
+    Export-ModuleMember -Function Invoke-Gx -Alias gxi
+} | Import-Module -Global
+`
+
+// runInit implements `gx init <shell>`: print the shell integration script
+// to load from the shell's profile. Only PowerShell is supported so far.
+func runInit(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	keyFlag := fs.String("key", defaultPowerShellKey, "PSReadLine chord that replaces the request on the command line with a command")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx init [--key chord] powershell")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	switch shell := strings.ToLower(fs.Arg(0)); shell {
+	case "powershell", "pwsh":
+		if strings.ContainsAny(*keyFlag, "'\n") {
+			fmt.Fprintf(os.Stderr, "Error: invalid key chord %q\n", *keyFlag)
+			return 1
+		}
+		fmt.Printf(powerShellModule, *keyFlag)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Error: gx init doesn't support %s yet (supported: powershell)\n", shell)
+		return 1
+	}
+}
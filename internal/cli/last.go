@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// isLastCommand reports whether the words after "last" are a `gx last [n]`
+// invocation rather than the rest of a prompt such as "last modified files".
+func isLastCommand(args []string) bool {
+	switch len(args) {
+	case 0:
+		return true
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		return err == nil && n >= 1
+	}
+	return false
+}
+
+// runLast implements `gx last [n]`: print the nth most recent generated
+// command (default 1, the latest) from history, without calling the model.
+// Other arguments never reach it; see isLastCommand.
+func runLast(ctx context.Context, env *runEnv, args []string) int {
+	n := 1
+	if len(args) == 1 {
+		n, _ = strconv.Atoi(args[0])
+	}
+
+	entries, err := env.histMgr.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: history is empty (run gx with a prompt first)")
+		return 1
+	}
+	if n > len(entries) {
+		fmt.Fprintf(os.Stderr, "Error: history holds only %d commands (GX_HISTORY sets how many are kept)\n", len(entries))
+		return 1
+	}
+
+	fmt.Println(entries[len(entries)-n].Response)
+	return 0
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// maxDocBytes caps how much documentation is sent to the model.
+const maxDocBytes = 60 * 1024
+
+var (
+	// overstrike matches the "X\bX" bold and "_\bX" underline sequences man emits.
+	overstrike = regexp.MustCompile(".\x08")
+	// ansiEscape matches SGR color/style sequences.
+	ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// runMan implements `gx man <command>`: summarize the local man page
+// (or --help output) into a condensed, example-driven overview.
+func runMan(ctx context.Context, env *runEnv, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gx man <command>")
+		return 1
+	}
+	name := args[0]
+
+	doc, source, err := fetchDocumentation(ctx, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	env.logger.Debug("fetched documentation", "command", name, "source", source, "bytes", len(doc))
+
+	cfg := env.clientCfg
+	cfg.Mode = gemini.ModeManSummary
+	cfg.NoTools = true
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	prompt := fmt.Sprintf("Summarize the documentation for `%s` (from %s):\n\n%s", name, source, doc)
+	summary, err := client.Generate(ctx, prompt, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(summary)
+	return 0
+}
+
+// fetchDocumentation returns the documentation text for name and where it came from.
+// It tries the man page first, then `name --help`, then `name -h`.
+func fetchDocumentation(ctx context.Context, name string) (string, string, error) {
+	if runtime.GOOS == "windows" {
+		out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command",
+			fmt.Sprintf("Get-Help %s -Full | Out-String -Width 120", quotePowerShell(name))).Output()
+		if err == nil && strings.TrimSpace(string(out)) != "" {
+			return truncateDoc(string(out)), "Get-Help", nil
+		}
+	} else {
+		cmd := exec.CommandContext(ctx, "man", name)
+		cmd.Env = append(os.Environ(), "MANPAGER=cat", "PAGER=cat", "MANWIDTH=100", "MAN_KEEP_FORMATTING=0")
+		if out, err := cmd.Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+			return truncateDoc(cleanManOutput(string(out))), "man page", nil
+		}
+	}
+
+	for _, helpFlag := range []string{"--help", "-h"} {
+		// Many tools exit non-zero for --help, so accept any output
+		out, _ := exec.CommandContext(ctx, name, helpFlag).CombinedOutput()
+		if text := strings.TrimSpace(string(out)); text != "" {
+			return truncateDoc(cleanManOutput(text)), name + " " + helpFlag, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("no man page or --help output found for %q", name)
+}
+
+// cleanManOutput strips overstrike formatting and ANSI escapes.
+func cleanManOutput(s string) string {
+	s = overstrike.ReplaceAllString(s, "")
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// truncateDoc limits documentation to maxDocBytes.
+func truncateDoc(s string) string {
+	if len(s) <= maxDocBytes {
+		return s
+	}
+	return s[:maxDocBytes] + "\n... (truncated)"
+}
+
+// quotePowerShell single-quotes a value for PowerShell.
+func quotePowerShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// runModels implements `gx models`: list the Gemini models available in the
+// configured project and region, marking the configured model.
+func runModels(ctx context.Context, env *runEnv, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: gx models")
+		return 1
+	}
+
+	location := gemini.ResolveLocation(env.clientCfg.Location)
+	configured := gemini.ResolveModel(env.clientCfg.Model)
+
+	models, err := gemini.ListModels(ctx, location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Show which aliases point at each model
+	aliasesByModel := make(map[string][]string)
+	for alias, model := range gemini.ModelAliases() {
+		aliasesByModel[model] = append(aliasesByModel[model], alias)
+	}
+
+	width := len(configured)
+	for _, m := range models {
+		width = max(width, len(m.ID))
+	}
+
+	found := false
+	for _, m := range models {
+		marker := " "
+		if m.ID == configured {
+			marker = "*"
+			found = true
+		}
+		line := fmt.Sprintf("%s %-*s  %-15s %s", marker, width, m.ID, m.LaunchStage, gemini.ModelHint(m.ID))
+		if aliases := aliasesByModel[m.ID]; len(aliases) > 0 {
+			sort.Strings(aliases)
+			line += "  (-m " + strings.Join(aliases, ", -m ") + ")"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Fprintf(os.Stderr, "\n* configured model (GX_MODEL or default) in %s\n", location)
+	if !found {
+		fmt.Fprintf(os.Stderr, "Warning: configured model %s is not listed in %s\n", configured, location)
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// runPlan implements `gx --plan <request>`: the model lays out every command
+// the request needs, each with its risk, and gx prints them without staging
+// or running any. The plan is saved to history so follow-up prompts such as
+// "do step 2" have it as context.
+func runPlan(ctx context.Context, prompt string, cfg gemini.Config, histMgr *history.Manager) int {
+	cfg.Mode = gemini.ModePlan
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return exitGenerationFailed
+	}
+	defer client.Close()
+
+	histContext, err := histMgr.GetRecentContext(3)
+	if err != nil {
+		// Non-fatal, continue without history
+		histContext = nil
+	}
+
+	plan, err := client.GeneratePlan(ctx, prompt, histContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return generationStatus(err)
+	}
+
+	if plan.Summary != "" {
+		fmt.Fprintf(os.Stderr, "Plan: %s\n\n", plan.Summary)
+	}
+	text := formatPlan(plan)
+	fmt.Print(text)
+
+	if err := histMgr.AppendEntry(history.Entry{Prompt: prompt, Response: strings.TrimSpace(text)}); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save history: %v\n", err)
+	}
+	return 0
+}
+
+// formatPlan renders the steps as a numbered list: a "n. [risk] explanation"
+// line followed by the indented command.
+func formatPlan(plan gemini.Plan) string {
+	var b strings.Builder
+	width := len(fmt.Sprint(len(plan.Steps))) + 2
+	indent := strings.Repeat(" ", width)
+	for i, step := range plan.Steps {
+		risk := step.Risk
+		if risk == "" {
+			risk = "?"
+		}
+		fmt.Fprintf(&b, "%-*s[%s] %s\n", width, fmt.Sprintf("%d.", i+1), risk, step.Explanation)
+		fmt.Fprintf(&b, "%s%s\n", indent, indentContinuation(step.Command, indent))
+	}
+	return b.String()
+}
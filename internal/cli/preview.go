@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/diff"
+	"github.com/nealhardesty/gx/internal/shell"
+)
+
+const (
+	// previewTimeout bounds the rehearsal run of a previewed command.
+	previewTimeout = 30 * time.Second
+	// maxPreviewFileSize is the largest file copied for a rehearsal.
+	maxPreviewFileSize = 10 << 20
+)
+
+// rehearsable lists programs that only read their inputs and write to stdout
+// or to the files shell.FileEdits reports, so running them once against
+// temporary copies has no other side effects. perl and awk can run commands
+// and sort -o writes files, so they are left out; sed is allowed as long as
+// its script has no e or w commands (see shell.SedHasSideEffects).
+var rehearsable = map[string]bool{
+	"base64": true, "cat": true, "column": true, "cut": true, "echo": true,
+	"egrep": true, "expand": true, "fgrep": true, "fold": true, "fmt": true,
+	"grep": true, "head": true, "iconv": true, "jq": true, "nl": true,
+	"paste": true, "printf": true, "rev": true, "rg": true, "sed": true,
+	"gsed": true, "sponge": true, "tac": true, "tail": true, "tee": true,
+	"tr": true, "true": true, "unexpand": true, "uniq": true, "wc": true,
+	"yq": true,
+}
+
+// previewCopy is a temporary copy of a file the previewed command edits.
+type previewCopy struct {
+	path   string // original path
+	temp   string // copy the rehearsal edits instead
+	before string // original contents ("" if the file does not exist yet)
+}
+
+// previewCommand rehearses a file-editing command against temporary copies
+// of its target files, prints a unified diff of the result to stderr, and
+// asks whether to run it for real. It returns errCancelled when the user
+// declines. Commands with no detectable file edits pass straight through.
+func previewCommand(command string, opts execOptions) error {
+	// The copies belong to the user, so the rehearsal never needs sudo
+	command = shell.StripSudo(command)
+
+	edits, err := shell.FileEdits(command)
+	if err == nil && len(edits) == 0 {
+		fmt.Fprintln(os.Stderr, "Preview: no file edits detected")
+		return nil
+	}
+	if err == nil {
+		err = checkRehearsable(command)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Preview unavailable: %v\n", err)
+		return confirm("Execute without a preview? [y/N] ")
+	}
+
+	tmpDir, err := os.MkdirTemp("", "gx-preview-")
+	if err != nil {
+		return fmt.Errorf("failed to create preview directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rehearsal, copies, err := rewriteTargets(command, edits, tmpDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Preview unavailable: %v\n", err)
+		return confirm("Execute without a preview? [y/N] ")
+	}
+
+	output, runErr := rehearse(rehearsal, opts)
+
+	for _, c := range copies {
+		after, err := os.ReadFile(c.temp)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to read preview of %s: %w", c.path, err)
+		}
+		if d := diff.Unified("a/"+c.path, "b/"+c.path, c.before, string(after)); d != "" {
+			fmt.Fprint(os.Stderr, d)
+		} else {
+			fmt.Fprintf(os.Stderr, "No changes to %s\n", c.path)
+		}
+	}
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Preview run failed: %v\n", runErr)
+		if output != "" {
+			fmt.Fprint(os.Stderr, indentContinuation(strings.TrimRight(output, "\n"), "  ")+"\n")
+		}
+	}
+
+	return confirm("Apply these changes? [y/N] ")
+}
+
+// substitutions run a command of their own wherever they appear, including
+// in the arguments of a rehearsable program, so the rehearsal would run it
+// for real.
+var substitutions = []string{"$(", "`", "<(", ">("}
+
+// checkRehearsable returns an error naming any program in command that might
+// have side effects beyond the files being previewed. Command and process
+// substitutions are refused outright; eval and sh -c are refused with the
+// other programs that aren't rehearsable.
+func checkRehearsable(command string) error {
+	for _, s := range substitutions {
+		if strings.Contains(command, s) {
+			return fmt.Errorf("cannot safely rehearse a command containing %s, which would run for real", s)
+		}
+	}
+	var unsafe []string
+	for _, name := range shell.CommandNames(command) {
+		if !rehearsable[name] {
+			unsafe = append(unsafe, name)
+		}
+	}
+	if len(unsafe) > 0 {
+		return fmt.Errorf("cannot safely rehearse %s", strings.Join(unsafe, ", "))
+	}
+	if shell.SedHasSideEffects(command) {
+		return errors.New("cannot safely rehearse a sed script that runs commands or writes files")
+	}
+	return nil
+}
+
+// rewriteTargets copies each edited file into dir and returns command with
+// the target words replaced by the copies' paths.
+func rewriteTargets(command string, edits []shell.FileEdit, dir string) (string, []previewCopy, error) {
+	var copies []previewCopy
+	tempFor := make(map[string]string)
+	replacements := make(map[int][]string) // word start -> quoted temp paths
+	ends := make(map[int]int)
+
+	for _, e := range edits {
+		temp, ok := tempFor[e.Path]
+		if !ok {
+			temp = filepath.Join(dir, fmt.Sprintf("%d-%s", len(copies), filepath.Base(e.Path)))
+			before, err := copyForPreview(e.Path, temp)
+			if err != nil {
+				return "", nil, err
+			}
+			tempFor[e.Path] = temp
+			copies = append(copies, previewCopy{path: e.Path, temp: temp, before: before})
+		}
+		replacements[e.Word.Start] = append(replacements[e.Word.Start], quoteForShell(temp))
+		ends[e.Word.Start] = e.Word.End
+	}
+
+	// Replace from the end so earlier offsets stay valid
+	starts := make([]int, 0, len(replacements))
+	for start := range replacements {
+		starts = append(starts, start)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(starts)))
+	for _, start := range starts {
+		command = command[:start] + strings.Join(replacements[start], " ") + command[ends[start]:]
+	}
+
+	return command, copies, nil
+}
+
+// copyForPreview copies path to temp, preserving its permissions, and returns
+// the original contents. A missing file is not copied, so the rehearsal
+// creates it the same way the real run would.
+func copyForPreview(path, temp string) (string, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > maxPreviewFileSize {
+		return "", fmt.Errorf("%s is too large to preview (%d bytes)", path, info.Size())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(temp, data, info.Mode().Perm()|0200); err != nil {
+		return "", fmt.Errorf("failed to copy %s for preview: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// rehearse runs the rewritten command with no stdin and returns its combined output.
+func rehearse(command string, opts execOptions) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+
+	argv := shellArgv(command, opts)
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = commandEnv(opts)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		err = fmt.Errorf("timed out after %s", previewTimeout)
+	}
+	return out.String(), err
+}
+
+// quoteForShell quotes a path for the shell shellArgv runs commands in.
+func quoteForShell(s string) string {
+	if runtime.GOOS == "windows" {
+		if os.Getenv("PSModulePath") != "" {
+			return quotePowerShell(s)
+		}
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// confirm asks a yes/no question on the terminal and returns errCancelled
+// unless the answer is yes. Without a terminal it refuses to proceed.
+func confirm(prompt string) error {
+	answer, err := readLine(prompt)
+	if err != nil {
+		return fmt.Errorf("cannot confirm: %w", err)
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return nil
+	}
+	return errCancelled
+}
@@ -0,0 +1,38 @@
+package cli
+
+import "testing"
+
+// TestCheckRehearsableRefusesHiddenCommands checks that commands which
+// would run something other than rehearsable programs during the preview
+// are refused before the rehearsal starts.
+func TestCheckRehearsableRefusesHiddenCommands(t *testing.T) {
+	for _, command := range []string{
+		"echo $(rm -rf ~/x) > f.txt",
+		"echo `rm -rf ~/x` > f.txt",
+		"cat <(rm -rf ~/x) > f.txt",
+		"tee >(rm -rf ~/x) < in.txt > f.txt",
+		"echo \"$(touch /tmp/pwned)\" >> f.txt",
+		"eval 'rm -rf ~/x' > f.txt",
+		"bash -c 'rm -rf ~/x' > f.txt",
+		"sh -c 'rm -rf ~/x' > f.txt",
+		"sed -i 's/a/b/e' f.txt",
+	} {
+		if err := checkRehearsable(command); err == nil {
+			t.Errorf("checkRehearsable(%q) = nil, want an error", command)
+		}
+	}
+}
+
+// TestCheckRehearsableAllowsPlainEdits checks that ordinary text edits can
+// still be previewed.
+func TestCheckRehearsableAllowsPlainEdits(t *testing.T) {
+	for _, command := range []string{
+		"echo hello > f.txt",
+		"sed -i 's/foo/bar/g' config.yml",
+		"grep -v debug app.log | uniq > clean.log",
+	} {
+		if err := checkRehearsable(command); err != nil {
+			t.Errorf("checkRehearsable(%q) = %v, want nil", command, err)
+		}
+	}
+}
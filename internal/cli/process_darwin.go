@@ -0,0 +1,12 @@
+package cli
+
+import "golang.org/x/sys/unix"
+
+// sstop is the process state of a stopped process (SSTOP in sys/proc.h).
+const sstop = 4
+
+// processStopped reports whether pid is stopped by job control.
+func processStopped(pid int) bool {
+	info, err := unix.SysctlKinfoProc("kern.proc.pid", pid)
+	return err == nil && info.Proc.P_stat == sstop
+}
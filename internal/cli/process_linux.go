@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// processStopped reports whether pid is stopped by job control, from the
+// state field of /proc/<pid>/stat, which follows the parenthesized command
+// name.
+func processStopped(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	stat := string(data)
+	i := strings.LastIndexByte(stat, ')')
+	return i >= 0 && strings.HasPrefix(stat[i+1:], " T")
+}
@@ -0,0 +1,10 @@
+//go:build !windows && !linux && !darwin
+
+package cli
+
+// processStopped can't tell on this platform, so a command stopped with
+// Ctrl-Z stays stopped until it is sent SIGCONT (kill -CONT) from another
+// terminal.
+func processStopped(pid int) bool {
+	return false
+}
@@ -0,0 +1,84 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// configureProcessGroup puts cmd in its own process group, so a pipeline
+// like `a | b` and anything it starts can be signalled as a whole. When
+// stdin is a terminal the group is also made the terminal's foreground
+// group, so the command reads from it and gets Ctrl-C and Ctrl-Z directly
+// (see foregroundJob).
+func configureProcessGroup(cmd *exec.Cmd, terminal bool) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if terminal {
+		cmd.SysProcAttr.Foreground = true
+		cmd.SysProcAttr.Ctty = int(os.Stdin.Fd())
+	}
+}
+
+// forwardSignal delivers sig to the child's process group.
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) {
+	if cmd.Process == nil {
+		return
+	}
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		_ = cmd.Process.Signal(sig)
+		return
+	}
+	_ = syscall.Kill(-cmd.Process.Pid, s)
+}
+
+// foregroundJob does a shell's job control for a command that runs in the
+// terminal's foreground group while gx waits in the background. When Ctrl-Z
+// stops the command, gx takes the terminal back and stops itself, so the
+// shell that started gx sees the job suspended; once resumed with fg, it
+// hands the terminal back and continues the command. The returned function,
+// called after the command exits, gives the terminal back to gx.
+func foregroundJob(cmd *exec.Cmd) (restore func()) {
+	if cmd.SysProcAttr == nil || !cmd.SysProcAttr.Foreground {
+		return func() {}
+	}
+	tty := cmd.SysProcAttr.Ctty
+	// A background group that changes the foreground group gets SIGTTOU
+	signal.Ignore(syscall.SIGTTOU)
+	children := make(chan os.Signal, 1)
+	signal.Notify(children, syscall.SIGCHLD)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-children:
+				if !processStopped(cmd.Process.Pid) {
+					continue
+				}
+				_ = unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, syscall.Getpgrp())
+				_ = syscall.Kill(syscall.Getpid(), syscall.SIGSTOP)
+				// Resumed
+				_ = unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, cmd.Process.Pid)
+				_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGCONT)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		signal.Stop(children)
+		_ = unix.IoctlSetPointerInt(tty, unix.TIOCSPGRP, syscall.Getpgrp())
+		signal.Reset(syscall.SIGTTOU)
+	}
+}
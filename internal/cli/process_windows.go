@@ -0,0 +1,29 @@
+//go:build windows
+
+package cli
+
+import (
+	"os"
+	"os/exec"
+)
+
+// configureProcessGroup is a no-op on Windows; console Ctrl-C events are
+// already delivered to every process attached to the console.
+func configureProcessGroup(cmd *exec.Cmd, interactive bool) {}
+
+// forwardSignal passes sig on to the child. The console already delivers
+// Ctrl-C to the child, which decides for itself whether to exit, so
+// os.Interrupt is ignored here. Windows cannot deliver other signals to
+// another process, so the rest (the --exec-timeout SIGTERM and SIGKILL
+// among them) are mapped to Kill.
+func forwardSignal(cmd *exec.Cmd, sig os.Signal) {
+	if cmd.Process == nil || sig == os.Interrupt {
+		return
+	}
+	_ = cmd.Process.Kill()
+}
+
+// foregroundJob is a no-op on Windows, which has no job control.
+func foregroundJob(cmd *exec.Cmd) (restore func()) {
+	return func() {}
+}
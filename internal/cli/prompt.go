@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// openTTY opens the controlling terminal for interactive input, so prompts
+// still work when stdin is a pipe (e.g. `cat log | gx - ...`).
+// The caller must close the returned file unless it is os.Stdin.
+func openTTY() (*os.File, error) {
+	if isTerminal(os.Stdin) {
+		return os.Stdin, nil
+	}
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal available for interactive input: %w", err)
+	}
+	return f, nil
+}
+
+// readLine prints prompt to stderr and reads one line from the terminal.
+func readLine(prompt string) (string, error) {
+	tty, err := openTTY()
+	if err != nil {
+		return "", err
+	}
+	if tty != os.Stdin {
+		defer tty.Close()
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// askClarification shows a clarifying question from the model and reads the
+// answer. Without a terminal it answers "" so the model makes an assumption;
+// q cancels.
+func askClarification(question string) (string, error) {
+	fmt.Fprintf(os.Stderr, "? %s\n", indentContinuation(question, "  "))
+	answer, err := readLine("> ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v; letting the model assume\n", err)
+		return "", nil
+	}
+	if answer == "q" || answer == "Q" {
+		return "", errCancelled
+	}
+	return answer, nil
+}
+
+// choice is a selectable command with an optional one-line note.
+type choice struct {
+	Command string
+	Note    string
+}
+
+// chooseCommand prints numbered choices to stderr and asks the user to pick one.
+// An empty answer selects the first choice. Without a terminal it fails
+// after listing them, rather than picking one the user never saw.
+func chooseCommand(choices []choice) (string, error) {
+	if len(choices) == 1 {
+		return choices[0].Command, nil
+	}
+
+	for i, c := range choices {
+		fmt.Fprintf(os.Stderr, "[%d] %s\n", i+1, indentContinuation(c.Command, "    "))
+		if c.Note != "" {
+			fmt.Fprintf(os.Stderr, "    %s\n", c.Note)
+		}
+	}
+
+	for {
+		answer, err := readLine(fmt.Sprintf("Select a command [1-%d] (default 1, q to quit): ", len(choices)))
+		if err != nil {
+			// Picking one unseen could run a command nobody chose
+			return "", fmt.Errorf("%d commands to choose from but no terminal to ask on: %w", len(choices), err)
+		}
+		if answer == "" {
+			return choices[0].Command, nil
+		}
+		if answer == "q" || answer == "Q" {
+			return "", errCancelled
+		}
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= 1 && n <= len(choices) {
+			return choices[n-1].Command, nil
+		}
+		fmt.Fprintf(os.Stderr, "Please enter a number between 1 and %d.\n", len(choices))
+	}
+}
+
+// indentContinuation indents every line after the first so multi-line
+// candidates stay visually grouped under their number.
+func indentContinuation(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
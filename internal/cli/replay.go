@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/nealhardesty/gx/internal/diff"
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// Exit codes for gx replay, matching diff(1).
+const (
+	exitReplayDiffers = 1
+	exitReplayError   = 2
+)
+
+// runReplay implements `gx replay <logfile>`: send the request recorded in a
+// JSONL prompt log again, with the current system prompt and the configured
+// model (-m), and diff the new response against the recorded one. Tool calls
+// the recording has are answered from it, so differences come from the model
+// and the prompt rather than from the machine having changed.
+func runReplay(ctx context.Context, env *runEnv, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: gx [-m model] replay <logfile>")
+		return exitReplayError
+	}
+
+	rec, err := gemini.LoadRecording(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitReplayError
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = rec.Mode
+	cfg.Replay = rec
+	cfg.Clarify = rec.Answer
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return exitReplayError
+	}
+	defer client.Close()
+
+	recordedModel := rec.Model
+	if recordedModel == "" {
+		recordedModel = "unknown model"
+	}
+	model := gemini.ResolveModel(cfg.Model)
+	fmt.Fprintf(os.Stderr, "Replaying %q (recorded %s with %s) on %s\n", rec.Prompt, rec.Time.Local().Format("2006-01-02 15:04"), recordedModel, model)
+
+	result, err := client.GenerateResult(ctx, rec.Prompt, rec.History)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitReplayError
+	}
+
+	d := diff.Unified("recorded ("+recordedModel+")", "replayed ("+model+")", rec.Response+"\n", result.Command+"\n")
+	if d == "" {
+		fmt.Println(result.Command)
+		fmt.Fprintln(os.Stderr, "Same response as recorded.")
+		return 0
+	}
+	fmt.Print(d)
+	return exitReplayDiffers
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/cache"
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/history"
+	"github.com/nealhardesty/gx/internal/suggest"
+)
+
+// generateWithSuggestion generates the command like generateCommand, but
+// first offers a close local match from history or the built-in snippets:
+// Enter takes it and cancels the model request, and otherwise the model's
+// answer is used as soon as it arrives. Without a terminal, when no local
+// match is close enough, or when the request needs more than a command, it
+// is generateCommand.
+func generateWithSuggestion(ctx context.Context, prompt string, cfg gemini.Config, noCache bool, histMgr *history.Manager, cacheStore *cache.Store) (gemini.Result, error) {
+	// Choosers, rationales, undo hints, and attached images need the model's answer
+	if cfg.Alternatives >= 2 || cfg.Why || cfg.Undo || len(cfg.Attachments) > 0 || *gemini.ResolveSampling(cfg.Sampling).CandidateCount > 1 {
+		return generateCommand(ctx, prompt, cfg, noCache, histMgr, cacheStore)
+	}
+	entries, err := histMgr.Load()
+	if err != nil {
+		entries = nil
+	}
+	match, ok := suggest.Find(prompt, entries)
+	if !ok || !isTerminal(os.Stderr) {
+		return generateCommand(ctx, prompt, cfg, noCache, histMgr, cacheStore)
+	}
+	offer, err := newLocalOffer(ctx)
+	if err != nil {
+		cfg.Logger.Debug("local match not offered", "error", err)
+		return generateCommand(ctx, prompt, cfg, noCache, histMgr, cacheStore)
+	}
+	defer offer.withdraw()
+	cfg.Logger.Debug("local match", "source", match.Source, "prompt", match.Prompt, "score", match.Score)
+
+	fmt.Fprintf(os.Stderr, "Local match (%s): %s\n", match.Source, indentContinuation(match.Command, "  "))
+	fmt.Fprintln(os.Stderr, "Press Enter to use it, or wait for AI...")
+
+	// A clarifying question needs the terminal, so the offer ends first
+	if clarify := cfg.Clarify; clarify != nil {
+		cfg.Clarify = func(question string) (string, error) {
+			offer.withdraw()
+			return clarify(question)
+		}
+	}
+
+	type generated struct {
+		result gemini.Result
+		err    error
+	}
+	genCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan generated, 1)
+	go func() {
+		result, err := generateCommand(genCtx, prompt, cfg, noCache, histMgr, cacheStore)
+		done <- generated{result, err}
+	}()
+
+	select {
+	case g := <-done:
+		return g.result, g.err
+	case <-offer.accepted:
+		cancel()
+		<-done
+		return gemini.Result{Command: match.Command, Risk: match.Risk}, nil
+	}
+}
+
+// localOffer waits for Enter on the terminal until it is withdrawn.
+type localOffer struct {
+	tty *os.File
+	// accepted is closed when the user presses Enter on an empty line
+	accepted chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// newLocalOffer starts reading the terminal. It opens its own handle rather
+// than using stdin so a read deadline can interrupt the read when the model
+// answers first; terminals that don't support deadlines get no offer.
+func newLocalOffer(ctx context.Context) (*localOffer, error) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return nil, err
+	}
+	if err := tty.SetReadDeadline(time.Time{}); err != nil {
+		tty.Close()
+		return nil, err
+	}
+	o := &localOffer{tty: tty, accepted: make(chan struct{}), stopped: make(chan struct{})}
+	go func() {
+		defer close(o.stopped)
+		line, err := bufio.NewReader(tty).ReadString('\n')
+		// Typing anything else dismisses the offer and keeps waiting for AI
+		if err == nil && strings.TrimSpace(line) == "" && ctx.Err() == nil {
+			close(o.accepted)
+		}
+	}()
+	return o, nil
+}
+
+// withdraw stops reading the terminal and waits for the reader to finish, so
+// later prompts (clarifying questions, confirmations) get the user's input.
+func (o *localOffer) withdraw() {
+	o.once.Do(func() {
+		o.tty.SetReadDeadline(time.Now())
+		<-o.stopped
+		o.tty.Close()
+	})
+}
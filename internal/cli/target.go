@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/buildfile"
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// runTarget implements `gx target [--taskfile] [-f file] <description>`:
+// generate a Makefile target (or Taskfile task) for the described task and
+// append it to the build file after confirmation.
+func runTarget(ctx context.Context, env *runEnv, args []string) int {
+	fs := flag.NewFlagSet("target", flag.ContinueOnError)
+	taskfileFlag := fs.Bool("taskfile", false, "Generate a Taskfile task instead of a Makefile target")
+	fileFlag := fs.String("f", "", "Build file to extend (default: Makefile or Taskfile.yml in the current directory)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: gx target [--taskfile] [-f file] <description>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	description := strings.Join(fs.Args(), " ")
+	if description == "" {
+		fs.Usage()
+		return 1
+	}
+
+	var b buildTarget = makeTarget{}
+	if *taskfileFlag {
+		b = taskfileTarget{}
+	}
+
+	path := *fileFlag
+	if path == "" {
+		path = buildfile.Find(b.names())
+	}
+	var existing string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		existing = string(data)
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = b.mode()
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	// Recent history lets "a target for that" refer to the last command
+	histContext, err := env.histMgr.GetRecentContext(3)
+	if err != nil {
+		histContext = nil
+	}
+
+	prompt := description
+	if names := b.existing(existing); len(names) > 0 {
+		prompt += fmt.Sprintf("\n\nExisting targets in %s: %s", path, strings.Join(names, ", "))
+	}
+
+	snippet, raw, err := generateChecked(ctx, env, client, prompt, histContext, func(result string) (string, error) {
+		return b.normalize(result, existing)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if raw != "" {
+			fmt.Fprintln(os.Stderr, indentContinuation("  "+raw, "  "))
+		}
+		return 1
+	}
+
+	fmt.Print(snippet)
+
+	// Only offer to write when someone is there to answer
+	if !isTerminal(os.Stderr) {
+		return 0
+	}
+
+	question := fmt.Sprintf("Append to %s? [y/N] ", path)
+	if path == "" {
+		path = b.defaultName()
+		question = fmt.Sprintf("Create %s with this target? [y/N] ", path)
+	}
+	updated, err := b.append(existing, snippet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Not appending: %v\n", err)
+		return 0
+	}
+	if err := confirm(question); err != nil {
+		if errors.Is(err, errCancelled) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Updated %s\n", path)
+	return 0
+}
+
+// buildTarget abstracts over the build file formats gx target supports.
+type buildTarget interface {
+	mode() gemini.Mode
+	// names lists the file names to look for in the current directory
+	names() []string
+	// defaultName is the file to create when none exists
+	defaultName() string
+	// existing lists the targets already defined in content
+	existing(content string) []string
+	// normalize validates a generated snippet against existing content
+	normalize(result, content string) (string, error)
+	// append returns content with the snippet added
+	append(content, snippet string) (string, error)
+}
+
+// makeTarget generates GNU make targets.
+type makeTarget struct{}
+
+func (makeTarget) mode() gemini.Mode { return gemini.ModeMakeTarget }
+
+func (makeTarget) names() []string { return buildfile.MakefileNames }
+
+func (makeTarget) defaultName() string { return "Makefile" }
+
+func (makeTarget) existing(content string) []string {
+	var names []string
+	for _, t := range buildfile.MakeTargets(content) {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func (m makeTarget) normalize(result, content string) (string, error) {
+	snippet, defined, err := buildfile.NormalizeMakeSnippet(result)
+	if err != nil {
+		return "", err
+	}
+	return snippet, checkRedefined(defined, m.existing(content))
+}
+
+func (makeTarget) append(content, snippet string) (string, error) {
+	return buildfile.AppendMake(content, snippet), nil
+}
+
+// taskfileTarget generates go-task tasks.
+type taskfileTarget struct{}
+
+func (taskfileTarget) mode() gemini.Mode { return gemini.ModeTaskfileTask }
+
+func (taskfileTarget) names() []string { return buildfile.TaskfileNames }
+
+func (taskfileTarget) defaultName() string { return "Taskfile.yml" }
+
+func (taskfileTarget) existing(content string) []string { return buildfile.TaskNames(content) }
+
+func (t taskfileTarget) normalize(result, content string) (string, error) {
+	snippet, name, err := buildfile.NormalizeTaskSnippet(result)
+	if err != nil {
+		return "", err
+	}
+	return snippet, checkRedefined([]string{name}, t.existing(content))
+}
+
+func (taskfileTarget) append(content, snippet string) (string, error) {
+	if strings.TrimSpace(content) == "" {
+		content = buildfile.NewTaskfileHeader
+	}
+	return buildfile.AppendTask(content, snippet)
+}
+
+// checkRedefined returns an error if any defined name already exists.
+func checkRedefined(defined, existing []string) error {
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[name] = true
+	}
+	for _, name := range defined {
+		if have[name] {
+			return fmt.Errorf("target %q already exists; choose a new name", name)
+		}
+	}
+	return nil
+}
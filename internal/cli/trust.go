@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/project"
+)
+
+// projectFlags are the options a .gxrc may set: how commands are generated
+// and shown. The file comes with the repository, so anything that runs
+// commands, sets their environment, changes directory, or sends local files
+// or account details to the model is left out.
+var projectFlags = map[string]bool{
+	"alt": true, "arch": true, "candidates": true, "comments": true,
+	"escalate-to": true, "lang": true, "m": true, "max-tokens": true,
+	"n": true, "no-cache": true, "no-clarify": true, "no-local": true,
+	"one-liner": true, "os": true, "plan": true, "posix": true,
+	"preview": true, "shell": true, "temperature": true, "tools-ro": true,
+	"top-k": true, "top-p": true, "undo": true, "why": true,
+}
+
+// projectValue sets a flag from a project config, unless the command line
+// already set it.
+type projectValue struct {
+	flag.Value
+	onCommandLine bool
+}
+
+func (v projectValue) Set(s string) error {
+	if v.onCommandLine {
+		return nil
+	}
+	return v.Value.Set(s)
+}
+
+// IsBoolFlag lets boolean flags appear without a value, as on the command line.
+func (v projectValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// applyProjectFlags parses a project config's flags into the command line's
+// flag values. Only projectFlags are accepted.
+func applyProjectFlags(args []string) error {
+	onCommandLine := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+
+	fs := flag.NewFlagSet("flags", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flag.VisitAll(func(f *flag.Flag) {
+		if projectFlags[f.Name] {
+			fs.Var(projectValue{Value: f.Value, onCommandLine: onCommandLine[f.Name]}, f.Name, f.Usage)
+		}
+	})
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("flags: %w (a project config may only set options such as -m, --comments, and --one-liner that change how commands are generated)", err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("flags may only hold options, found %q", fs.Arg(0))
+	}
+	return nil
+}
+
+// runTrust implements `gx trust`: show the project config that applies in
+// the current directory and, once confirmed, record its contents as trusted
+// so its instructions and flags take effect. Any later edit to the file
+// needs another `gx trust`.
+func runTrust(ctx context.Context, env *runEnv, args []string) int {
+	proj, err := project.Find(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if proj == nil {
+		fmt.Fprintf(os.Stderr, "Error: no %s in this repository\n", strings.Join(project.FileNames, " or "))
+		return 1
+	}
+	if proj.Trusted() {
+		fmt.Fprintf(os.Stderr, "%s is already trusted\n", proj.Path)
+		return 0
+	}
+
+	fmt.Fprintln(os.Stderr, proj.Path)
+	if proj.Instructions != "" {
+		fmt.Fprintf(os.Stderr, "  Instructions for the model:\n    %s\n", indentContinuation(proj.Instructions, "    "))
+	}
+	if len(proj.Flags) > 0 {
+		if err := applyProjectFlags(proj.Flags); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "  Flags: %s\n", strings.Join(proj.Flags, " "))
+	}
+	if proj.Instructions == "" && len(proj.Flags) == 0 {
+		fmt.Fprintln(os.Stderr, "  (no instructions or flags; tool limits apply without trust)")
+	}
+
+	if err := confirm("Trust this file? [y/N] "); err != nil {
+		if errors.Is(err, errCancelled) {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := proj.Trust(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Trusted %s\n", proj.Path)
+	return 0
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// runUndo implements `gx undo`: print and stage the undo hint saved with the
+// most recent command, so `gx -x` reverses it.
+func runUndo(ctx context.Context, env *runEnv, args []string) int {
+	if len(args) != 0 {
+		fmt.Fprintln(os.Stderr, "Usage: gx undo")
+		return 1
+	}
+
+	entry, err := env.histMgr.Last()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if entry.Undo == "" {
+		fmt.Fprintf(os.Stderr, "Error: no undo hint saved for the last command: %s\n", entry.Response)
+		fmt.Fprintln(os.Stderr, "(generate commands with --undo to record one)")
+		return 1
+	}
+
+	fmt.Println(entry.Undo)
+	if entry.Machine != "" {
+		// Like the command, its undo is for the other machine
+		fmt.Fprintf(os.Stderr, "Not staged: the command was generated with %s, for another machine.\n", entry.Machine)
+		return 0
+	}
+	if err := env.histMgr.StageCommand(entry.Undo); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "Staged; run gx -x to execute it.")
+	return 0
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+)
+
+// runWhy implements `gx why - [question]`: explain or diagnose piped input
+// (logs, stack traces, diffs) in prose instead of generating a command.
+func runWhy(ctx context.Context, env *runEnv, args []string) int {
+	hasStdin := false
+	var questionArgs []string
+	for _, arg := range args {
+		if arg == "-" {
+			hasStdin = true
+		} else {
+			questionArgs = append(questionArgs, arg)
+		}
+	}
+	question := strings.Join(questionArgs, " ")
+
+	var input string
+	if hasStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			return 1
+		}
+		input = strings.TrimSpace(string(data))
+	}
+	if input == "" && question == "" {
+		fmt.Fprintln(os.Stderr, "Usage: <command> | gx why - [question]")
+		return 1
+	}
+
+	cfg := env.clientCfg
+	cfg.Mode = gemini.ModeExplain
+
+	client, err := gemini.NewClient(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to create client: %v\n", err)
+		return 1
+	}
+	defer client.Close()
+
+	// The last command is often what produced the input
+	histContext, err := env.histMgr.GetRecentContext(1)
+	if err != nil {
+		histContext = nil
+	}
+
+	prompt := question
+	if input != "" {
+		if prompt == "" {
+			prompt = "Explain this output and diagnose any problems."
+		}
+		prompt += "\n\n---\n\n" + truncateDoc(input)
+	}
+
+	explanation, err := client.Generate(ctx, prompt, histContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Println(explanation)
+	return 0
+}
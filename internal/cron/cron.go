@@ -0,0 +1,254 @@
+// Package cron parses and validates crontab schedules and systemd timer units.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record an unrestricted day field; when both day
+	// fields are restricted, cron runs on days matching either of them
+	domStar, dowStar bool
+	// reboot is set for @reboot, which has no time-based schedule
+	reboot bool
+}
+
+// field describes the bounds and names of one cron field.
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day of week accepts 7 as an alias for Sunday
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// macros maps the @ shorthands cron implementations accept to their expansion.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a five-field cron expression or an @ macro such as @daily.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		if strings.ToLower(spec) == "@reboot" {
+			return &Schedule{reboot: true}, nil
+		}
+		expansion, ok := macros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("unknown schedule macro %q", spec)
+		}
+		spec = expansion
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 schedule fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	// Fold Sunday-as-7 into 0
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// ParseLine splits a crontab line into its schedule and command, validating both.
+func ParseLine(line string) (*Schedule, string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, "", fmt.Errorf("not a crontab entry: %q", line)
+	}
+
+	n := 5
+	if strings.HasPrefix(line, "@") {
+		n = 1
+	}
+	fields := strings.Fields(line)
+	if len(fields) <= n {
+		return nil, "", fmt.Errorf("crontab entry has no command: %q", line)
+	}
+
+	schedule, err := Parse(strings.Join(fields[:n], " "))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Cut the command from the original line to preserve its spacing
+	command := line
+	for i := 0; i < n; i++ {
+		command = strings.TrimLeft(command, " \t")
+		command = command[len(fields[i]):]
+	}
+	command = strings.TrimSpace(command)
+
+	if i := unescapedPercent(command); i >= 0 {
+		return nil, "", fmt.Errorf("unescaped %% at column %d of the command (cron turns %% into a newline; write \\%%)", i+1)
+	}
+	return schedule, command, nil
+}
+
+// unescapedPercent returns the index of the first % not preceded by a
+// backslash, or -1.
+func unescapedPercent(command string) int {
+	for i := 0; i < len(command); i++ {
+		if command[i] == '\\' {
+			i++
+			continue
+		}
+		if command[i] == '%' {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseField parses a comma-separated list of values, ranges, and steps
+// (e.g. "1-5", "*/15", "mon,wed,fri") into a bitset.
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", part[i+1:], f.name)
+			}
+			rangeExpr, step = part[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangeExpr == "*":
+			if f.name == dowField.name {
+				hi = 6
+			}
+		case strings.Contains(rangeExpr, "-"):
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("range %q is backwards in %s field", rangeExpr, f.name)
+			}
+		default:
+			v, err := f.value(rangeExpr)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// "5/10" means every 10 starting at 5
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single number or name and checks it is in range.
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %d out of range %d-%d", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Reboot reports whether the schedule runs once at startup (@reboot).
+func (s *Schedule) Reboot() bool {
+	return s.reboot
+}
+
+// Next returns the first time after t that matches the schedule, or the
+// zero time if it never matches (e.g. February 30th) or runs only at boot.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.reboot {
+		return time.Time{}
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's day rule: if either day field is unrestricted
+// both must match, otherwise matching either is enough.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,108 @@
+package cron
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// UnitFilePrefix marks the line naming each unit file in a generated timer.
+const UnitFilePrefix = "# FILE:"
+
+// Unit is a systemd unit file.
+type Unit struct {
+	Name    string
+	Content string
+}
+
+// Timer is a systemd timer unit and the service it activates.
+type Timer struct {
+	Service Unit
+	Timer   Unit
+	// OnCalendar holds the timer's calendar expressions.
+	OnCalendar []string
+}
+
+// ParseTimer splits text containing "# FILE: name.service" and
+// "# FILE: name.timer" sections into units and validates their structure.
+func ParseTimer(text string) (*Timer, error) {
+	var units []Unit
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, UnitFilePrefix) {
+			name := strings.TrimSpace(strings.TrimPrefix(trimmed, UnitFilePrefix))
+			if name == "" || name != filepath.Base(name) {
+				return nil, fmt.Errorf("invalid unit file name %q", name)
+			}
+			units = append(units, Unit{Name: name})
+			continue
+		}
+		if len(units) == 0 {
+			if trimmed != "" {
+				return nil, fmt.Errorf("unit content before the first %q line", UnitFilePrefix)
+			}
+			continue
+		}
+		units[len(units)-1].Content += line + "\n"
+	}
+
+	t := &Timer{}
+	for _, u := range units {
+		u.Content = strings.TrimSpace(u.Content) + "\n"
+		switch filepath.Ext(u.Name) {
+		case ".service":
+			if t.Service.Name != "" {
+				return nil, fmt.Errorf("more than one .service unit")
+			}
+			t.Service = u
+		case ".timer":
+			if t.Timer.Name != "" {
+				return nil, fmt.Errorf("more than one .timer unit")
+			}
+			t.Timer = u
+		default:
+			return nil, fmt.Errorf("unexpected unit %q (expected a .service and a .timer)", u.Name)
+		}
+	}
+	if t.Service.Name == "" || t.Timer.Name == "" {
+		return nil, fmt.Errorf("expected a .service and a .timer unit")
+	}
+	if strings.TrimSuffix(t.Service.Name, ".service") != strings.TrimSuffix(t.Timer.Name, ".timer") {
+		return nil, fmt.Errorf("%s and %s must share a name so the timer activates the service", t.Service.Name, t.Timer.Name)
+	}
+
+	if !hasKey(t.Service.Content, "Service", "ExecStart") {
+		return nil, fmt.Errorf("%s has no ExecStart= in its [Service] section", t.Service.Name)
+	}
+	t.OnCalendar = keyValues(t.Timer.Content, "Timer", "OnCalendar")
+	if len(t.OnCalendar) == 0 {
+		return nil, fmt.Errorf("%s has no OnCalendar= in its [Timer] section", t.Timer.Name)
+	}
+	return t, nil
+}
+
+// hasKey reports whether key is set in section of a unit file.
+func hasKey(content, section, key string) bool {
+	return len(keyValues(content, section, key)) > 0
+}
+
+// keyValues returns the values of key in section of a unit file.
+func keyValues(content, section, key string) []string {
+	var values []string
+	current := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = line[1 : len(line)-1]
+			continue
+		}
+		if current != section {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(k) == key && strings.TrimSpace(v) != "" {
+			values = append(values, strings.TrimSpace(v))
+		}
+	}
+	return values
+}
@@ -0,0 +1,173 @@
+// Package diff renders unified diffs of text for command previews.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// contextLines is the number of unchanged lines shown around each change.
+	contextLines = 3
+	// maxCells bounds the LCS table; larger inputs fall back to replacing the
+	// whole changed region instead of computing a minimal diff.
+	maxCells = 4_000_000
+)
+
+// op is one line of an edit script: ' ' (unchanged), '-' (removed), or '+' (added).
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns a unified diff turning from into to, labelled with the
+// given names, or "" when the texts are equal.
+func Unified(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	ops := lineOps(splitLines(from), splitLines(to))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	writeHunks(&sb, ops)
+	return sb.String()
+}
+
+// splitLines splits s into lines, keeping each line's trailing newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineOps computes a line edit script from a to b using the longest common
+// subsequence of the region between their common prefix and suffix.
+func lineOps(a, b []string) []op {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []op
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(midA), len(midB)
+	if (n+1)*(m+1) > maxCells {
+		for _, line := range midA {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range midB {
+			ops = append(ops, op{'+', line})
+		}
+	} else {
+		// lcs[i*(m+1)+j] is the LCS length of midA[i:] and midB[j:]
+		lcs := make([]int32, (n+1)*(m+1))
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+				} else {
+					lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < n && j < m {
+			switch {
+			case midA[i] == midB[j]:
+				ops = append(ops, op{' ', midA[i]})
+				i++
+				j++
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				ops = append(ops, op{'-', midA[i]})
+				i++
+			default:
+				ops = append(ops, op{'+', midB[j]})
+				j++
+			}
+		}
+		for ; i < n; i++ {
+			ops = append(ops, op{'-', midA[i]})
+		}
+		for ; j < m; j++ {
+			ops = append(ops, op{'+', midB[j]})
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+	return ops
+}
+
+// writeHunks groups changes that are close together into hunks with
+// surrounding context and writes them in unified format.
+func writeHunks(sb *strings.Builder, ops []op) {
+	// aPos[k] and bPos[k] count the old and new lines before ops[k]
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for k, o := range ops {
+		aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+		if o.kind != '+' {
+			aPos[k+1]++
+		}
+		if o.kind != '-' {
+			bPos[k+1]++
+		}
+	}
+
+	for k := 0; k < len(ops); {
+		if ops[k].kind == ' ' {
+			k++
+			continue
+		}
+
+		// Extend the hunk while the next change is within two context windows
+		last := k
+		for j := k + 1; j < len(ops) && j-last <= 2*contextLines; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		start := max(0, k-contextLines)
+		end := min(len(ops), last+1+contextLines)
+
+		fmt.Fprintf(sb, "@@ -%s +%s @@\n",
+			hunkRange(aPos[start], aPos[end]-aPos[start]),
+			hunkRange(bPos[start], bPos[end]-bPos[start]))
+		for _, o := range ops[start:end] {
+			sb.WriteByte(o.kind)
+			sb.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		k = end
+	}
+}
+
+// hunkRange formats a hunk's line range; empty ranges name the line before them.
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
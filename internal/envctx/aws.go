@@ -0,0 +1,110 @@
+package envctx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// aws describes the active AWS profile: its account (alias and ID), the
+// identity it signs in as, and its region, or returns "" when the aws CLI
+// isn't installed. The account comes from `aws sts get-caller-identity`, so
+// it is only looked up when the user asks for it (gx --aws).
+func aws(ctx context.Context) string {
+	cli, err := exec.LookPath("aws")
+	if err != nil {
+		return ""
+	}
+	profile := awsProfile()
+	// Static keys in the environment replace the profile's credentials;
+	// only a hash of the key ID goes into the cache file
+	keyID := ""
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		sum := sha256.Sum256([]byte(id))
+		keyID = hex.EncodeToString(sum[:8])
+	}
+	extra := []string{cli, "profile=" + profile, "region=" + os.Getenv("AWS_REGION") + "," + os.Getenv("AWS_DEFAULT_REGION"), "key=" + keyID}
+	return cached("aws", fingerprint(awsConfigPaths(), extra...), func() string {
+		return lookupAWS(ctx, cli, profile)
+	})
+}
+
+// awsProfile returns the profile the aws CLI uses.
+func awsProfile() string {
+	for _, key := range []string{"AWS_PROFILE", "AWS_DEFAULT_PROFILE"} {
+		if profile := os.Getenv(key); profile != "" {
+			return profile
+		}
+	}
+	return "default"
+}
+
+// awsConfigPaths returns the files a sign-in or profile change touches: the
+// config and credentials files and the SSO token cache.
+func awsConfigPaths() []string {
+	homeDir, _ := os.UserHomeDir()
+	config := os.Getenv("AWS_CONFIG_FILE")
+	if config == "" {
+		config = filepath.Join(homeDir, ".aws", "config")
+	}
+	credentials := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentials == "" {
+		credentials = filepath.Join(homeDir, ".aws", "credentials")
+	}
+	return []string{config, credentials, filepath.Join(homeDir, ".aws", "sso", "cache")}
+}
+
+// lookupAWS asks AWS who the profile's credentials belong to. When they
+// have expired, the profile and region are still reported.
+func lookupAWS(ctx context.Context, cli, profile string) string {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		out, _ := output(ctx, cli, "configure", "get", "region")
+		region = strings.TrimSpace(string(out))
+	}
+
+	line := "- AWS: profile " + profile
+	var identity struct {
+		Account string `json:"Account"`
+		Arn     string `json:"Arn"`
+	}
+	out, err := output(ctx, cli, "sts", "get-caller-identity", "--output", "json")
+	if err == nil && json.Unmarshal(out, &identity) == nil && identity.Account != "" {
+		account := identity.Account
+		if alias := awsAccountAlias(ctx, cli); alias != "" {
+			account = fmt.Sprintf("%s (%s)", alias, identity.Account)
+		}
+		line += fmt.Sprintf(", account %s, signed in as %s", account, identity.Arn)
+	} else {
+		line += " (credentials missing or expired)"
+	}
+	if region != "" {
+		line += ", region " + region
+	}
+	return line + " (aws commands use this profile and region unless given --profile or --region; check the account before changing resources)"
+}
+
+// awsAccountAlias returns the account's alias, or "" when it has none or
+// the identity may not list it.
+func awsAccountAlias(ctx context.Context, cli string) string {
+	out, err := output(ctx, cli, "iam", "list-account-aliases", "--output", "json")
+	if err != nil {
+		return ""
+	}
+	var aliases struct {
+		AccountAliases []string `json:"AccountAliases"`
+	}
+	if json.Unmarshal(out, &aliases) != nil || len(aliases.AccountAliases) == 0 {
+		return ""
+	}
+	return aliases.AccountAliases[0]
+}
@@ -0,0 +1,90 @@
+package envctx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheFile is the default path for cached lookups.
+	DefaultCacheFile = ".gxcontext"
+	// cacheTTL bounds how long a lookup is trusted even if nothing on disk
+	// appears to have changed (a cluster upgrade, for one).
+	cacheTTL = time.Hour
+)
+
+// entry is a cached lookup. The fingerprint captures the configuration files
+// the result depends on, so switching contexts or profiles invalidates it.
+type entry struct {
+	Value       string    `json:"value"`
+	Fingerprint string    `json:"fingerprint"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+}
+
+// cacheMu serializes access to the cache file between concurrent lookups.
+var cacheMu sync.Mutex
+
+// cached returns the value stored under key when its fingerprint matches and
+// it is younger than cacheTTL, and otherwise runs lookup and stores the
+// result. Failures to read or write the cache only cost a fresh lookup.
+func cached(key, fingerprint string, lookup func() string) string {
+	path, err := cachePath()
+	if err != nil {
+		return lookup()
+	}
+	cacheMu.Lock()
+	e, ok := readCache(path)[key]
+	cacheMu.Unlock()
+	if ok && e.Fingerprint == fingerprint && time.Since(e.ResolvedAt) < cacheTTL {
+		return e.Value
+	}
+
+	value := lookup()
+	// Re-read so entries other lookups stored meanwhile are kept
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	entries := readCache(path)
+	entries[key] = entry{Value: value, Fingerprint: fingerprint, ResolvedAt: time.Now()}
+	if data, err := json.MarshalIndent(entries, "", "  "); err == nil {
+		_ = os.WriteFile(path, data, 0600)
+	}
+	return value
+}
+
+// readCache reads the cache file; a missing or corrupt file is empty.
+func readCache(path string) map[string]entry {
+	entries := map[string]entry{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return entries
+}
+
+// cachePath returns the path to ~/.gxcontext.
+func cachePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, DefaultCacheFile), nil
+}
+
+// fingerprint summarizes the presence and modification times of files, plus
+// any extra values (environment variables) the result depends on.
+func fingerprint(paths []string, extra ...string) string {
+	parts := append([]string{}, extra...)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			parts = append(parts, path+":missing")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", path, info.ModTime().UnixNano()))
+	}
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,92 @@
+// Package envctx describes the infrastructure tools the user works with
+// (the current Kubernetes context, the gcloud project, the AWS account, the
+// Terraform workspace, ...) and the language runtimes installed for the
+// system prompt, so generated commands target what the user has selected
+// rather than the model's guess.
+// Lookups that run a CLI are cached in ~/.gxcontext. StagedChanges adds a
+// repository's staged changes to requests about committing.
+package envctx
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+const (
+	// lookupTimeout bounds each CLI a lookup runs. gcloud alone can take a
+	// second to start.
+	lookupTimeout = 3 * time.Second
+	// collectTimeout bounds Collect as a whole, so an unreachable cluster
+	// can't hold up a request for long; lookups still running are dropped.
+	collectTimeout = 3 * time.Second
+)
+
+// Options configures Collect.
+type Options struct {
+	// Dir is the directory commands run in, searched for project files such
+	// as Terraform configuration and lockfiles; "" is the current directory
+	Dir string
+	// AWS adds the active AWS profile's account, which takes a request to
+	// AWS to find out
+	AWS bool
+}
+
+// Collect returns a "- " context line for each tool that is installed and
+// configured, skipping any whose lookup fails or misses collectTimeout. The
+// lookups run concurrently.
+func Collect(ctx context.Context, opts Options) []string {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	lookups := []func(context.Context) string{
+		kubernetes,
+		gcloud,
+		func(ctx context.Context) string { return terraform(ctx, dir) },
+		func(ctx context.Context) string { return runtimes(ctx, dir) },
+	}
+	if opts.AWS {
+		lookups = append(lookups, aws)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, collectTimeout)
+	defer cancel()
+	type result struct {
+		i    int
+		line string
+	}
+	results := make(chan result, len(lookups))
+	for i, lookup := range lookups {
+		go func(i int, lookup func(context.Context) string) {
+			results <- result{i, lookup(ctx)}
+		}(i, lookup)
+	}
+	found := make([]string, len(lookups))
+collect:
+	for range lookups {
+		select {
+		case r := <-results:
+			found[r.i] = r.line
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	// Kept in lookup order, so the prompt is the same from run to run
+	var lines []string
+	for _, line := range found {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// output runs a CLI with lookupTimeout and returns its standard output,
+// which is returned along with any error.
+func output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
@@ -0,0 +1,87 @@
+package envctx
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// gcloudEnv are the variables that override the active configuration.
+var gcloudEnv = []string{"CLOUDSDK_CONFIG", "CLOUDSDK_ACTIVE_CONFIG_NAME", "CLOUDSDK_CORE_ACCOUNT", "CLOUDSDK_CORE_PROJECT", "CLOUDSDK_COMPUTE_REGION", "CLOUDSDK_COMPUTE_ZONE"}
+
+// gcloud describes the active gcloud configuration's account, project, and
+// default compute region and zone, or returns "" when gcloud isn't
+// installed or has none of them set.
+func gcloud(ctx context.Context) string {
+	cli, err := exec.LookPath("gcloud")
+	if err != nil {
+		return ""
+	}
+	extra := []string{cli}
+	for _, key := range gcloudEnv {
+		extra = append(extra, key+"="+os.Getenv(key))
+	}
+	return cached("gcloud", fingerprint(gcloudConfigPaths(), extra...), func() string {
+		return lookupGcloud(ctx, cli)
+	})
+}
+
+// gcloudConfigPaths returns the files `gcloud config set` and
+// `gcloud config configurations activate` write.
+func gcloudConfigPaths() []string {
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" && runtime.GOOS == "windows" {
+		dir = filepath.Join(os.Getenv("APPDATA"), "gcloud")
+	} else if dir == "" {
+		homeDir, _ := os.UserHomeDir()
+		dir = filepath.Join(homeDir, ".config", "gcloud")
+	}
+	active := "default"
+	if data, err := os.ReadFile(filepath.Join(dir, "active_config")); err == nil && strings.TrimSpace(string(data)) != "" {
+		active = strings.TrimSpace(string(data))
+	}
+	if name := os.Getenv("CLOUDSDK_ACTIVE_CONFIG_NAME"); name != "" {
+		active = name
+	}
+	return []string{filepath.Join(dir, "active_config"), filepath.Join(dir, "configurations", "config_"+active)}
+}
+
+// lookupGcloud reads the active configuration's properties.
+func lookupGcloud(ctx context.Context, cli string) string {
+	out, err := output(ctx, cli, "config", "list", "--format=json")
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		Core struct {
+			Account string `json:"account"`
+			Project string `json:"project"`
+		} `json:"core"`
+		Compute struct {
+			Region string `json:"region"`
+			Zone   string `json:"zone"`
+		} `json:"compute"`
+	}
+	if json.Unmarshal(out, &config) != nil {
+		return ""
+	}
+	var parts []string
+	for _, p := range [][2]string{
+		{"account", config.Core.Account},
+		{"project", config.Core.Project},
+		{"compute region", config.Compute.Region},
+		{"zone", config.Compute.Zone},
+	} {
+		if p[1] != "" {
+			parts = append(parts, p[0]+" "+p[1])
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "- Google Cloud: " + strings.Join(parts, ", ") + " (gcloud and gsutil commands use these defaults, so leave out --project, --region, and --zone unless the request names others)"
+}
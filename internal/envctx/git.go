@@ -0,0 +1,111 @@
+package envctx
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/ignore"
+)
+
+const (
+	// maxStatBytes truncates the `git diff --cached --stat` summary.
+	maxStatBytes = 4 * 1024
+	// maxDiffBytes truncates the staged diff itself (gx --diff).
+	maxDiffBytes = 16 * 1024
+)
+
+var (
+	// commitWords match requests about committing or reviewing changes.
+	commitWords = regexp.MustCompile(`(?i)\bcommit(?:s|ted|ting)?\b|\bpull request|\bmerge request|\bamend\b|\bsquash\b`)
+	// prWords match "PR" and "MR", which are too short to match
+	// case-insensitively.
+	prWords = regexp.MustCompile(`\b(PR|MR)s?\b`)
+)
+
+// MentionsCommit reports whether a request is about committing, amending,
+// or opening a pull request, for which the staged changes are context.
+func MentionsCommit(prompt string) bool {
+	return commitWords.MatchString(prompt) || prWords.MatchString(prompt)
+}
+
+// StagedChanges returns the `git diff --cached --stat` of the repository in
+// dir ("" is the current directory), followed by the diff itself when
+// withDiff is set, each truncated. Files ignored matches (the repository's
+// .gxignore) are left out of the diff, and the diff is dropped when they
+// can't be listed. It returns "" when nothing is staged or dir isn't in a
+// git repository.
+func StagedChanges(ctx context.Context, dir string, withDiff bool, ignored *ignore.Matcher) string {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		return ""
+	}
+	gitArgs := func(args ...string) []string {
+		if dir != "" {
+			return append([]string{"-C", dir}, args...)
+		}
+		return args
+	}
+	stat, err := output(ctx, git, gitArgs("diff", "--cached", "--stat")...)
+	if err != nil || len(strings.TrimSpace(string(stat))) == 0 {
+		return ""
+	}
+	changes := "STAGED CHANGES (git diff --cached --stat):\n" + truncate(string(stat), maxStatBytes)
+	if withDiff {
+		excluded, err := excludedPathspecs(ctx, git, gitArgs, dir, ignored)
+		if err != nil {
+			return changes
+		}
+		if diff, err := output(ctx, git, gitArgs(append([]string{"diff", "--cached", "--"}, excluded...)...)...); err == nil {
+			changes += "\n\nSTAGED DIFF (git diff --cached):\n" + truncate(string(diff), maxDiffBytes)
+		}
+	}
+	return changes
+}
+
+// excludedPathspecs returns pathspecs that keep the staged files ignored
+// matches out of a diff: the whole tree, then an :(exclude) for each file.
+func excludedPathspecs(ctx context.Context, git string, gitArgs func(...string) []string, dir string, ignored *ignore.Matcher) ([]string, error) {
+	if ignored == nil {
+		return nil, nil
+	}
+	// Staged paths are relative to the repository root, which is cdup
+	// from dir
+	cdup, err := output(ctx, git, gitArgs("rev-parse", "--show-cdup")...)
+	if err != nil {
+		return nil, err
+	}
+	names, err := output(ctx, git, gitArgs("diff", "--cached", "--name-only", "-z")...)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(filepath.Join(dir, strings.TrimSpace(string(cdup))))
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []string
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name != "" && ignored.Ignored(filepath.Join(root, filepath.FromSlash(name)), false) {
+			specs = append(specs, ":(top,exclude,literal)"+name)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	return append([]string{":(top)"}, specs...), nil
+}
+
+// truncate cuts text to max bytes at a line boundary, noting the cut.
+func truncate(text string, max int) string {
+	text = strings.TrimRight(text, "\n")
+	if len(text) <= max {
+		return text
+	}
+	if i := strings.LastIndexByte(text[:max], '\n'); i > 0 {
+		max = i
+	}
+	return text[:max] + "\n... (truncated)"
+}
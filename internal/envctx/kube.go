@@ -0,0 +1,82 @@
+package envctx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// kubernetes describes kubectl's current context, its namespace, and the
+// cluster's version, or returns "" when kubectl isn't installed or has no
+// current context.
+func kubernetes(ctx context.Context) string {
+	kubectl, err := exec.LookPath("kubectl")
+	if err != nil {
+		return ""
+	}
+	return cached("kubernetes", fingerprint(kubeconfigPaths(), kubectl), func() string {
+		return lookupKubernetes(ctx, kubectl)
+	})
+}
+
+// kubeconfigPaths returns the files kubectl reads its configuration from:
+// those listed in KUBECONFIG, or ~/.kube/config.
+func kubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(homeDir, ".kube", "config")}
+}
+
+// lookupKubernetes reads the current context from kubectl's merged config.
+func lookupKubernetes(ctx context.Context, kubectl string) string {
+	out, err := output(ctx, kubectl, "config", "view", "--minify", "-o", "json")
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		CurrentContext string `json:"current-context"`
+		Contexts       []struct {
+			Context struct {
+				Namespace string `json:"namespace"`
+			} `json:"context"`
+		} `json:"contexts"`
+	}
+	if json.Unmarshal(out, &config) != nil || config.CurrentContext == "" {
+		return ""
+	}
+	namespace := "default"
+	if len(config.Contexts) > 0 && config.Contexts[0].Context.Namespace != "" {
+		namespace = config.Contexts[0].Context.Namespace
+	}
+
+	line := fmt.Sprintf("- Kubernetes: context %s, namespace %s", config.CurrentContext, namespace)
+	if version := kubernetesVersion(ctx, kubectl); version != "" {
+		line += ", server " + version
+	}
+	return line + " (kubectl commands act on this context and namespace unless given --context or -n)"
+}
+
+// kubernetesVersion asks the cluster for its version, or returns "" when it
+// can't be reached in time. kubectl prints the client version and exits
+// non-zero in that case, so the output is read regardless of the error; its
+// request timeout is shorter than lookupTimeout so it gets to print.
+func kubernetesVersion(ctx context.Context, kubectl string) string {
+	out, _ := output(ctx, kubectl, "version", "-o", "json", "--request-timeout=1s")
+	var version struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if json.Unmarshal(out, &version) != nil {
+		return ""
+	}
+	return version.ServerVersion.GitVersion
+}
@@ -0,0 +1,139 @@
+package envctx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// runtimeVersionNumber matches the version in "Python 3.12.1", "v20.11.0",
+// "ruby 3.3.0p0 (...)", and "go version go1.22.1 linux/amd64".
+var runtimeVersionNumber = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// nodeLockfiles name the package manager a Node project uses, in the
+// order they are checked.
+var nodeLockfiles = []struct{ file, manager string }{
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"bun.lockb", "bun"},
+	{"bun.lock", "bun"},
+	{"package-lock.json", "npm"},
+}
+
+// runtimes describes the Python, Node.js, Ruby, and Go installed, with the
+// active virtualenv or conda environment, version manager, and package
+// manager, or returns "" when none is installed.
+func runtimes(ctx context.Context, dir string) string {
+	var parts []string
+	for _, describe := range []func(context.Context, string) string{python, node, ruby, golang} {
+		if part := describe(ctx, dir); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "- Language runtimes: " + strings.Join(parts, "; ") + " (install packages with these: the pip that exists, the active environment, the project's package manager)"
+}
+
+// python describes the Python on PATH, the active environment, and which
+// pip command exists.
+func python(ctx context.Context, _ string) string {
+	name, version := runtimeVersion(ctx, []string{"python3", "python"}, "--version")
+	if name == "" {
+		return ""
+	}
+	desc := "Python " + version + " (" + name
+	switch {
+	case os.Getenv("VIRTUAL_ENV") != "":
+		desc += ", virtualenv " + os.Getenv("VIRTUAL_ENV")
+	case os.Getenv("CONDA_DEFAULT_ENV") != "":
+		desc += ", conda env " + os.Getenv("CONDA_DEFAULT_ENV")
+	}
+	if _, err := exec.LookPath("pip"); err == nil {
+		desc += ", pip"
+	} else if _, err := exec.LookPath("pip3"); err == nil {
+		desc += ", pip3 only"
+	} else {
+		desc += ", no pip: use " + name + " -m pip"
+	}
+	return desc + ")"
+}
+
+// node describes the Node.js on PATH, whether nvm manages it, and the
+// project's package manager from its lockfile.
+func node(ctx context.Context, dir string) string {
+	_, version := runtimeVersion(ctx, []string{"node"}, "--version")
+	if version == "" {
+		return ""
+	}
+	var notes []string
+	if os.Getenv("NVM_BIN") != "" {
+		notes = append(notes, "via nvm")
+	}
+	for _, lock := range nodeLockfiles {
+		if _, err := os.Stat(filepath.Join(dir, lock.file)); err == nil {
+			notes = append(notes, "project uses "+lock.manager+", per "+lock.file)
+			break
+		}
+	}
+	if len(notes) == 0 {
+		return "Node.js " + version
+	}
+	return "Node.js " + version + " (" + strings.Join(notes, ", ") + ")"
+}
+
+// ruby describes the Ruby on PATH and whether rbenv selects it.
+func ruby(ctx context.Context, dir string) string {
+	_, version := runtimeVersion(ctx, []string{"ruby"}, "--version")
+	if version == "" {
+		return ""
+	}
+	if _, err := exec.LookPath("rbenv"); err != nil {
+		return "Ruby " + version
+	}
+	// rbenv's shim runs the version a .ruby-version selects, so the
+	// cached version may be another directory's
+	if data, err := os.ReadFile(filepath.Join(dir, ".ruby-version")); err == nil {
+		version = strings.TrimSpace(string(data))
+	}
+	if env := os.Getenv("RBENV_VERSION"); env != "" {
+		version = env
+	}
+	return "Ruby " + version + " (via rbenv)"
+}
+
+// golang describes the Go toolchain on PATH.
+func golang(ctx context.Context, _ string) string {
+	_, version := runtimeVersion(ctx, []string{"go"}, "version")
+	if version == "" {
+		return ""
+	}
+	return "Go " + version
+}
+
+// runtimeVersion finds the first of names on PATH and returns it with the
+// version it reports, cached until the binary changes.
+func runtimeVersion(ctx context.Context, names []string, args ...string) (name, version string) {
+	for _, name := range names {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		version := cached("version:"+path, fingerprint([]string{path}), func() string {
+			out, err := output(ctx, path, args...)
+			if err != nil {
+				return ""
+			}
+			return runtimeVersionNumber.FindString(string(out))
+		})
+		if version == "" {
+			continue
+		}
+		return name, version
+	}
+	return "", ""
+}
@@ -0,0 +1,123 @@
+package envctx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxTerraformFiles caps the .tf files read for the backend and
+	// required version.
+	maxTerraformFiles = 50
+	// maxTerraformProviders caps the providers listed from the lock file.
+	maxTerraformProviders = 10
+)
+
+var (
+	// terraformBackend matches a backend block (backend "s3" {) or HCP
+	// Terraform's cloud block.
+	terraformBackend = regexp.MustCompile(`(?m)^\s*(?:backend\s+"([^"]+)"|(cloud))\s*\{`)
+	// terraformRequired matches required_version = ">= 1.5".
+	terraformRequired = regexp.MustCompile(`(?m)^\s*required_version\s*=\s*"([^"]+)"`)
+	// lockedProvider matches a provider and its version in .terraform.lock.hcl.
+	lockedProvider = regexp.MustCompile(`(?m)^provider\s+"(?:[^"/]+/)?([^"]+)"\s*\{\s*\n\s*version\s*=\s*"([^"]+)"`)
+)
+
+// terraform describes the Terraform configuration in dir: its backend, the
+// selected workspace, the installed and required Terraform versions, and
+// the locked provider versions. It returns "" when dir has no .tf files.
+func terraform(ctx context.Context, dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if len(files) == 0 {
+		return ""
+	}
+	if len(files) > maxTerraformFiles {
+		files = files[:maxTerraformFiles]
+	}
+	backend, required := "local", ""
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if m := terraformBackend.FindSubmatch(data); m != nil {
+			backend = string(m[1]) + string(m[2])
+		}
+		if m := terraformRequired.FindSubmatch(data); m != nil {
+			required = string(m[1])
+		}
+	}
+
+	parts := []string{"backend " + backend, "workspace " + terraformWorkspace(dir)}
+	version := terraformVersion(ctx)
+	switch {
+	case version != "" && required != "":
+		parts = append(parts, fmt.Sprintf("terraform %s (required %s)", version, required))
+	case version != "":
+		parts = append(parts, "terraform "+version)
+	case required != "":
+		parts = append(parts, "terraform "+required+" required, not installed")
+	}
+	if providers := lockedProviders(dir); len(providers) > 0 {
+		parts = append(parts, "providers "+strings.Join(providers, ", "))
+	}
+	return "- Terraform project in the working directory: " + strings.Join(parts, ", ") + " (plan and apply act on this workspace; use syntax and provider arguments these versions support)"
+}
+
+// terraformWorkspace returns the selected workspace: TF_WORKSPACE, or the
+// one `terraform workspace select` recorded.
+func terraformWorkspace(dir string) string {
+	if ws := os.Getenv("TF_WORKSPACE"); ws != "" {
+		return ws
+	}
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = filepath.Join(dir, ".terraform")
+	}
+	if data, err := os.ReadFile(filepath.Join(dataDir, "environment")); err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data))
+	}
+	return "default"
+}
+
+// terraformVersion returns the installed Terraform's version, cached until
+// the binary changes.
+func terraformVersion(ctx context.Context) string {
+	cli, err := exec.LookPath("terraform")
+	if err != nil {
+		return ""
+	}
+	return cached("terraform", fingerprint([]string{cli}), func() string {
+		out, err := output(ctx, cli, "version", "-json")
+		if err != nil {
+			return ""
+		}
+		var version struct {
+			TerraformVersion string `json:"terraform_version"`
+		}
+		if json.Unmarshal(out, &version) != nil {
+			return ""
+		}
+		return version.TerraformVersion
+	})
+}
+
+// lockedProviders lists the providers `terraform init` locked, as
+// "hashicorp/aws 5.31.0".
+func lockedProviders(dir string) []string {
+	data, err := os.ReadFile(filepath.Join(dir, ".terraform.lock.hcl"))
+	if err != nil {
+		return nil
+	}
+	var providers []string
+	for _, m := range lockedProvider.FindAllSubmatch(data, maxTerraformProviders) {
+		providers = append(providers, string(m[1])+" "+string(m[2]))
+	}
+	return providers
+}
@@ -0,0 +1,136 @@
+// Package envset loads environment variables for executed commands from
+// --env flags and named sets in ~/.gxenv.
+package envset
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultEnvFile is the default path for the env set file.
+const DefaultEnvFile = ".gxenv"
+
+// File holds the variables from ~/.gxenv. Variables before the first
+// [section] apply to every executed command; each [name] section is a set
+// selected with --env-set name.
+//
+//	EDITOR=vim
+//
+//	[staging]
+//	AWS_PROFILE=staging
+//	API_URL="https://staging.example.com"
+type File struct {
+	defaults []string
+	sets     map[string][]string
+}
+
+// Load reads ~/.gxenv. A missing file is an empty File.
+func Load() (*File, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return LoadFile(filepath.Join(homeDir, DefaultEnvFile))
+}
+
+// LoadFile reads an env set file. A missing file is an empty File.
+func LoadFile(path string) (*File, error) {
+	f := &File{sets: make(map[string][]string)}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return nil, fmt.Errorf("%s:%d: empty set name", path, lineNum)
+			}
+			if _, ok := f.sets[section]; !ok {
+				f.sets[section] = nil
+			}
+			continue
+		}
+
+		assignment, err := Parse(strings.TrimPrefix(line, "export "))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		if section == "" {
+			f.defaults = append(f.defaults, assignment)
+		} else {
+			f.sets[section] = append(f.sets[section], assignment)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Env returns the default variables followed by those of each named set, as
+// KEY=VALUE strings. Later entries override earlier ones for the same key.
+func (f *File) Env(names []string) ([]string, error) {
+	env := append([]string(nil), f.defaults...)
+	for _, name := range names {
+		set, ok := f.sets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown env set %q (defined in ~/%s: %s)", name, DefaultEnvFile, f.setNames())
+		}
+		env = append(env, set...)
+	}
+	return env, nil
+}
+
+// setNames lists the defined sets for error messages.
+func (f *File) setNames() string {
+	if len(f.sets) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(f.sets))
+	for name := range f.sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Parse validates a KEY=VALUE assignment and returns it with surrounding
+// quotes removed from the value.
+func Parse(assignment string) (string, error) {
+	key, value, ok := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", fmt.Errorf("invalid environment assignment %q (want KEY=VALUE)", assignment)
+	}
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key + "=" + value, nil
+}
+
+// Keys returns the variable names in env, for logging without values.
+func Keys(env []string) []string {
+	keys := make([]string, len(env))
+	for i, kv := range env {
+		keys[i], _, _ = strings.Cut(kv, "=")
+	}
+	return keys
+}
@@ -0,0 +1,130 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// agentCorrection is sent when an agent reply isn't the expected JSON.
+const agentCorrection = `Reply with only the JSON object described in the instructions: {"plan", "command", "explanation", "risk", "done", "summary"}.`
+
+// AgentStep is the model's next move in agent mode.
+type AgentStep struct {
+	// Plan is the remaining plan, revised after each result.
+	Plan string `json:"plan"`
+	// Command is the next command to run; empty when Done.
+	Command string `json:"command"`
+	// Explanation says what the step does in one sentence.
+	Explanation string `json:"explanation"`
+	// Risk is RiskLow, RiskMedium, or RiskHigh.
+	Risk string `json:"risk"`
+	// Done is set when the goal is achieved or abandoned.
+	Done bool `json:"done"`
+	// Summary describes the outcome once Done is set.
+	Summary string `json:"summary"`
+}
+
+// agentSchema is the response schema for AgentStep.
+func agentSchema() *genai.Schema {
+	text := func(description string) *genai.Schema {
+		return &genai.Schema{Type: genai.TypeString, Description: description}
+	}
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"plan":        text("The remaining steps as a short numbered list."),
+			"command":     text("The next command to run, or empty when done."),
+			"explanation": text("One sentence saying what the command does."),
+			"risk":        {Type: genai.TypeString, Enum: []string{RiskLow, RiskMedium, RiskHigh}},
+			"done":        {Type: genai.TypeBoolean, Description: "Whether the goal is achieved or cannot be achieved."},
+			"summary":     text("When done, what was accomplished or why not."),
+		},
+		Required: []string{"plan", "command", "explanation", "risk", "done", "summary"},
+	}
+}
+
+// AgentSession is a running agent-mode conversation. The client must have
+// been created with Config.Mode set to ModeAgent.
+type AgentSession struct {
+	c         *Client
+	chat      *genai.ChatSession
+	promptLog *transcript
+	turn      int
+}
+
+// StartAgent sends the goal and returns the session with the first step.
+func (c *Client) StartAgent(ctx context.Context, goal string) (*AgentSession, AgentStep, error) {
+	if c.mode != ModeAgent {
+		return nil, AgentStep{}, fmt.Errorf("agent mode not enabled for this client")
+	}
+	s := &AgentSession{
+		c:         c,
+		chat:      c.model.StartChat(),
+		promptLog: &transcript{},
+	}
+	s.promptLog.add(promptRecord{Type: recordSystem, Model: c.model.Name(), Mode: string(c.mode), Text: c.buildSystemInstruction()})
+	step, err := s.Next(ctx, "GOAL: "+goal)
+	return s, step, err
+}
+
+// Next sends feedback on the previous step (its output, or that the user
+// skipped it) and returns the following step.
+func (s *AgentSession) Next(ctx context.Context, feedback string) (AgentStep, error) {
+	// Every step is a request against the local rate limit
+	if err := s.c.limiter.Acquire(); err != nil {
+		return AgentStep{}, err
+	}
+
+	// Attachments go with the goal, in the first message
+	first := s.turn == 0
+	if first {
+		s.promptLog.add(promptRecord{Type: recordUser, Text: feedback, Attachments: s.c.attachmentNames()})
+	} else {
+		s.promptLog.addText(recordUser, feedback)
+	}
+	defer s.c.writePromptLog(s.promptLog)
+
+	message := feedback
+	for attempt := 0; ; attempt++ {
+		parts := []genai.Part{genai.Text(message)}
+		if first && attempt == 0 {
+			parts = s.c.promptParts(message)
+		}
+		resp, err := s.c.send(ctx, s.chat, s.turn, parts...)
+		s.turn++
+		if err != nil {
+			return AgentStep{}, fmt.Errorf("failed to generate response: %w", err)
+		}
+		result, err := s.c.processResponse(ctx, s.chat, resp, s.promptLog)
+		if err != nil {
+			return AgentStep{}, err
+		}
+		if step, ok := decodeAgentStep(result); ok {
+			return step, nil
+		}
+		if attempt >= maxCorrections {
+			return AgentStep{}, invalidf("model did not reply with an agent step after %d attempts", maxCorrections)
+		}
+		s.c.logger.Info("agent reply is not a step, re-prompting", "attempt", attempt+1)
+		message = agentCorrection
+		s.promptLog.addText(recordCorrection, message)
+	}
+}
+
+// decodeAgentStep parses an agent reply, tolerating code fences. It reports
+// false unless the reply has a command or is marked done.
+func decodeAgentStep(response string) (AgentStep, bool) {
+	var step AgentStep
+	if !decodeJSONObject(response, &step) {
+		return AgentStep{}, false
+	}
+	step.Command, _ = stripMarkdown(step.Command)
+	step.Risk = strings.ToLower(strings.TrimSpace(step.Risk))
+	if step.Done {
+		step.Command = ""
+	}
+	return step, step.Done || step.Command != ""
+}
@@ -0,0 +1,68 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// Alternative is one distinct approach to a request, with a short tradeoff note.
+type Alternative struct {
+	Command  string
+	Tradeoff string
+	// Risk is the model's rating, when it gave one (Result.Options)
+	Risk string
+}
+
+const (
+	alternativeCommandPrefix  = "COMMAND:"
+	alternativeTradeoffPrefix = "TRADEOFF:"
+)
+
+// alternativesInstruction describes the output format used when alternatives are requested.
+func alternativesInstruction(n int) string {
+	return fmt.Sprintf(`OUTPUT FORMAT (overrides rule 1):
+Provide exactly %d distinct approaches that use different tools or techniques (for example find vs fd vs ls piping).
+Write each approach as exactly two lines, and separate approaches with a blank line:
+%s <single-line command>
+%s <one-line note on when to prefer it or what it costs>`, n, alternativeCommandPrefix, alternativeTradeoffPrefix)
+}
+
+// GenerateAlternatives asks for distinct approaches to the prompt in a single request.
+// The client must have been created with Config.Alternatives set.
+func (c *Client) GenerateAlternatives(ctx context.Context, prompt string, historyContext []history.Entry) ([]Alternative, error) {
+	if c.alternatives < 2 {
+		return nil, fmt.Errorf("alternatives not enabled for this client")
+	}
+
+	response, err := c.Generate(ctx, prompt, historyContext)
+	if err != nil {
+		return nil, err
+	}
+
+	alternatives := ParseAlternatives(response)
+	if len(alternatives) == 0 {
+		return nil, fmt.Errorf("model did not return alternatives in the expected format")
+	}
+	return alternatives, nil
+}
+
+// ParseAlternatives extracts COMMAND/TRADEOFF pairs from a response.
+func ParseAlternatives(response string) []Alternative {
+	var alternatives []Alternative
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, alternativeCommandPrefix):
+			command, _ := stripMarkdown(strings.TrimPrefix(line, alternativeCommandPrefix))
+			if command != "" {
+				alternatives = append(alternatives, Alternative{Command: command})
+			}
+		case strings.HasPrefix(line, alternativeTradeoffPrefix) && len(alternatives) > 0:
+			alternatives[len(alternatives)-1].Tradeoff = strings.TrimSpace(strings.TrimPrefix(line, alternativeTradeoffPrefix))
+		}
+	}
+	return alternatives
+}
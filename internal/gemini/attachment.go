@@ -0,0 +1,179 @@
+package gemini
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// maxAttachmentBytes is the largest file Vertex AI accepts inline.
+const maxAttachmentBytes = 7 << 20
+
+// imageTypes are the image formats Gemini accepts, by extension, for files
+// whose content http.DetectContentType doesn't recognize (HEIC, HEIF).
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+}
+
+// audioTypes are the audio formats Gemini accepts, by extension; voice memo
+// containers aren't reliably told apart by content.
+var audioTypes = map[string]string{
+	".wav":  "audio/wav",
+	".mp3":  "audio/mp3",
+	".m4a":  "audio/m4a",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/opus",
+	".flac": "audio/flac",
+	".aiff": "audio/aiff",
+	".aif":  "audio/aiff",
+	".webm": "audio/webm",
+}
+
+// Attachment is a file sent to the model as its own content part alongside
+// the prompt, such as a screenshot of an error dialog (--image), a voice
+// memo (--audio), or a file named with @path in the prompt.
+type Attachment struct {
+	// Name is the file name shown to the model and in the prompt log.
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// LoadImage reads an image to attach to the prompt. PNG, JPEG, WebP, HEIC,
+// and HEIF are supported.
+func LoadImage(path string) (Attachment, error) {
+	data, err := readAttachment(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	mimeType := imageType(path, data)
+	if mimeType == "" {
+		return Attachment{}, fmt.Errorf("%s is not a supported image (use PNG, JPEG, WebP, HEIC, or HEIF)", path)
+	}
+	return Attachment{Name: filepath.Base(path), MIMEType: mimeType, Data: data}, nil
+}
+
+// LoadAudio reads a short recording, such as a voice memo describing the
+// task, to attach to the prompt. WAV, MP3, M4A, AAC, Ogg, Opus, FLAC, AIFF,
+// and WebM are supported.
+func LoadAudio(path string) (Attachment, error) {
+	mimeType := audioTypes[strings.ToLower(filepath.Ext(path))]
+	if mimeType == "" {
+		return Attachment{}, fmt.Errorf("%s is not a supported recording (use WAV, MP3, M4A, AAC, Ogg, Opus, FLAC, AIFF, or WebM)", path)
+	}
+	data, err := readAttachment(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	return Attachment{Name: filepath.Base(path), MIMEType: mimeType, Data: data}, nil
+}
+
+// LoadFile reads a file to attach to the prompt under name: text (source
+// code, config, logs) as text/plain, which is what Gemini accepts for any
+// text, labeled with its name so the model knows the language; images; and
+// PDFs. Other binary files are rejected.
+func LoadFile(path, name string) (Attachment, error) {
+	data, err := readAttachment(path)
+	if err != nil {
+		return Attachment{}, err
+	}
+	mimeType := imageType(path, data)
+	switch {
+	case mimeType != "":
+	case http.DetectContentType(data) == "application/pdf":
+		mimeType = "application/pdf"
+	case utf8.Valid(data) && !bytes.ContainsRune(data, 0):
+		mimeType = "text/plain"
+	default:
+		return Attachment{}, fmt.Errorf("%s is a binary file; only text, images, and PDFs can be attached", path)
+	}
+	return Attachment{Name: name, MIMEType: mimeType, Data: data}, nil
+}
+
+// readAttachment reads a file, enforcing the inline size limit.
+func readAttachment(path string) ([]byte, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory; attach files", path)
+	}
+	if info.Size() > maxAttachmentBytes {
+		return nil, fmt.Errorf("%s is %d MB; the limit is %d MB (crop or excerpt it)", path, info.Size()>>20, maxAttachmentBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	return data, nil
+}
+
+// imageType returns the MIME type of a supported image, or "".
+func imageType(path string, data []byte) string {
+	switch mimeType := http.DetectContentType(data); {
+	case isImageType(mimeType):
+		return mimeType
+	case mimeType == "application/octet-stream":
+		return imageTypes[strings.ToLower(filepath.Ext(path))]
+	}
+	return ""
+}
+
+// isImageType reports whether mimeType is one of imageTypes.
+func isImageType(mimeType string) bool {
+	for _, t := range imageTypes {
+		if t == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// Digest identifies the attachment's content, for cache keys.
+func (a Attachment) Digest() string {
+	sum := sha256.Sum256(a.Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// String describes the attachment for logs, e.g. "error.png (image/png, 48 KB)".
+func (a Attachment) String() string {
+	return fmt.Sprintf("%s (%s, %d KB)", a.Name, a.MIMEType, (len(a.Data)+1023)/1024)
+}
+
+// promptParts returns the prompt followed by each attachment, labeled with
+// its name so the prompt can refer to it.
+func (c *Client) promptParts(prompt string) []genai.Part {
+	parts := []genai.Part{genai.Text(prompt)}
+	for _, a := range c.attachments {
+		parts = append(parts, genai.Text("Attached file "+a.Name+":"), genai.Blob{MIMEType: a.MIMEType, Data: a.Data})
+	}
+	return parts
+}
+
+// attachmentNames describes the attachments for the prompt log.
+func (c *Client) attachmentNames() []string {
+	var names []string
+	for _, a := range c.attachments {
+		names = append(names, a.String())
+	}
+	return names
+}
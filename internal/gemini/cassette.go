@@ -0,0 +1,58 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	"github.com/nealhardesty/gx/internal/vcr"
+)
+
+// openCassette returns the cassette named by path (or GX_CASSETTE), or nil
+// when none is configured. GX_CASSETTE_MODE picks record or replay; by
+// default an existing cassette is replayed and a missing one recorded.
+func openCassette(path string) (*vcr.Cassette, error) {
+	if path == "" {
+		path = os.Getenv("GX_CASSETTE")
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	mode := cassetteMode(path)
+	switch mode {
+	case "record":
+		return vcr.Record(path), nil
+	case "replay":
+		return vcr.Load(path)
+	}
+	return nil, fmt.Errorf("unknown GX_CASSETTE_MODE %q (expected record or replay)", mode)
+}
+
+// cassetteMode returns GX_CASSETTE_MODE, defaulting to replay for an
+// existing cassette and record for a missing one.
+func cassetteMode(path string) string {
+	mode := strings.ToLower(os.Getenv("GX_CASSETTE_MODE"))
+	if mode == "" {
+		mode = "replay"
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			mode = "record"
+		}
+	}
+	return mode
+}
+
+// cassetteClient returns an HTTP client that records through the real API,
+// authenticated with creds, or replays without touching the network.
+func cassetteClient(cassette *vcr.Cassette, creds *google.Credentials) *http.Client {
+	var base http.RoundTripper
+	if cassette.Recording() {
+		base = &oauth2.Transport{Source: creds.TokenSource, Base: http.DefaultTransport}
+	}
+	return &http.Client{Transport: cassette.Transport(base)}
+}
@@ -0,0 +1,61 @@
+package gemini
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// replayClient returns a client that replays testdata/list-files.json, with
+// the environment cleared of settings that would change the requests.
+func replayClient(t *testing.T) *Client {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	for _, key := range []string{"GX_PROVIDER", "GX_CASSETTE", "GX_MODEL", "GX_LOCATION", "GX_ESCALATE_TO"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("GX_CASSETTE_MODE", "replay")
+
+	client, err := NewClient(context.Background(), Config{
+		Model:    "gemini-2.5-flash-lite",
+		Cassette: "testdata/list-files.json",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestGenerateReplaysCassette drives a client end to end from a recorded
+// cassette: a pwd tool call, then the structured answer.
+func TestGenerateReplaysCassette(t *testing.T) {
+	client := replayClient(t)
+
+	result, err := client.GenerateResult(context.Background(), "list files", nil)
+	if err != nil {
+		t.Fatalf("GenerateResult: %v", err)
+	}
+	if result.Command != "ls -la" {
+		t.Errorf("Command = %q, want %q", result.Command, "ls -la")
+	}
+	if result.Risk != RiskLow {
+		t.Errorf("Risk = %q, want %q", result.Risk, RiskLow)
+	}
+	if len(result.Tools) != 1 || !strings.HasPrefix(result.Tools[0].Call, "pwd(") {
+		t.Errorf("Tools = %+v, want one pwd call", result.Tools)
+	}
+}
+
+// TestReplayFailsOnUnrecordedRequest checks that a request past the end of
+// the cassette is an error rather than a live API call.
+func TestReplayFailsOnUnrecordedRequest(t *testing.T) {
+	client := replayClient(t)
+
+	if _, err := client.Generate(context.Background(), "list files", nil); err != nil {
+		t.Fatalf("first Generate: %v", err)
+	}
+	if _, err := client.Generate(context.Background(), "list files again", nil); err == nil {
+		t.Fatal("second Generate succeeded past the end of the cassette")
+	}
+}
@@ -0,0 +1,61 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// maxQuestions bounds how many clarifying questions one request can ask.
+const maxQuestions = 3
+
+// Messages continuing the chat after a question. assumeMessage replaces the
+// answer when the user can't be asked or the question limit is reached.
+const (
+	answerMessage = "The user answered: %s\nNow reply with the command JSON."
+	assumeMessage = "Don't ask any more questions: make the most reasonable assumption, mention it in the explanation, and reply with the command JSON."
+)
+
+// resolveQuestions handles replies where the model asked a clarifying
+// question instead of returning a command: it asks the user through
+// c.clarify and continues the same chat with the answer until a command
+// comes back. An empty answer tells the model to assume. It reports whether
+// the user answered anything; errors from c.clarify abort the request.
+func (c *Client) resolveQuestions(ctx context.Context, chat *genai.ChatSession, result string, promptLog *transcript) (string, bool, error) {
+	answered, assumed := false, false
+	for asked := 0; ; asked++ {
+		var r structuredResponse
+		if !decodeJSONObject(result, &r) || strings.TrimSpace(r.Command) != "" || strings.TrimSpace(r.Question) == "" {
+			return result, answered, nil
+		}
+		if assumed {
+			return "", answered, invalidf("model kept asking questions instead of returning a command")
+		}
+
+		message := assumeMessage
+		if asked < maxQuestions {
+			c.logger.Info("model asked a clarifying question", "question", r.Question)
+			answer, err := c.clarify(strings.TrimSpace(r.Question))
+			if err != nil {
+				return "", answered, err
+			}
+			if answer != "" {
+				message = fmt.Sprintf(answerMessage, answer)
+				answered = true
+			}
+		}
+		assumed = message == assumeMessage
+
+		promptLog.addText(recordQuestion, r.Question)
+		promptLog.addText(recordAnswer, message)
+		resp, err := c.send(ctx, chat, asked+1, genai.Text(message))
+		if err != nil {
+			return "", answered, fmt.Errorf("failed to send answer: %w", err)
+		}
+		if result, err = c.processResponse(ctx, chat, resp, promptLog); err != nil {
+			return "", answered, err
+		}
+	}
+}
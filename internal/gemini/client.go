@@ -3,18 +3,32 @@ package gemini
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/vertexai/genai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
 
+	"github.com/nealhardesty/gx/internal/envctx"
 	"github.com/nealhardesty/gx/internal/history"
+	"github.com/nealhardesty/gx/internal/ignore"
+	"github.com/nealhardesty/gx/internal/logging"
+	"github.com/nealhardesty/gx/internal/ratelimit"
+	"github.com/nealhardesty/gx/internal/shell"
+	"github.com/nealhardesty/gx/internal/telemetry"
 	"github.com/nealhardesty/gx/internal/tools"
+	"github.com/nealhardesty/gx/internal/vcr"
 )
 
 const (
@@ -29,9 +43,59 @@ type Client struct {
 	client   *genai.Client
 	model    *genai.GenerativeModel
 	tools    *tools.Registry
-	verbose  bool
-	shell    string
-	platform string
+	logger   *slog.Logger
+	comments bool
+	oneLiner bool
+	sampling Sampling
+	// alternatives is the number of distinct approaches requested (0 = normal output)
+	alternatives int
+	why          bool
+	undo         bool
+	language     string
+	// instructions is project guidance from .gxrc ("" = none)
+	instructions string
+	// attachments follow the prompt in the first message (--image)
+	attachments []Attachment
+	mode        Mode
+	shell       string
+	platform    string
+	limiter     *ratelimit.Limiter
+	// escalateTo is the stronger model retried on unusable answers ("" = off)
+	escalateTo string
+	// confidence is set when structured replies include the model's confidence
+	confidence bool
+	// structured is set when single commands come back as JSON (see schema.go)
+	structured bool
+	// clarify asks the user a question from the model (nil = don't ask)
+	clarify func(question string) (string, error)
+	// replay answers tool calls from a recording (gx replay)
+	replay  *Recording
+	workDir string
+	// customizations are the user's aliases and functions (--aliases)
+	customizations   shell.Customizations
+	interactiveShell bool
+	// posix restricts commands to POSIX sh (--posix)
+	posix bool
+	// goos is the operating system commands are for (runtime.GOOS unless --os)
+	goos string
+	// platformOverridden is set when platform and goos come from --os or
+	// --arch rather than this machine
+	platformOverridden bool
+	// infra are context lines about the user's infrastructure tools (see
+	// envctx.Collect)
+	infra []string
+	// host describes the --target machine for the prompt
+	host string
+	// shellOverridden is set when shell is --shell's target rather than
+	// the shell gx runs in, whose version says nothing about it
+	shellOverridden bool
+	// usage accumulates token counts across requests (see Usage)
+	usageMu sync.Mutex
+	usage   Usage
+	// projectID, location, and modelName identify where quota is charged
+	projectID string
+	location  string
+	modelName string
 }
 
 // Config holds configuration for the Gemini client.
@@ -39,60 +103,287 @@ type Config struct {
 	ProjectID string
 	Location  string
 	Model     string
-	Verbose   bool
-	NoTools   bool
+	// Comments asks for explanatory comments in the generated command.
+	Comments bool
+	NoTools  bool
+	// ReadOnlyTools offers only tools that inspect local state (pwd, ls,
+	// stat, cat, ps, ...), leaving out any that change things or use the network.
+	ReadOnlyTools bool
+	// Endpoint overrides the Vertex AI endpoint (host:port), e.g. a Private
+	// Service Connect or regional endpoint. Defaults to GX_ENDPOINT.
+	Endpoint string
+	// Transport selects "grpc" (default) or "rest". REST is often needed behind
+	// corporate proxies that don't support HTTP/2. Defaults to GX_TRANSPORT.
+	Transport string
+	// Logger receives tool tracing and diagnostics. Defaults to discarding.
+	Logger *slog.Logger
+	// Sampling overrides temperature, topP, topK, candidate count, and max output tokens.
+	Sampling Sampling
+	// OneLiner instructs the model to return a single-line command and
+	// re-prompts when it returns a multi-line script anyway.
+	OneLiner bool
+	// Alternatives, when 2 or more, asks for that many distinct approaches
+	// with tradeoff notes instead of a single command (see GenerateAlternatives).
+	Alternatives int
+	// Why asks for a one-sentence rationale alongside the command (see Result.Rationale).
+	Why bool
+	// Undo asks for the closest inverse of the command alongside it (see GenerateResult).
+	Undo bool
+	// Language is the language code (e.g. "de") for comments and explanations;
+	// commands stay standard. Defaults to GX_LANG (see ResolveLanguage).
+	Language string
+	// Mode selects a non-command task such as summarizing a man page.
+	Mode Mode
+	// RateLimiter caps requests per minute across gx processes. Nil means no limit.
+	RateLimiter *ratelimit.Limiter
+	// WorkDir is the directory the command will run in (gx -C). The process
+	// is expected to have changed into it already; this only tells the model.
+	WorkDir string
+	// EscalateTo is the model or alias to retry once on when an answer fails
+	// validation or the model's confidence is low. Defaults to
+	// GX_ESCALATE_TO, then "off".
+	EscalateTo string
+	// Clarify, when set, lets the model ask the user a question instead of
+	// guessing when a request is ambiguous. It is called with the question
+	// and returns the user's answer; the same chat then continues with it.
+	// An empty answer lets the model assume; an error aborts generation.
+	// Ignored for multiple candidates, alternatives, and non-command modes.
+	Clarify func(question string) (string, error)
+	// Provider is ProviderVertex or ProviderMock, which answers with canned
+	// commands and tool calls without credentials. Defaults to GX_PROVIDER.
+	Provider string
+	// Cassette is a file that records API traffic, or replays it without
+	// network access or credentials, for end-to-end tests (see package vcr).
+	// Defaults to GX_CASSETTE.
+	Cassette string
+	// Replay, when set, answers tool calls that match a call in the
+	// recording with the recorded response instead of running the tool.
+	Replay *Recording
+	// Instructions is project-specific guidance for the system prompt, such
+	// as "we deploy with make deploy, never kubectl directly" (see package project).
+	Instructions string
+	// AllowPaths, when non-nil, are the only absolute paths file tools may
+	// read (see tools.Registry.AllowPaths).
+	AllowPaths []string
+	// Ignore hides the paths a .gxignore excludes from tools (see tools.Registry.Ignore).
+	Ignore *ignore.Matcher
+	// Attachments are sent as separate parts after the prompt (see LoadImage).
+	Attachments []Attachment
+	// Customizations are the user's shell aliases and functions (gx
+	// --aliases), listed so commands can use them or avoid being changed by
+	// them.
+	Customizations shell.Customizations
+	// InteractiveShell tells the model the command runs with $SHELL -ic
+	// (gx -i), where the aliases and functions are defined.
+	InteractiveShell bool
+	// Shell generates for this shell instead of the detected one (gx
+	// --shell), e.g. for a command to paste into someone else's terminal.
+	// It must be one of TargetShells.
+	Shell string
+	// OS and Arch generate for another platform than the one gx runs on
+	// (gx --os, --arch), e.g. a Linux server from a Mac. OS must be one of
+	// TargetOSes; an empty field keeps the local value. Local environment
+	// variables are left out of the prompt, and without Shell the target
+	// OS's usual shell is assumed.
+	OS   string
+	Arch string
+	// POSIX restricts commands to POSIX sh (gx --posix), for minimal
+	// containers, BusyBox, and init scripts, and re-prompts on bashisms.
+	POSIX bool
+	// Host describes a remote machine from a host profile (gx --target),
+	// as "- " context lines (see hosts.Profile.Context). Like OS, it leaves
+	// this machine's environment out of the prompt.
+	Host string
+	// AWS adds the active AWS profile's account, identity, and region to
+	// the context (gx --aws), from a cached `aws sts get-caller-identity`.
+	AWS bool
+	// Infra are context lines about the user's infrastructure tools and
+	// runtimes (see CollectInfra). Nil collects them in NewClient; callers
+	// that cache answers collect them first so the cache key covers them.
+	Infra []string
 }
 
 // NewClient creates a new Gemini client.
-func NewClient(ctx context.Context, cfg Config) (*Client, error) {
-	if cfg.ProjectID == "" {
-		// Try to get project ID from gcloud
-		projectID, err := getDefaultProject()
+func NewClient(ctx context.Context, cfg Config) (_ *Client, err error) {
+	ctx, span := telemetry.Start(ctx, "gemini.NewClient")
+	defer func() { telemetry.End(span, err) }()
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Discard()
+	}
+
+	provider, err := ResolveProvider(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	mock := provider == ProviderMock
+
+	var cassette *vcr.Cassette
+	if !mock {
+		if cassette, err = openCassette(cfg.Cassette); err != nil {
+			return nil, err
+		}
+	}
+	// Replaying a cassette needs no credentials; it was recorded with them
+	replaying := cassette != nil && !cassette.Recording()
+
+	var creds *google.Credentials
+	if !mock && !replaying {
+		if creds, err = findCredentials(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case cfg.ProjectID != "":
+	case mock:
+		cfg.ProjectID = mockProject
+	case replaying:
+		cfg.ProjectID = cassette.Project
+	default:
+		// Try GOOGLE_CLOUD_PROJECT, the credentials, the cached project, then gcloud
+		projectID, err := resolveProject(creds)
 		if err != nil {
 			return nil, fmt.Errorf("no project ID specified and failed to get default: %w", err)
 		}
 		cfg.ProjectID = projectID
 	}
 
-	if cfg.Location == "" {
-		cfg.Location = DefaultLocation
+	if cfg.Location == "" && replaying {
+		cfg.Location = cassette.Location
 	}
+	cfg.Location = ResolveLocation(cfg.Location)
+
+	cfg.Model = ResolveModel(cfg.Model)
+	logger.Debug("creating client", "project", cfg.ProjectID, "location", cfg.Location, "model", cfg.Model)
 
-	if cfg.Model == "" {
-		cfg.Model = os.Getenv("GX_MODEL")
-		if cfg.Model == "" {
-			cfg.Model = DefaultModel
+	opts, err := clientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case mock:
+		transport := &mockTransport{mode: cfg.Mode, alternatives: cfg.Alternatives}
+		opts = append(opts, genai.WithREST(), option.WithHTTPClient(&http.Client{Transport: transport}))
+	case cassette != nil:
+		// Cassettes hold HTTP traffic, so they always use the REST transport
+		if cassette.Recording() {
+			cassette.Project, cassette.Location = cfg.ProjectID, cfg.Location
 		}
+		opts = append(opts, genai.WithREST(), option.WithHTTPClient(cassetteClient(cassette, creds)))
+	default:
+		opts = append(opts, option.WithCredentials(creds))
 	}
 
-	client, err := genai.NewClient(ctx, cfg.ProjectID, cfg.Location)
+	client, err := genai.NewClient(ctx, cfg.ProjectID, cfg.Location, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
-	toolRegistry := tools.NewRegistry(!cfg.NoTools)
+	toolRegistry := tools.NewRegistry(!cfg.NoTools, cfg.ReadOnlyTools)
+	toolRegistry.AllowPaths(cfg.AllowPaths)
+	toolRegistry.Ignore(cfg.Ignore)
+	for _, err := range toolRegistry.LoadPlugins(tools.PluginDir()) {
+		logger.Warn("skipping tool plugin", "error", err)
+	}
 	model := client.GenerativeModel(cfg.Model)
 
-	// Configure the model
-	model.SetTemperature(0.1) // Low temperature for deterministic output
-	model.SetTopP(0.95)
+	// Configure the model (low temperature by default for deterministic output)
+	sampling := ResolveSampling(cfg.Sampling)
+	sampling.apply(model)
+	logger.Debug("sampling", "params", sampling.String())
 
 	// Set up tools if enabled
 	if toolRegistry.IsEnabled() {
 		model.Tools = toolRegistry.GetToolDefinitions()
 	}
 
+	// Single commands, agent steps, and plans come back as JSON. Gemini rejects a
+	// response schema combined with function calling, so with tools the
+	// format is only requested in the system instruction.
+	structured := cfg.Mode.producesCommand() && cfg.Alternatives < 2
+
+	// Questions need a single conversation: candidates run concurrently
+	clarify := cfg.Clarify
+	if !structured || *sampling.CandidateCount > 1 {
+		clarify = nil
+	}
+	// The model rates its confidence only when there is a model to escalate to
+	escalateTo := ResolveEscalateTo(cfg.EscalateTo)
+	confidence := structured && escalateTo != "" && escalateTo != cfg.Model
+
+	var schema *genai.Schema
+	switch {
+	case structured:
+		schema = commandSchema(cfg.Undo, clarify != nil, confidence)
+	case cfg.Mode == ModeAgent:
+		schema = agentSchema()
+	case cfg.Mode == ModePlan:
+		schema = planSchema()
+	}
+	if schema != nil && !toolRegistry.IsEnabled() {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = schema
+	}
+
 	// Detect shell and platform
-	shell := detectShell()
+	shellName := ResolveShell(cfg.Shell)
 	platform := detectPlatform()
+	goos := runtime.GOOS
+	platformOverridden := cfg.OS != "" || cfg.Arch != "" || cfg.Host != ""
+	if platformOverridden {
+		if cfg.OS != "" {
+			goos = cfg.OS
+		}
+		arch := cfg.Arch
+		if arch == "" {
+			arch = runtime.GOARCH
+		}
+		platform = goos + "/" + arch
+		if cfg.Shell == "" && goos != runtime.GOOS {
+			shellName = defaultShells[goos]
+		}
+	}
 
 	c := &Client{
-		client:   client,
-		model:    model,
-		tools:    toolRegistry,
-		verbose:  cfg.Verbose,
-		shell:    shell,
-		platform: platform,
+		client:             client,
+		model:              model,
+		tools:              toolRegistry,
+		logger:             logger,
+		comments:           cfg.Comments,
+		oneLiner:           cfg.OneLiner,
+		sampling:           sampling,
+		alternatives:       cfg.Alternatives,
+		why:                cfg.Why,
+		undo:               cfg.Undo,
+		structured:         structured,
+		clarify:            clarify,
+		replay:             cfg.Replay,
+		workDir:            cfg.WorkDir,
+		customizations:     cfg.Customizations,
+		interactiveShell:   cfg.InteractiveShell,
+		posix:              cfg.POSIX,
+		shellOverridden:    cfg.Shell != "" || cfg.Host != "" || (cfg.OS != "" && cfg.OS != runtime.GOOS),
+		host:               cfg.Host,
+		goos:               goos,
+		platformOverridden: platformOverridden,
+		language:           ResolveLanguage(cfg.Language),
+		instructions:       cfg.Instructions,
+		attachments:        cfg.Attachments,
+		mode:               cfg.Mode,
+		shell:              shellName,
+		platform:           platform,
+		limiter:            cfg.RateLimiter,
+		escalateTo:         escalateTo,
+		confidence:         confidence,
+		projectID:          cfg.ProjectID,
+		location:           cfg.Location,
+		modelName:          cfg.Model,
+	}
+	c.infra = cfg.Infra
+	if c.infra == nil {
+		c.infra = CollectInfra(ctx, cfg)
 	}
 
 	// Set system instruction
@@ -105,6 +396,132 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 	return c, nil
 }
 
+// CollectInfra looks up the selected Kubernetes context, cloud accounts,
+// Terraform workspace, and language runtimes for the prompt (see
+// envctx.Collect). They belong to this machine, so there are none for
+// another platform or host, and none for the mock provider or a cassette
+// replay, whose answers don't depend on them. The result is never nil.
+func CollectInfra(ctx context.Context, cfg Config) []string {
+	if cfg.OS != "" || cfg.Arch != "" || cfg.Host != "" || cfg.Replay != nil {
+		return []string{}
+	}
+	if provider, _ := ResolveProvider(cfg.Provider); provider == ProviderMock {
+		return []string{}
+	}
+	cassette := cfg.Cassette
+	if cassette == "" {
+		cassette = os.Getenv("GX_CASSETTE")
+	}
+	if cassette != "" && cassetteMode(cassette) == "replay" {
+		return []string{}
+	}
+	lines := envctx.Collect(ctx, envctx.Options{Dir: cfg.WorkDir, AWS: cfg.AWS})
+	if lines == nil {
+		lines = []string{}
+	}
+	return lines
+}
+
+// clientOptions builds the endpoint and transport options for the Vertex AI client.
+// Both transports honor HTTPS_PROXY and NO_PROXY from the environment.
+func clientOptions(cfg Config) ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("GX_ENDPOINT")
+	}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	transport := cfg.Transport
+	if transport == "" {
+		transport = os.Getenv("GX_TRANSPORT")
+	}
+	switch strings.ToLower(transport) {
+	case "", "grpc":
+	case "rest", "http":
+		opts = append(opts, genai.WithREST())
+	default:
+		return nil, fmt.Errorf("unknown transport %q (expected grpc or rest)", transport)
+	}
+
+	return opts, nil
+}
+
+// ResolveModel returns the model name to use, falling back to GX_MODEL and then DefaultModel.
+// Aliases such as "fast" and "smart" are expanded (see ModelAliases).
+func ResolveModel(model string) string {
+	if model == "" {
+		model = os.Getenv("GX_MODEL")
+	}
+	if model == "" {
+		return DefaultModel
+	}
+	if concrete, ok := ModelAliases()[strings.ToLower(model)]; ok {
+		return concrete
+	}
+	return model
+}
+
+// defaultModelAliases maps the built-in aliases to concrete models.
+var defaultModelAliases = map[string]string{
+	"fast":  "gemini-2.5-flash-lite",
+	"smart": "gemini-2.5-pro",
+}
+
+// ModelAliases returns the model aliases and the concrete models they map to.
+// Each built-in alias can be remapped with GX_MODEL_<ALIAS> (e.g. GX_MODEL_SMART).
+func ModelAliases() map[string]string {
+	aliases := make(map[string]string, len(defaultModelAliases))
+	for alias, model := range defaultModelAliases {
+		if envModel := os.Getenv("GX_MODEL_" + strings.ToUpper(alias)); envModel != "" {
+			model = envModel
+		}
+		aliases[alias] = model
+	}
+	return aliases
+}
+
+// ResolveLocation returns the Vertex AI location to use, falling back to GX_LOCATION and then DefaultLocation.
+func ResolveLocation(location string) string {
+	if location != "" {
+		return location
+	}
+	if envLocation := os.Getenv("GX_LOCATION"); envLocation != "" {
+		return envLocation
+	}
+	return DefaultLocation
+}
+
+// ResolveLanguage returns the language for comments and explanations,
+// falling back to GX_LANG. An empty result means the model's default (English).
+func ResolveLanguage(language string) string {
+	if language != "" {
+		return language
+	}
+	return os.Getenv("GX_LANG")
+}
+
+// TargetOSes are the operating systems --os accepts.
+var TargetOSes = []string{"linux", "darwin", "windows"}
+
+// defaultShells is the shell assumed for a --os target without --shell.
+var defaultShells = map[string]string{"linux": "bash", "darwin": "zsh", "windows": "pwsh"}
+
+// TargetShells are the shells --shell accepts.
+var TargetShells = []string{"sh", "bash", "zsh", "fish", "ksh", "dash", "pwsh", "powershell", "cmd"}
+
+// ResolveShell returns the shell to generate for: shell when set (see
+// Config.Shell), otherwise the detected one.
+func ResolveShell(shell string) string {
+	if shell != "" {
+		return shell
+	}
+	return detectShell()
+}
+
 // Close closes the underlying client.
 func (c *Client) Close() error {
 	return c.client.Close()
@@ -119,11 +536,22 @@ func (c *Client) BuildPrompt(prompt string, historyContext []history.Entry) stri
 	systemInstruction := c.buildSystemInstruction()
 	parts = append(parts, fmt.Sprintf("SYSTEM INSTRUCTION:\n%s", systemInstruction))
 
+	// Add the function declarations sent alongside the prompt
+	if c.tools.IsEnabled() {
+		defs, err := c.tools.Definitions()
+		if err != nil {
+			defs = err.Error()
+		}
+		parts = append(parts, fmt.Sprintf("TOOL DEFINITIONS:\n%s", defs))
+	} else {
+		parts = append(parts, "TOOL DEFINITIONS:\n(tools disabled)")
+	}
+
 	// Add history context
 	if len(historyContext) > 0 {
 		histText := "HISTORY CONTEXT:\n"
 		for _, entry := range historyContext {
-			histText += fmt.Sprintf("User: %s\nAssistant: %s\n", entry.Prompt, entry.Response)
+			histText += fmt.Sprintf("User: %s\n%sAssistant: %s\n", entry.Prompt, historyToolsNote(entry), entry.Response)
 		}
 		parts = append(parts, histText)
 	}
@@ -135,32 +563,130 @@ func (c *Client) BuildPrompt(prompt string, historyContext []history.Entry) stri
 }
 
 // Generate generates a shell command from a natural language prompt.
-func (c *Client) Generate(ctx context.Context, prompt string, historyContext []history.Entry) (string, error) {
-	// Track prompts for debugging output
-	var promptLog []string
+func (c *Client) Generate(ctx context.Context, prompt string, historyContext []history.Entry) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "gemini.Generate")
+	defer func() { telemetry.End(span, err) }()
 
-	// Add system instruction to log
-	systemInstruction := c.buildSystemInstruction()
-	promptLog = append(promptLog, fmt.Sprintf("SYSTEM INSTRUCTION:\n%s", systemInstruction))
+	gen, err := c.generateEscalating(ctx, prompt, historyContext)
+	return gen.command, err
+}
 
-	// Add history context to log
-	if len(historyContext) > 0 {
-		histText := "HISTORY CONTEXT:\n"
-		for _, entry := range historyContext {
-			histText += fmt.Sprintf("User: %s\nAssistant: %s\n", entry.Prompt, entry.Response)
+// Result is a generated command with the optional extras requested by
+// Config.Why and Config.Undo.
+type Result struct {
+	Command string
+	// Rationale is the one-sentence explanation (Config.Why).
+	Rationale string
+	// Undo is the closest inverse command, or empty when the command has
+	// no meaningful undo (Config.Undo).
+	Undo string
+	// Risk is RiskLow, RiskMedium, or RiskHigh as judged by the model, or
+	// empty if the model didn't say.
+	Risk string
+	// NeedsConfirmation is set when the model thinks the command should be
+	// reviewed before it runs.
+	NeedsConfirmation bool
+	// Clarified is set when the user answered a question from the model
+	// (Config.Clarify), so the command depends on more than the prompt.
+	Clarified bool
+	// Tools are the tool calls made while generating, for the history.
+	Tools []history.ToolCall
+	// Options are independent commands to choose from, set only when the
+	// user asked for several ways to do something; Command is the first.
+	Options []Alternative
+	// QuotingIssue describes a quoting problem the model didn't fix when
+	// asked, such as an unterminated quote, or "" when the check passed.
+	QuotingIssue string
+}
+
+// GenerateResult is like Generate but also returns the rationale and undo
+// hint when the client was created with Config.Why or Config.Undo set.
+func (c *Client) GenerateResult(ctx context.Context, prompt string, historyContext []history.Entry) (_ Result, err error) {
+	ctx, span := telemetry.Start(ctx, "gemini.Generate")
+	defer func() { telemetry.End(span, err) }()
+
+	gen, err := c.generateEscalating(ctx, prompt, historyContext)
+	return Result{
+		Command:           gen.command,
+		Rationale:         gen.rationale,
+		Undo:              gen.undo,
+		Risk:              gen.risk,
+		NeedsConfirmation: gen.needsConfirmation,
+		Clarified:         gen.clarified,
+		Tools:             gen.tools,
+		Options:           gen.options,
+		QuotingIssue:      gen.quotingIssue,
+	}, err
+}
+
+// GenerateCandidates generates n alternative commands using temperature-varied
+// samples run concurrently. Duplicate commands are dropped, so fewer than n may be returned.
+func (c *Client) GenerateCandidates(ctx context.Context, prompt string, historyContext []history.Entry, n int) (_ []string, err error) {
+	ctx, span := telemetry.Start(ctx, "gemini.GenerateCandidates", trace.WithAttributes(attribute.Int("candidates", n)))
+	defer func() { telemetry.End(span, err) }()
+
+	results := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		// Each sample gets its own copy of the model so temperatures don't race
+		model := *c.model
+		model.SetTemperature(c.sampling.candidateTemperature(i))
+		wg.Add(1)
+		go func(i int, model *genai.GenerativeModel) {
+			defer wg.Done()
+			// Only the first sample writes the prompt log to avoid clobbering it
+			gen, err := c.generate(ctx, model, prompt, historyContext, i == 0)
+			results[i], errs[i] = gen.command, err
+		}(i, &model)
+	}
+	wg.Wait()
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for i, result := range results {
+		if errs[i] != nil {
+			c.logger.Warn("candidate failed", "index", i, "error", errs[i])
+			continue
 		}
-		promptLog = append(promptLog, histText)
+		if result == "" || seen[result] {
+			continue
+		}
+		seen[result] = true
+		candidates = append(candidates, result)
 	}
 
-	chat := c.model.StartChat()
+	if len(candidates) == 0 {
+		if errs[0] != nil {
+			return nil, errs[0]
+		}
+		return nil, fmt.Errorf("no candidates generated")
+	}
+	return candidates, nil
+}
+
+// generate runs a single chat against model, writing the prompt log if writeLog is set.
+func (c *Client) generate(ctx context.Context, model *genai.GenerativeModel, prompt string, historyContext []history.Entry, writeLog bool) (generation, error) {
+	// Track prompts for debugging output
+	promptLog := &transcript{}
+
+	// Add system instruction and history context to log
+	promptLog.add(promptRecord{Type: recordSystem, Model: model.Name(), Mode: string(c.mode), Text: c.buildSystemInstruction()})
+	promptLog.addHistory(historyContext)
+
+	chat := model.StartChat()
 
 	// If we have history, add it to the chat
 	if len(historyContext) > 0 {
 		for _, entry := range historyContext {
+			userParts := []genai.Part{genai.Text(entry.Prompt)}
+			if note := historyToolsNote(entry); note != "" {
+				userParts = append(userParts, genai.Text(note))
+			}
 			chat.History = append(chat.History,
 				&genai.Content{
 					Role:  "user",
-					Parts: []genai.Part{genai.Text(entry.Prompt)},
+					Parts: userParts,
 				},
 				&genai.Content{
 					Role:  "model",
@@ -171,23 +697,104 @@ func (c *Client) Generate(ctx context.Context, prompt string, historyContext []h
 	}
 
 	// Add initial user prompt to log
-	promptLog = append(promptLog, fmt.Sprintf("USER PROMPT:\n%s", prompt))
+	promptLog.add(promptRecord{Type: recordUser, Text: prompt, Attachments: c.attachmentNames()})
+
+	// Each generation counts once against the local rate limit
+	if err := c.limiter.Acquire(); err != nil {
+		return generation{}, err
+	}
 
 	// Send the message
-	resp, err := chat.SendMessage(ctx, genai.Text(prompt))
+	c.logger.Debug("sending prompt", "history_entries", len(historyContext), "prompt_bytes", len(prompt))
+	resp, err := c.send(ctx, chat, 0, c.promptParts(prompt)...)
 	if err != nil {
 		// Write prompt log even on error
-		c.writePromptLog(promptLog)
-		return "", fmt.Errorf("failed to generate response: %w", err)
+		if writeLog {
+			c.writePromptLog(promptLog)
+		}
+		var quotaErr *QuotaError
+		if errors.As(err, &quotaErr) {
+			return generation{}, err
+		}
+		return generation{}, fmt.Errorf("failed to generate response: %w", err)
 	}
 
 	// Process the response, handling tool calls
 	result, err := c.processResponse(ctx, chat, resp, promptLog)
-	
+	clarified := false
+	if err == nil && c.clarify != nil {
+		result, clarified, err = c.resolveQuestions(ctx, chat, result, promptLog)
+	}
+	var meta structuredResponse
+	if err == nil && c.structured {
+		if decoded, ok := decodeStructured(result); ok {
+			meta = decoded
+			result = decoded.Command
+		} else {
+			c.logger.Debug("response is not structured, using it as the command")
+		}
+	}
+	// Only single-command output goes through command validation
+	validate := c.mode.producesCommand() && c.alternatives < 2
+	if err == nil && c.mode.stripsMarkdown() && c.alternatives < 2 {
+		result, err = c.cleanResponse(ctx, chat, result, promptLog)
+	}
+	if err == nil && validate {
+		result, err = c.ensureCommand(ctx, chat, result, promptLog)
+	}
+	if err == nil && c.oneLiner && validate {
+		result, err = c.enforceOneLiner(ctx, chat, result, promptLog)
+	}
+	if err == nil && c.posix && validate {
+		result, err = c.enforcePOSIX(ctx, chat, result, promptLog)
+	}
+	quotingIssue := ""
+	if err == nil && validate {
+		result, quotingIssue, err = c.checkQuoting(ctx, chat, result, prompt, promptLog)
+	}
+
 	// Write prompt log
-	c.writePromptLog(promptLog)
-	
-	return result, err
+	if writeLog {
+		c.writePromptLog(promptLog)
+	}
+
+	gen := generation{command: result, risk: meta.Risk, confidence: meta.Confidence, needsConfirmation: meta.NeedsConfirmation, clarified: clarified, quotingIssue: quotingIssue}
+	gen.tools = c.toolCalls(chat.History[2*len(historyContext):])
+	if c.why {
+		gen.rationale = meta.Explanation
+	}
+	if c.undo {
+		gen.undo = meta.Undo
+	}
+	for _, o := range meta.Options {
+		gen.options = append(gen.options, Alternative{Command: o.Command, Tradeoff: o.Note, Risk: o.Risk})
+	}
+	return gen, err
+}
+
+// generation is the post-processed result of a single chat.
+type generation struct {
+	command           string
+	rationale         string
+	undo              string
+	risk              string
+	confidence        string
+	needsConfirmation bool
+	clarified         bool
+	tools             []history.ToolCall
+	options           []Alternative
+	quotingIssue      string
+}
+
+// send sends parts to the chat session inside a tracing span for the given turn.
+func (c *Client) send(ctx context.Context, chat *genai.ChatSession, turn int, parts ...genai.Part) (resp *genai.GenerateContentResponse, err error) {
+	ctx, span := telemetry.Start(ctx, "gemini.turn", trace.WithAttributes(attribute.Int("turn", turn)))
+	defer func() { telemetry.End(span, err) }()
+	resp, err = chat.SendMessage(ctx, parts...)
+	if err == nil {
+		c.recordUsage(resp)
+	}
+	return resp, c.wrapQuotaError(err)
 }
 
 // formatToolArgs formats tool arguments as a function call parameter list.
@@ -195,8 +802,15 @@ func (c *Client) formatToolArgs(args map[string]any) string {
 	if len(args) == 0 {
 		return ""
 	}
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	var parts []string
-	for k, v := range args {
+	for _, k := range keys {
+		v := args[k]
 		var valStr string
 		switch val := v.(type) {
 		case string:
@@ -213,9 +827,14 @@ func (c *Client) formatToolArgs(args map[string]any) string {
 	return strings.Join(parts, ", ")
 }
 
-// formatToolResult formats tool result for verbose output, truncating if too long.
+// formatToolResult formats tool result for log output, truncating if too long.
 func (c *Client) formatToolResult(result string) string {
-	const maxLen = 200
+	return truncateResult(result, 200)
+}
+
+// truncateResult shortens a tool result to about maxLen bytes, breaking at a
+// newline when one is near the limit.
+func truncateResult(result string, maxLen int) string {
 	if len(result) <= maxLen {
 		return result
 	}
@@ -229,7 +848,7 @@ func (c *Client) formatToolResult(result string) string {
 }
 
 // processResponse handles the response, including any tool calls.
-func (c *Client) processResponse(ctx context.Context, chat *genai.ChatSession, resp *genai.GenerateContentResponse, promptLog []string) (string, error) {
+func (c *Client) processResponse(ctx context.Context, chat *genai.ChatSession, resp *genai.GenerateContentResponse, promptLog *transcript) (string, error) {
 	turnNum := 1
 	for {
 		if len(resp.Candidates) == 0 {
@@ -257,27 +876,18 @@ func (c *Client) processResponse(ctx context.Context, chat *genai.ChatSession, r
 		// If there are function calls, execute them and continue
 		if len(functionCalls) > 0 {
 			// Log the function calls
-			funcCallText := fmt.Sprintf("TURN %d - MODEL RESPONSE (FUNCTION CALLS):\n", turnNum)
 			for _, fc := range functionCalls {
-				argsJSON, _ := json.MarshalIndent(fc.Args, "", "  ")
-				funcCallText += fmt.Sprintf("Function: %s\nArgs: %s\n", fc.Name, string(argsJSON))
+				promptLog.add(promptRecord{Type: recordToolCall, Turn: turnNum, Tool: fc.Name, Args: fc.Args})
 			}
-			promptLog = append(promptLog, funcCallText)
 
-			// Verbose output: show that function calls were received
-			if c.verbose {
-				fmt.Fprintf(os.Stderr, "[tool] Received %d function call(s)\n", len(functionCalls))
-			}
+			c.logger.Info("received function calls", "turn", turnNum, "count", len(functionCalls))
 
 			var functionResponses []genai.Part
-			funcResponseText := fmt.Sprintf("TURN %d - TOOL RESPONSES:\n", turnNum)
 			for _, fc := range functionCalls {
 				name, args, err := tools.ParseFunctionCall(fc)
 				if err != nil {
-					if c.verbose {
-						fmt.Fprintf(os.Stderr, "[tool] %s() - Error parsing: %s\n", fc.Name, err.Error())
-					}
-					funcResponseText += fmt.Sprintf("Function: %s - Error: %s\n", fc.Name, err.Error())
+					c.logger.Warn("failed to parse tool call", "tool", fc.Name, "error", err)
+					promptLog.add(promptRecord{Type: recordToolResponse, Turn: turnNum, Tool: fc.Name, Error: err.Error()})
 					functionResponses = append(functionResponses, genai.FunctionResponse{
 						Name:     fc.Name,
 						Response: map[string]any{"error": err.Error()},
@@ -285,41 +895,44 @@ func (c *Client) processResponse(ctx context.Context, chat *genai.ChatSession, r
 					continue
 				}
 
-				// Verbose output: show tool call with arguments
-				if c.verbose {
-					argsStr := c.formatToolArgs(args)
-					fmt.Fprintf(os.Stderr, "[tool] %s(%s)\n", name, argsStr)
-				}
+				c.logger.Info("tool call", "tool", name, "args", c.formatToolArgs(args))
 
-				result, err := c.tools.ExecuteTool(name, args)
-				if err != nil {
-					if c.verbose {
-						fmt.Fprintf(os.Stderr, "[tool] %s -> error: %s\n", name, err.Error())
+				var result string
+				if recorded, ok := c.replay.toolResponse(name, args); ok {
+					// Replays reuse what the tool returned when recorded
+					c.logger.Info("tool call answered from recording", "tool", name)
+					result, err = recorded.Text, nil
+					if recorded.Error != "" {
+						err = errors.New(recorded.Error)
 					}
-					funcResponseText += fmt.Sprintf("Function: %s - Error: %s\n", name, err.Error())
+				} else {
+					_, toolSpan := telemetry.Start(ctx, "tool."+name, trace.WithAttributes(attribute.Int("turn", turnNum)))
+					start := time.Now()
+					result, err = c.tools.ExecuteTool(name, args)
+					telemetry.End(toolSpan, err)
+					c.logToolCall(name, args, start, result, err)
+				}
+				if err != nil {
+					c.logger.Info("tool error", "tool", name, "error", err)
+					promptLog.add(promptRecord{Type: recordToolResponse, Turn: turnNum, Tool: name, Error: err.Error()})
 					functionResponses = append(functionResponses, genai.FunctionResponse{
 						Name:     fc.Name,
 						Response: map[string]any{"error": err.Error()},
 					})
 				} else {
-					// Verbose output: show result (truncated if too long)
-					if c.verbose {
-						resultStr := c.formatToolResult(result)
-						fmt.Fprintf(os.Stderr, "[tool] %s -> %s\n", name, resultStr)
-					}
-					resultJSON, _ := json.MarshalIndent(result, "", "  ")
-					funcResponseText += fmt.Sprintf("Function: %s\nResult: %s\n", name, string(resultJSON))
+					c.logger.Info("tool result", "tool", name, "result", c.formatToolResult(result))
+					promptLog.add(promptRecord{Type: recordToolResponse, Turn: turnNum, Tool: name, Text: result})
 					functionResponses = append(functionResponses, genai.FunctionResponse{
 						Name:     fc.Name,
 						Response: map[string]any{"result": result},
 					})
 				}
 			}
-			promptLog = append(promptLog, funcResponseText)
 
 			// Send function responses back
+			c.logger.Debug("sending function responses", "turn", turnNum, "count", len(functionResponses))
 			var err error
-			resp, err = chat.SendMessage(ctx, functionResponses...)
+			resp, err = c.send(ctx, chat, turnNum, functionResponses...)
 			if err != nil {
 				return "", fmt.Errorf("failed to send function responses: %w", err)
 			}
@@ -330,7 +943,7 @@ func (c *Client) processResponse(ctx context.Context, chat *genai.ChatSession, r
 		// No more function calls, log final response and return
 		if len(textParts) > 0 {
 			finalResponse := strings.TrimSpace(strings.Join(textParts, "\n"))
-			promptLog = append(promptLog, fmt.Sprintf("TURN %d - MODEL RESPONSE (FINAL):\n%s", turnNum, finalResponse))
+			promptLog.add(promptRecord{Type: recordModel, Turn: turnNum, Text: finalResponse})
 		}
 		return strings.TrimSpace(strings.Join(textParts, "\n")), nil
 	}
@@ -340,7 +953,7 @@ func (c *Client) processResponse(ctx context.Context, chat *genai.ChatSession, r
 // Returns a formatted string with platform-appropriate environment variables.
 func (c *Client) collectEnvironment() string {
 	var envVars []string
-	
+
 	// Helper to safely get and format env var
 	getEnv := func(key string) (string, bool) {
 		val := os.Getenv(key)
@@ -349,7 +962,7 @@ func (c *Client) collectEnvironment() string {
 		}
 		return val, true
 	}
-	
+
 	// Helper to sanitize sensitive values
 	sanitize := func(key, val string) string {
 		keyUpper := strings.ToUpper(key)
@@ -361,7 +974,7 @@ func (c *Client) collectEnvironment() string {
 		}
 		return val
 	}
-	
+
 	// Helper to truncate long values (like PATH)
 	truncate := func(val string, maxLen int) string {
 		if len(val) <= maxLen {
@@ -369,7 +982,7 @@ func (c *Client) collectEnvironment() string {
 		}
 		return val[:maxLen] + " (truncated)"
 	}
-	
+
 	// Cross-platform variables
 	if val, ok := getEnv("GX_MODEL"); ok {
 		envVars = append(envVars, fmt.Sprintf("- GX_MODEL: %s", sanitize("GX_MODEL", val)))
@@ -380,7 +993,7 @@ func (c *Client) collectEnvironment() string {
 	if val, ok := getEnv("GX_PROMPT_OUTPUT"); ok {
 		envVars = append(envVars, fmt.Sprintf("- GX_PROMPT_OUTPUT: %s", sanitize("GX_PROMPT_OUTPUT", val)))
 	}
-	
+
 	// Platform-specific variables
 	if runtime.GOOS == "windows" {
 		// Windows-specific
@@ -418,7 +1031,7 @@ func (c *Client) collectEnvironment() string {
 			envVars = append(envVars, fmt.Sprintf("- PWD: %s", sanitize("PWD", val)))
 		}
 	}
-	
+
 	// Common variables (both platforms)
 	if val, ok := getEnv("PATH"); ok {
 		envVars = append(envVars, fmt.Sprintf("- PATH: %s", truncate(sanitize("PATH", val), 300)))
@@ -444,11 +1057,11 @@ func (c *Client) collectEnvironment() string {
 	if val, ok := getEnv("GCP_PROJECT"); ok {
 		envVars = append(envVars, fmt.Sprintf("- GCP_PROJECT: %s", sanitize("GCP_PROJECT", val)))
 	}
-	
+
 	if len(envVars) == 0 {
 		return ""
 	}
-	
+
 	return strings.Join(envVars, "\n")
 }
 
@@ -457,24 +1070,23 @@ func (c *Client) buildToolsDescription() string {
 	if !c.tools.IsEnabled() {
 		return ""
 	}
-	
-	toolDescs := []string{
-		"- pwd: Get current working directory",
-		"- ls(path, recursive): List files and directories",
-		"- stat(path): Get detailed file information",
-		"- cat(path): Read file contents (max 100KB)",
-		"- ps: List running processes",
-		"- uptime: Get system uptime",
+
+	toolDescs := c.tools.Descriptions()
+	for i, desc := range toolDescs {
+		toolDescs[i] = "- " + desc
 	}
-	
 	return strings.Join(toolDescs, "\n")
 }
 
 // buildSystemInstruction creates the system instruction based on shell and platform.
 func (c *Client) buildSystemInstruction() string {
+	if !c.mode.producesCommand() {
+		return c.buildModeInstruction() + c.projectInstruction() + c.languageInstruction()
+	}
+
 	commentSyntax := "#"
 	commentWarning := ""
-	if c.shell == "powershell" || c.shell == "pwsh" {
+	if shell.IsPowerShell(c.shell) {
 		commentSyntax = "#"
 		commentWarning = "CRITICAL: For PowerShell, use # for comments. NEVER use REM (REM is only for CMD)."
 	} else if c.shell == "cmd" {
@@ -482,42 +1094,75 @@ func (c *Client) buildSystemInstruction() string {
 		commentWarning = "For CMD, use REM for comments."
 	}
 
-	verboseInstruction := ""
-	if c.verbose {
-		verboseInstruction = "Include helpful comments explaining what each part of the command does."
+	commentInstruction := ""
+	if c.comments {
+		commentInstruction = "Include helpful comments explaining what each part of the command does."
 	} else {
-		verboseInstruction = "Do not include comments unless absolutely necessary for understanding."
+		commentInstruction = "Do not include comments unless absolutely necessary for understanding."
+	}
+
+	extraRules := ""
+	if c.oneLiner {
+		extraRules = "\n8. Return exactly ONE line. Chain steps with && or ; — no line continuations, no multi-line scripts."
+	}
+	if c.posix {
+		extraRules += fmt.Sprintf("\n%d. Use only POSIX sh: the command must run unchanged in dash and BusyBox ash. No [[ ]], arrays, function keyword, source, $'...', brace expansion, <(...), <<<, &>, ${var,,}, ${var:0:3}, ${var/a/b}, or echo -e (use printf).", 8+strings.Count(extraRules, "\n"))
+	}
+
+	workDirText := ""
+	if c.workDir != "" {
+		workDirText = fmt.Sprintf("\n- Working directory: %s (the command runs here; work relative to this directory)", c.workDir)
 	}
 
 	var warningSection string
 	if commentWarning != "" {
 		warningSection = commentWarning + "\n\n"
 	}
-	
+
 	// Collect environment variables
-	envSection := c.collectEnvironment()
+	// This machine's variables say nothing about another platform
+	envSection := ""
+	if !c.platformOverridden {
+		envSection = c.collectEnvironment()
+	}
 	envText := ""
 	if envSection != "" {
 		envText = "\n\nENVIRONMENT:\n" + envSection
 	}
-	
+
+	envText += c.customizationsText()
+
 	// Build tools description
 	toolsSection := c.buildToolsDescription()
 	toolsText := ""
 	if toolsSection != "" {
 		toolsText = "\n\nAVAILABLE TOOLS:\n" + toolsSection
 	}
-	
+
+	outputRules := `1. Return ONLY the shell command(s) - no explanations, no markdown, no backticks.
+2. Do not wrap output in code blocks or use markdown formatting.`
+	if c.alternatives >= 2 {
+		toolsText += "\n\n" + alternativesInstruction(c.alternatives)
+	} else if c.structured {
+		outputRules = `1. Put ONLY the shell command(s) in the "command" field - no explanations, no markdown, no backticks.
+2. Reply with the JSON object described under OUTPUT FORMAT and nothing else - no code fences.`
+		toolsText += "\n\n" + structuredInstruction(c.undo, c.clarify != nil, c.confidence)
+	}
+
+	workDirText += c.platformNotes()
+	for _, line := range c.infra {
+		workDirText += "\n" + line
+	}
+
 	instruction := fmt.Sprintf(`You are a shell command generator. Your task is to convert natural language requests into executable shell commands.
 
 %sCRITICAL RULES:
-1. Return ONLY the shell command(s) - no explanations, no markdown, no backticks.
-2. Do not wrap output in code blocks or use markdown formatting.
+%s
 3. If you need to add comments, use the appropriate syntax for the shell: %s
 4. %s
 5. The command must be directly executable - copy-paste ready. This is an absolute requirement no matter what.
 6. For multi-line commands, use appropriate line continuation for the shell.
-7. If a task cannot be accomplished with a shell command, explain briefly using shell comments.
+7. If a task cannot be accomplished with a shell command, explain briefly using shell comments.%s
 
 PAY ATTENTION:
 Again, the command must be directly executable - copy-paste ready. This is an absolute requirement no matter what.
@@ -525,9 +1170,104 @@ Again, the command must be directly executable - copy-paste ready. This is an ab
 CONTEXT:
 - Shell: %s
 - Platform: %s
-- Operating System: %s%s%s`, warningSection, commentSyntax, verboseInstruction, c.shell, c.platform, runtime.GOOS, envText, toolsText)
-	
-	return instruction
+- Operating System: %s%s%s%s`, warningSection, outputRules, commentSyntax, commentInstruction, extraRules, c.shellDescription(), c.platform, c.goos, workDirText, envText, toolsText)
+
+	return instruction + c.projectInstruction() + c.languageInstruction()
+}
+
+// customizationsText lists the user's aliases and functions (--aliases)
+// and whether the command will see them.
+func (c *Client) customizationsText() string {
+	if c.customizations.Empty() {
+		return ""
+	}
+	usage := "The command runs in a non-interactive shell where these are NOT defined, so don't rely on them. The user may still paste it into their own shell, so where an alias changes a command's meaning (alias rm='rm -i', alias grep='grep -P'), write the command so it works either way, e.g. with `command rm`."
+	if c.interactiveShell {
+		usage = "The command runs in the user's interactive shell, where these are defined: use them where they fit the request, and mind aliases that change a command's behavior (alias rm='rm -i'); use `command name` to bypass one."
+	}
+	return "\n\nUSER ALIASES AND FUNCTIONS (from the user's shell rc files):\n" + c.customizations.String() + "\n" + usage
+}
+
+// projectInstruction adds the project's own guidance (from .gxrc), which
+// takes precedence over general conventions.
+func (c *Client) projectInstruction() string {
+	if c.instructions == "" {
+		return ""
+	}
+	return "\n\nPROJECT INSTRUCTIONS (from the project's gx config; follow them over general conventions):\n" + c.instructions
+}
+
+// languageInstruction asks for prose in the configured language while
+// keeping everything executable or machine-parsed unchanged.
+func (c *Client) languageInstruction() string {
+	if c.language == "" || strings.HasPrefix(strings.ToLower(c.language), "en") {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+LANGUAGE:
+Write all comments, explanations, summaries, notes, and warnings in the language with code %q. Keep commands, flags, file names, code, JSON field names and values other than explanations, and output markers (%s, %s) exactly as they would be in English.`,
+		c.language, alternativeCommandPrefix, alternativeTradeoffPrefix)
+}
+
+// isWSL reports whether gx runs under WSL, where Windows paths need
+// translating.
+func (c *Client) isWSL() bool {
+	return strings.HasPrefix(c.platform, "wsl")
+}
+
+// platformNotes returns context lines for environments whose paths and
+// tools differ from what the platform name suggests: WSL, and the POSIX
+// shells on Windows (MSYS2, Git Bash, Cygwin). For a --target host they are
+// the profile's lines instead.
+func (c *Client) platformNotes() string {
+	if c.host != "" {
+		return "\n" + c.host
+	}
+	if c.platformOverridden {
+		return ""
+	}
+	if c.isWSL() {
+		return fmt.Sprintf("\n- WSL: Windows drives are mounted under %[1]s<drive> (C:\\ is %[1]sc). Use `wslpath -w` to pass a path to a Windows program (*.exe, called with the .exe suffix, e.g. explorer.exe, clip.exe) and `wslpath -u` for a Windows path the user gives.", shell.WSLMountRoot())
+	}
+	m, ok := shell.DetectMSYS()
+	if !ok {
+		return ""
+	}
+	packages := "There is no package manager; don't suggest apt, dnf, or brew."
+	switch m.Name {
+	case "msys2":
+		packages = "Install packages with pacman (no sudo)."
+	case "cygwin":
+		packages = "Packages are installed with Cygwin's setup program; there is no apt, dnf, or sudo."
+	}
+	return fmt.Sprintf("\n- %s: a POSIX shell on Windows. Use bash syntax and Unix tools (find, grep, sed, awk), not PowerShell or cmd. Drives are under %[2]s<drive> (C:\\ is %[2]sc); convert paths with `cygpath -w` before passing them to Windows programs (*.exe) and `cygpath -u` for a Windows path the user gives. There is no systemd or /proc-based service management. %[3]s", m.Name, m.DriveRoot, packages)
+}
+
+// shellDescription returns the shell name for the prompt context, including
+// the PowerShell major version so the model avoids cmdlets the edition lacks,
+// and the bash, zsh, or fish version with the features it lacks (bash 3.2
+// on macOS has no associative arrays or mapfile).
+func (c *Client) shellDescription() string {
+	if c.posix {
+		return "sh (POSIX only)"
+	}
+	if c.shellOverridden {
+		return c.shell
+	}
+	if v, ok := shell.DetectVersion(c.shell); ok {
+		if constraints := v.Constraints(); constraints != "" {
+			return fmt.Sprintf("%s %s (%s)", c.shell, v.Full, constraints)
+		}
+		return c.shell + " " + v.Full
+	}
+	if !shell.IsPowerShell(c.shell) {
+		return c.shell
+	}
+	if major := shell.DetectPowerShell().Major; major > 0 && c.shell == shell.DetectPowerShell().Executable {
+		return fmt.Sprintf("%s (PowerShell %d)", c.shell, major)
+	}
+	return c.shell
 }
 
 // detectShell detects the current shell.
@@ -539,11 +1279,16 @@ func detectShell() string {
 		return parts[len(parts)-1]
 	}
 
+	// MSYS2, Git Bash, and Cygwin run bash even when SHELL isn't exported
+	if _, ok := shell.DetectMSYS(); ok {
+		return "bash"
+	}
+
 	// Check PSModulePath for PowerShell first (Windows)
 	// This must be checked before ComSpec because ComSpec is often set
 	// even when running PowerShell
 	if os.Getenv("PSModulePath") != "" {
-		return "powershell"
+		return shell.DetectPowerShell().Executable
 	}
 
 	// Check ComSpec for Windows CMD (only if PowerShell not detected)
@@ -555,7 +1300,7 @@ func detectShell() string {
 
 	// Default based on OS
 	if runtime.GOOS == "windows" {
-		return "powershell"
+		return shell.DetectPowerShell().Executable
 	}
 	return "bash"
 }
@@ -565,71 +1310,14 @@ func detectPlatform() string {
 	os := runtime.GOOS
 	arch := runtime.GOARCH
 
-	// Check for WSL
-	if os == "linux" {
-		if data, err := exec.Command("uname", "-r").Output(); err == nil {
-			if strings.Contains(strings.ToLower(string(data)), "microsoft") ||
-				strings.Contains(strings.ToLower(string(data)), "wsl") {
-				return fmt.Sprintf("wsl2/%s", arch)
-			}
-		}
-	}
-
-	return fmt.Sprintf("%s/%s", os, arch)
-}
-
-// writePromptLog writes the prompt log to a file if GX_PROMPT_OUTPUT is set.
-// If GX_PROMPT_OUTPUT is not set, defaults to ~/.gxprompt.
-func (c *Client) writePromptLog(promptLog []string) {
-	outputPath := os.Getenv("GX_PROMPT_OUTPUT")
-	
-	// If not set, default to ~/.gxprompt
-	if outputPath == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return // Silently fail if we can't get home directory
-		}
-		outputPath = filepath.Join(homeDir, ".gxprompt")
-	} else {
-		// Expand ~ if present in the env var value
-		if strings.HasPrefix(outputPath, "~") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return // Silently fail if we can't get home directory
-			}
-			// Handle both ~ and ~/ cases
-			if outputPath == "~" {
-				outputPath = homeDir
-			} else if strings.HasPrefix(outputPath, "~/") {
-				outputPath = filepath.Join(homeDir, strings.TrimPrefix(outputPath, "~/"))
-			} else {
-				outputPath = filepath.Join(homeDir, strings.TrimPrefix(outputPath, "~"))
-			}
-		}
-	}
-
-	// Join all prompts with separator
-	content := strings.Join(promptLog, "\n---\n\n")
-
-	// Write to file (create or overwrite)
-	if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-		// Silently fail - this is a debugging feature
-		_ = err
-	}
-}
-
-// getDefaultProject gets the default GCP project from gcloud config.
-func getDefaultProject() (string, error) {
-	cmd := exec.Command("gcloud", "config", "get-value", "project")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to get default project: %w (ensure gcloud is installed and configured)", err)
+	// MSYS2, Git Bash, and Cygwin run a POSIX shell on Windows
+	if m, ok := shell.DetectMSYS(); ok {
+		return fmt.Sprintf("%s/%s", m.Name, arch)
 	}
 
-	project := strings.TrimSpace(string(output))
-	if project == "" {
-		return "", fmt.Errorf("no default project set (run: gcloud config set project PROJECT_ID)")
+	if shell.IsWSL() {
+		return fmt.Sprintf("wsl2/%s", arch)
 	}
 
-	return project, nil
+	return fmt.Sprintf("%s/%s", os, arch)
 }
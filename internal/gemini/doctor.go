@@ -0,0 +1,108 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"cloud.google.com/go/vertexai/genai"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The helpers in this file expose what NewClient resolves internally so
+// `gx doctor` can check each step on its own.
+
+// FindCredentials resolves application default credentials the way NewClient does.
+func FindCredentials(ctx context.Context) (*google.Credentials, error) {
+	return findCredentials(ctx)
+}
+
+// CredentialsSource describes where application default credentials come from.
+func CredentialsSource() string {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return "GOOGLE_APPLICATION_CREDENTIALS (" + path + ")"
+	}
+	if _, err := os.Stat(adcPath()); err == nil {
+		return "gcloud ADC file (" + adcPath() + ")"
+	}
+	return "metadata server"
+}
+
+// ResolveProject returns the GCP project NewClient would use with creds.
+func ResolveProject(creds *google.Credentials) (string, error) {
+	return resolveProject(creds)
+}
+
+// ResolveEndpoint returns the Vertex AI endpoint (host:port) for a location,
+// honoring GX_ENDPOINT.
+func ResolveEndpoint(location string) string {
+	if endpoint := os.Getenv("GX_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return fmt.Sprintf("%s-aiplatform.googleapis.com:443", ResolveLocation(location))
+}
+
+// DetectShell returns the shell commands are generated for.
+func DetectShell() string {
+	return detectShell()
+}
+
+// DetectPlatform returns the platform reported to the model (e.g. linux/amd64, wsl2/amd64).
+func DetectPlatform() string {
+	return detectPlatform()
+}
+
+// Ping checks that the model is reachable and usable from this project and
+// location by counting the tokens of a tiny prompt, which costs no quota for
+// generation.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.model.CountTokens(ctx, genai.Text("ping"))
+	return c.wrapQuotaError(err)
+}
+
+// Project returns the project the client sends requests to.
+func (c *Client) Project() string {
+	return c.projectID
+}
+
+// PingRemedy suggests a fix for an error returned by Ping.
+func (c *Client) PingRemedy(err error) string {
+	code := codes.Unknown
+	if s, ok := status.FromError(err); ok {
+		code = s.Code()
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusForbidden:
+			code = codes.PermissionDenied
+		case http.StatusUnauthorized:
+			code = codes.Unauthenticated
+		}
+	}
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		code = codes.ResourceExhausted
+	}
+
+	switch code {
+	case codes.NotFound:
+		return fmt.Sprintf("model %s is not available in %s; set GX_LOCATION to a supported region (e.g. %s) or pick another GX_MODEL", c.modelName, c.location, DefaultLocation)
+	case codes.PermissionDenied:
+		return fmt.Sprintf("enable the API with `gcloud services enable aiplatform.googleapis.com --project %s` and make sure your account has roles/aiplatform.user", c.projectID)
+	case codes.Unauthenticated:
+		return "refresh credentials with `gcloud auth application-default login`"
+	case codes.ResourceExhausted:
+		return "quota is exhausted right now; wait a minute or request an increase for project " + c.projectID
+	case codes.DeadlineExceeded, codes.Unavailable:
+		return "the endpoint didn't answer in time; check the network checks above and any proxy settings"
+	}
+	return "re-run with --debug for details"
+}
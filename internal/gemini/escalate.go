@@ -0,0 +1,123 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// DefaultEscalateTo is the model (or alias) retried when the configured
+// model's answer is unusable. Escalation costs an extra request on a pricier
+// model, so it is off unless asked for.
+const DefaultEscalateTo = "off"
+
+// ResolveEscalateTo returns the concrete model to escalate to, falling back
+// to GX_ESCALATE_TO and then DefaultEscalateTo. "off" (or "none") disables
+// escalation and returns "".
+func ResolveEscalateTo(model string) string {
+	if model == "" {
+		model = os.Getenv("GX_ESCALATE_TO")
+	}
+	if model == "" {
+		model = DefaultEscalateTo
+	}
+	switch strings.ToLower(model) {
+	case "off", "none", "0":
+		return ""
+	}
+	return ResolveModel(model)
+}
+
+// validationError marks a response that still failed validation after the
+// corrective re-prompts, as opposed to an API or tool failure.
+type validationError struct {
+	err error
+}
+
+func (e *validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+// IsInvalid reports whether err is an answer that failed validation, as
+// opposed to a failed request.
+func IsInvalid(err error) bool {
+	var invalid *validationError
+	return errors.As(err, &invalid)
+}
+
+// invalidf returns a validationError with a formatted message.
+func invalidf(format string, args ...any) error {
+	return &validationError{err: fmt.Errorf(format, args...)}
+}
+
+// CanEscalate reports whether a stronger model is configured that differs
+// from the one this client uses.
+func (c *Client) CanEscalate() bool {
+	return c.escalateTo != "" && c.escalateTo != c.modelName
+}
+
+// escalationModel returns a model for c.escalateTo with the same sampling,
+// tools, and system instruction as the client's model.
+func (c *Client) escalationModel() *genai.GenerativeModel {
+	model := c.client.GenerativeModel(c.escalateTo)
+	model.GenerationConfig = c.model.GenerationConfig
+	model.SafetySettings = c.model.SafetySettings
+	model.Tools = c.model.Tools
+	model.ToolConfig = c.model.ToolConfig
+	model.SystemInstruction = c.model.SystemInstruction
+	return model
+}
+
+// generateEscalating runs generate on the client's model and retries once on
+// the escalation model when the answer failed validation or the model rated
+// its own confidence low. An answer that is only a comment is the model
+// declining (see rule 7), which a stronger model won't change, so it is
+// returned as is. The stronger model's answer is returned whether or not it
+// fares better, unless it fails outright.
+func (c *Client) generateEscalating(ctx context.Context, prompt string, historyContext []history.Entry) (generation, error) {
+	gen, err := c.generate(ctx, c.model, prompt, historyContext, true)
+	if !c.CanEscalate() {
+		return gen, err
+	}
+
+	var invalid *validationError
+	reason := ""
+	switch {
+	case errors.As(err, &invalid):
+		reason = err.Error()
+	case err == nil && gen.confidence == ConfidenceLow:
+		reason = "model reported low confidence"
+	default:
+		return gen, err
+	}
+
+	c.logger.Info("escalating to a stronger model", "from", c.modelName, "to", c.escalateTo, "reason", reason)
+	escalated, escalatedErr := c.generate(ctx, c.escalationModel(), prompt, historyContext, true)
+	if escalatedErr != nil {
+		c.logger.Warn("escalation failed", "model", c.escalateTo, "error", escalatedErr)
+		return gen, err
+	}
+	return escalated, nil
+}
+
+// GenerateEscalated generates directly on the escalation model, for callers
+// that validate output themselves (e.g. a syntax check) and want one last
+// attempt on a stronger model. It returns an error if CanEscalate is false.
+func (c *Client) GenerateEscalated(ctx context.Context, prompt string, historyContext []history.Entry) (string, error) {
+	if !c.CanEscalate() {
+		return "", fmt.Errorf("no stronger model configured (GX_ESCALATE_TO)")
+	}
+	c.logger.Info("escalating to a stronger model", "from", c.modelName, "to", c.escalateTo)
+	gen, err := c.generate(ctx, c.escalationModel(), prompt, historyContext, true)
+	return gen.command, err
+}
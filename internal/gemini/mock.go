@@ -0,0 +1,227 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Providers selectable with GX_PROVIDER.
+const (
+	// ProviderVertex sends requests to Vertex AI (the default).
+	ProviderVertex = "vertex"
+	// ProviderMock answers with canned commands and tool calls, without
+	// network access or credentials, for demos and integration tests.
+	ProviderMock = "mock"
+)
+
+// mockProject is the project reported by the mock provider.
+const mockProject = "gx-mock"
+
+// ResolveProvider returns the provider to use, falling back to GX_PROVIDER
+// and then ProviderVertex.
+func ResolveProvider(provider string) (string, error) {
+	if provider == "" {
+		provider = os.Getenv("GX_PROVIDER")
+	}
+	switch strings.ToLower(provider) {
+	case "", ProviderVertex:
+		return ProviderVertex, nil
+	case ProviderMock:
+		return ProviderMock, nil
+	}
+	return "", fmt.Errorf("unknown provider %q (expected vertex or mock)", provider)
+}
+
+// mockAnswer is a canned reply: the model first calls tool (when tools are
+// enabled), then answers with command.
+type mockAnswer struct {
+	keywords    []string
+	tool        string
+	args        map[string]any
+	command     string
+	explanation string
+	risk        string
+	undo        string
+	// options are offered when the prompt asks for several ways
+	options []structuredOption
+}
+
+// mockAnswers are matched in order against the lowercased prompt; the last
+// one is the fallback.
+var mockAnswers = []mockAnswer{
+	{keywords: []string{"ways"},
+		command: "wc -l notes.txt", explanation: "Counts the lines in notes.txt.", risk: RiskLow,
+		options: []structuredOption{
+			{Command: "wc -l notes.txt", Note: "Standard and fastest.", Risk: RiskLow},
+			{Command: "grep -c '' notes.txt", Note: "Also counts a last line without a newline.", Risk: RiskLow},
+			{Command: "awk 'END { print NR }' notes.txt", Note: "Easy to extend with conditions.", Risk: RiskLow},
+		}},
+	{keywords: []string{"port", "listen"}, tool: "lsof", args: map[string]any{"port": float64(8080)},
+		command: "lsof -nP -iTCP:8080 -sTCP:LISTEN", explanation: "Shows the process listening on TCP port 8080.", risk: RiskLow},
+	{keywords: []string{"disk", "space", "full"}, tool: "mounts",
+		command: "df -h", explanation: "Shows free and used space on each mounted filesystem.", risk: RiskLow},
+	{keywords: []string{"memory", "process", "cpu"}, tool: "ps", args: map[string]any{"sort_by": "memory", "limit": float64(10)},
+		command: "ps aux --sort=-%mem | head -n 11", explanation: "Lists the ten processes using the most memory.", risk: RiskLow},
+	{keywords: []string{"log", "delete", "clean"}, tool: "ls", args: map[string]any{"path": ".", "pattern": "*.log"},
+		command: "find . -name '*.log' -mtime +7 -delete", explanation: "Deletes .log files under the current directory older than 7 days.", risk: RiskHigh},
+	{keywords: []string{"rename", "move"}, tool: "ls", args: map[string]any{"path": "."},
+		command: "mv notes.txt notes.md", explanation: "Renames notes.txt to notes.md.", risk: RiskMedium, undo: "mv notes.md notes.txt"},
+	{keywords: []string{"git", "commit", "branch"}, tool: "pwd",
+		command: "git status --short --branch", explanation: "Shows the current branch and changed files.", risk: RiskLow},
+	{keywords: []string{"find", "search", "grep"}, tool: "pwd",
+		command: "grep -rn 'TODO' .", explanation: "Searches files under the current directory for TODO.", risk: RiskLow},
+	{tool: "ls", args: map[string]any{"path": "."},
+		command: "ls -la", explanation: "Lists the files in the current directory with details.", risk: RiskLow},
+}
+
+// matchMockAnswer picks the canned reply for a prompt.
+func matchMockAnswer(prompt string) mockAnswer {
+	prompt = strings.ToLower(prompt)
+	for _, a := range mockAnswers {
+		for _, k := range a.keywords {
+			if strings.Contains(prompt, k) {
+				return a
+			}
+		}
+	}
+	return mockAnswers[len(mockAnswers)-1]
+}
+
+// mockRequest is the part of a REST generateContent request the mock reads.
+type mockRequest struct {
+	Contents []struct {
+		Role  string `json:"role"`
+		Parts []struct {
+			Text             string          `json:"text"`
+			FunctionCall     json.RawMessage `json:"functionCall"`
+			FunctionResponse json.RawMessage `json:"functionResponse"`
+		} `json:"parts"`
+	} `json:"contents"`
+	Tools []json.RawMessage `json:"tools"`
+}
+
+// mockTransport answers generateContent requests locally. It goes through
+// the REST client so everything past the HTTP layer (tool execution,
+// validation, history) runs as it would against Vertex AI.
+type mockTransport struct {
+	mode Mode
+	// alternatives is Config.Alternatives, which expects COMMAND/TRADEOFF pairs
+	alternatives int
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !strings.HasSuffix(req.URL.Path, ":generateContent") {
+		return nil, fmt.Errorf("mock provider does not support %s", req.URL.Path)
+	}
+	var r mockRequest
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("mock provider: invalid request: %w", err)
+	}
+
+	// The prompt is the latest user text; after it, tool responses or model
+	// replies show how far the conversation has got
+	var prompt string
+	answeredTool, replied := false, false
+	for _, c := range r.Contents {
+		for i, p := range c.Parts {
+			switch {
+			case len(p.FunctionResponse) > 0:
+				answeredTool = true
+			case c.Role == "user" && p.Text != "" && i == 0:
+				// Later parts label attachments
+				prompt, answeredTool = p.Text, false
+			case c.Role == "model" && p.Text != "":
+				replied = true
+			}
+		}
+	}
+	answer := matchMockAnswer(prompt)
+
+	var part map[string]any
+	if len(r.Tools) > 0 && !answeredTool && answer.tool != "" && t.mode.producesCommand() {
+		args := answer.args
+		if args == nil {
+			args = map[string]any{}
+		}
+		part = map[string]any{"functionCall": map[string]any{"name": answer.tool, "args": args}}
+	} else {
+		part = map[string]any{"text": t.reply(answer, replied)}
+	}
+
+	resp, err := json.Marshal(map[string]any{
+		"candidates": []any{map[string]any{
+			"content":      map[string]any{"role": "model", "parts": []any{part}},
+			"finishReason": 1, // STOP
+		}},
+		"usageMetadata": map[string]any{
+			"promptTokenCount":     len(body) / 4,
+			"candidatesTokenCount": 20,
+			"totalTokenCount":      len(body)/4 + 20,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json; charset=UTF-8"}},
+		Body:          io.NopCloser(bytes.NewReader(resp)),
+		ContentLength: int64(len(resp)),
+		Request:       req,
+	}, nil
+}
+
+// reply renders the final answer in the format the client's mode expects.
+// replied is set when the model already answered earlier in the chat, which
+// ends an agent session.
+func (t *mockTransport) reply(a mockAnswer, replied bool) string {
+	var v any
+	switch t.mode {
+	case ModeCommand:
+		if t.alternatives >= 2 {
+			return fmt.Sprintf("%s %s\n%s %s\n\n%s echo %q\n%s Prints the command instead of running it (mock provider).",
+				alternativeCommandPrefix, a.command, alternativeTradeoffPrefix, a.explanation,
+				alternativeCommandPrefix, a.command, alternativeTradeoffPrefix)
+		}
+		v = structuredResponse{Command: a.command, Explanation: a.explanation, Risk: a.risk, NeedsConfirmation: a.risk == RiskHigh, Undo: a.undo, Options: a.options}
+	case ModeAgent:
+		if replied {
+			v = AgentStep{Plan: "1. (done)", Risk: RiskLow, Done: true, Summary: "Mock session finished: " + a.explanation}
+		} else {
+			v = AgentStep{Plan: "1. " + a.command, Command: a.command, Explanation: a.explanation, Risk: a.risk}
+		}
+	case ModePlan:
+		v = Plan{Summary: a.explanation, Steps: []PlanStep{
+			{Command: "pwd", Explanation: "Shows the current directory.", Risk: RiskLow},
+			{Command: a.command, Explanation: a.explanation, Risk: a.risk},
+		}}
+	case ModeCron:
+		return "0 7 * * 1-5 " + a.command
+	case ModeBatchScript:
+		return fmt.Sprintf("@echo off\nREM Mock provider output for batch mode (GX_PROVIDER=mock)\n%s\nexit /b %%ERRORLEVEL%%", a.command)
+	case ModeExplain:
+		return "This is a canned explanation from the mock provider (GX_PROVIDER=mock); set GX_PROVIDER=vertex for a real diagnosis."
+	default:
+		return fmt.Sprintf("# Mock provider output for %s mode (GX_PROVIDER=mock)\n%s", t.mode, a.command)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,114 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ModelInfo describes a Gemini model published in Vertex AI.
+type ModelInfo struct {
+	// ID is the model name passed to GX_MODEL (e.g. "gemini-2.5-flash").
+	ID string
+	// LaunchStage is GA, PUBLIC_PREVIEW, EXPERIMENTAL, ... as reported by Vertex.
+	LaunchStage string
+}
+
+// publisherModelsPage is one page of the v1beta1 publisher models list.
+type publisherModelsPage struct {
+	PublisherModels []struct {
+		Name        string `json:"name"`
+		LaunchStage string `json:"launchStage"`
+	} `json:"publisherModels"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// ListModels lists the Gemini models Google publishes in the Model Garden
+// for the configured location, sorted by ID.
+func ListModels(ctx context.Context, location string) ([]ModelInfo, error) {
+	creds, err := findCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	project, err := resolveProject(creds)
+	if err != nil {
+		return nil, fmt.Errorf("no project ID specified and failed to get default: %w", err)
+	}
+
+	httpClient := oauth2.NewClient(ctx, creds.TokenSource)
+	base := fmt.Sprintf("https://%s/v1beta1/publishers/google/models", strings.TrimSuffix(ResolveEndpoint(location), ":443"))
+
+	var models []ModelInfo
+	pageToken := ""
+	for {
+		query := url.Values{"pageSize": {"100"}, "listAllVersions": {"false"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		// Bill the listing to the same project generation uses
+		req.Header.Set("x-goog-user-project", project)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list models: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read model list: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to list models: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		}
+
+		var page publisherModelsPage
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse model list: %w", err)
+		}
+		for _, m := range page.PublisherModels {
+			id := m.Name[strings.LastIndex(m.Name, "/")+1:]
+			if strings.HasPrefix(id, "gemini") {
+				models = append(models, ModelInfo{ID: id, LaunchStage: m.LaunchStage})
+			}
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// modelHints gives relative price and speed by model family, most specific first.
+var modelHints = []struct {
+	family string
+	hint   string
+}{
+	{"flash-lite", "$     fastest, cheapest; simple commands"},
+	{"flash", "$$    fast; good default for most prompts"},
+	{"pro", "$$$$  slower; best for tricky multi-step commands"},
+}
+
+// ModelHint returns a relative pricing/speed hint for a model ID, or "".
+func ModelHint(id string) string {
+	for _, h := range modelHints {
+		if strings.Contains(id, "-"+h.family) {
+			return h.hint
+		}
+	}
+	return ""
+}
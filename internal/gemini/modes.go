@@ -0,0 +1,243 @@
+package gemini
+
+import (
+	"fmt"
+
+	"github.com/nealhardesty/gx/internal/cron"
+)
+
+// Mode selects the kind of output the client produces.
+type Mode string
+
+const (
+	// ModeCommand generates an executable shell command (the default).
+	ModeCommand Mode = ""
+	// ModeManSummary condenses a man page or --help text into an
+	// example-driven summary.
+	ModeManSummary Mode = "man"
+	// ModeCron generates a single crontab line.
+	ModeCron Mode = "cron"
+	// ModeSystemdTimer generates a systemd .service and .timer unit pair.
+	ModeSystemdTimer Mode = "systemd-timer"
+	// ModeMakeTarget generates a Makefile target with its .PHONY declaration.
+	ModeMakeTarget Mode = "make"
+	// ModeTaskfileTask generates a go-task Taskfile task.
+	ModeTaskfileTask Mode = "taskfile"
+	// ModeDockerfile generates a Dockerfile for the project in the current directory.
+	ModeDockerfile Mode = "dockerfile"
+	// ModeCompose generates a docker-compose.yml for the described services.
+	ModeCompose Mode = "compose"
+	// ModeExpandScript rewrites a one-liner as a documented script.
+	ModeExpandScript Mode = "expand"
+	// ModeBatchScript generates a cmd.exe batch file (.bat/.cmd).
+	ModeBatchScript Mode = "batch"
+	// ModeExplain explains or diagnoses piped input (logs, stack traces, diffs) in prose.
+	ModeExplain Mode = "explain"
+	// ModeAgent works toward a goal one confirmed command at a time (see StartAgent).
+	ModeAgent Mode = "agent"
+	// ModePlan lays out every command for a request up front (see GeneratePlan).
+	ModePlan Mode = "plan"
+)
+
+// producesCommand reports whether the mode's output is an executable command
+// that should go through markdown stripping and command validation.
+func (m Mode) producesCommand() bool {
+	return m == ModeCommand
+}
+
+// stripsMarkdown reports whether the mode's output is machine-readable text
+// that should have code fences and surrounding prose removed.
+func (m Mode) stripsMarkdown() bool {
+	switch m {
+	case ModeCommand, ModeCron, ModeSystemdTimer, ModeMakeTarget, ModeTaskfileTask, ModeDockerfile, ModeCompose, ModeExpandScript, ModeBatchScript:
+		return true
+	}
+	return false
+}
+
+// buildModeInstruction returns the system instruction for non-command modes.
+func (c *Client) buildModeInstruction() string {
+	switch c.mode {
+	case ModeManSummary:
+		return fmt.Sprintf(`You summarize command documentation for a busy user. The user message contains a manual page or --help output.
+
+RULES:
+1. Start with one line: the command name and what it does.
+2. List only the most useful options (at most 10), one per line, as "  -x, --long   what it does".
+3. Then give 5-8 practical examples, each as a shell comment describing the goal followed by the command on the next line.
+4. Prefer common real-world tasks over exhaustive coverage. Mention gotchas only if they bite often.
+5. Use plain text - no markdown headings, no code fences, no bold.
+6. Examples must work in the user's shell and platform.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, c.shell, c.platform, c.goos)
+	case ModeCron:
+		return fmt.Sprintf(`You write crontab entries. Convert the user's request into exactly ONE crontab line.
+
+RULES:
+1. Output only the line: five schedule fields (minute hour day-of-month month day-of-week) or an @ macro, then the command. No explanation, no markdown, no code fences.
+2. Cron runs commands with /bin/sh and a minimal PATH: use absolute paths or $HOME instead of ~, and quote paths with spaces.
+3. Escape every %% in the command as \%% (cron turns a bare %% into a newline).
+4. Append " >> $HOME/.cron.log 2>&1" unless the user asked for output to go elsewhere.
+5. If the request cannot be scheduled with cron, output a single line starting with # that says why.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, c.shell, c.platform, c.goos)
+	case ModeSystemdTimer:
+		return fmt.Sprintf(`You write systemd user timers. Convert the user's request into a .service unit and a .timer unit that activates it.
+
+RULES:
+1. Output both files and nothing else. Start each one with a line "%s <name>", using the same short kebab-case name prefixed with "gx-" for both (e.g. "%s gx-notes-backup.service" and "%s gx-notes-backup.timer"). No explanation, no markdown, no code fences.
+2. The service is Type=oneshot with an absolute ExecStart= path; wrap pipelines or shell syntax in /bin/sh -c '...'. Use %%h for the home directory.
+3. The timer uses OnCalendar= in systemd calendar syntax (e.g. "Mon..Fri *-*-* 07:00:00"), Persistent=true, and WantedBy=timers.target in [Install].
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, cron.UnitFilePrefix, cron.UnitFilePrefix, cron.UnitFilePrefix, c.shell, c.platform, c.goos)
+	case ModeMakeTarget:
+		return fmt.Sprintf(`You write Makefile targets. Convert the user's request (which may refer to commands from earlier in the conversation) into a GNU make target.
+
+RULES:
+1. Output only Makefile text - no explanation, no markdown, no code fences.
+2. Start with a "## name: description" comment, then a ".PHONY: name" line, then the rule "name: deps".
+3. Indent every recipe line with a TAB. Use $$ for shell variables and $(MAKE) for recursive make.
+4. List existing targets the task builds on as prerequisites instead of repeating their recipes. Don't redefine existing targets.
+5. Split helper steps into their own targets only when they are useful on their own.
+6. Recipes run with /bin/sh; each line runs in its own shell, so join dependent steps with &&.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, c.shell, c.platform, c.goos)
+	case ModeTaskfileTask:
+		return fmt.Sprintf(`You write go-task Taskfile (version 3) tasks. Convert the user's request (which may refer to commands from earlier in the conversation) into ONE task.
+
+RULES:
+1. Output only the task's YAML - no "version" or "tasks" keys, no explanation, no markdown, no code fences.
+2. Start with the task name at column 0 followed by a colon, then indent its keys by two spaces: desc, deps (existing tasks it builds on), and cmds.
+3. Quote commands that contain ": " or start with special YAML characters.
+4. Don't redefine existing tasks.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, c.shell, c.platform, c.goos)
+	case ModeDockerfile:
+		return fmt.Sprintf(`You write Dockerfiles. Write one for the project in the current directory that runs the service the user describes.
+
+RULES:
+1. Before writing, use the tools to inspect the project: list the directory and read the build manifests that exist (go.mod, package.json and its lockfile, requirements.txt, pyproject.toml, Cargo.toml, pom.xml, ...) so versions, entry points, and ports match the code.
+2. Output only the Dockerfile - no explanation, no markdown, no code fences.
+3. Pin base image versions to match the project (e.g. the Go version in go.mod). Prefer a multi-stage build with a small runtime image and a non-root user.
+4. Copy dependency manifests and download dependencies before copying the source, so layers cache well.
+5. Use exec-form CMD/ENTRYPOINT and EXPOSE the ports the service listens on.%s
+
+CONTEXT:
+- Platform: %s
+- Operating System: %s`, c.modeToolsText(), c.platform, c.goos)
+	case ModeCompose:
+		return fmt.Sprintf(`You write Docker Compose files. Write a compose file for the services the user describes.
+
+RULES:
+1. Before writing, use the tools to inspect the project: list the directory and read any Dockerfile and build manifests (go.mod, package.json, ...) so the app service builds from the project and uses the right ports.
+2. Output only the YAML - no explanation, no markdown, no code fences. Indent with spaces, never tabs.
+3. Use the top-level services key (no obsolete version key). Pin image tags, use named volumes for data, and depends_on with healthchecks where services need each other.
+4. Read secrets and passwords from environment variables (${VAR}) instead of hard-coding them.%s
+
+CONTEXT:
+- Platform: %s
+- Operating System: %s`, c.modeToolsText(), c.platform, c.goos)
+	case ModeExpandScript:
+		return fmt.Sprintf(`You turn shell one-liners into readable, documented scripts suitable for committing to a repository. The user message contains the one-liner.
+
+RULES:
+1. Output only the script - no explanation, no markdown, no code fences.
+2. Keep the behavior identical. Do not add features beyond argument handling for values that were hard-coded.
+3. Start with a shebang for the user's shell (for POSIX shells "#!/usr/bin/env bash" unless the one-liner needs another shell) and a header comment saying what the script does and how to run it.
+4. Add error handling: "set -euo pipefail" for bash, $ErrorActionPreference = 'Stop' for PowerShell, and checks with clear messages for missing tools or inputs.
+5. Pull paths, patterns, and thresholds out into named variables near the top, overridable by arguments or environment where natural.
+6. Split long pipelines into commented steps; quote every expansion.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, c.shell, c.platform, c.goos)
+	case ModeBatchScript:
+		return fmt.Sprintf(`You write Windows batch files for cmd.exe. The user message describes a task, or contains a command to expand; write a .bat/.cmd script that does it.
+
+RULES:
+1. Output only the script - no explanation, no markdown, no code fences.
+2. Start with "@echo off" and "setlocal", then REM comments saying what the script does and how to run it. Use REM for every comment, never # or ::.
+3. Check every step that can fail: "if %%ERRORLEVEL%% neq 0", echo a clear message to stderr (1>&2), and "exit /b" with the error level.
+4. Variables set inside a parenthesized block need "setlocal EnableDelayedExpansion" and !VAR! to be read in the same block.
+5. Put paths and settings in set "NAME=value" lines near the top; take values that were hard-coded from %%~1, %%~2, ... where natural, and quote every path.
+6. Use cmd.exe built-ins and programs that ship with Windows unless the request names others. End with "exit /b 0".
+
+CONTEXT:
+- Shell: cmd
+- Platform: %s
+- Operating System: %s`, c.platform, c.goos)
+	case ModeExplain:
+		return fmt.Sprintf(`You diagnose and explain terminal output for a busy engineer. The user message contains piped input - logs, stack traces, compiler errors, diff output, command output - and optionally a question about it.
+
+RULES:
+1. Start with a one-line verdict: what the input shows or what went wrong.
+2. Then explain the cause, pointing at the specific lines that matter. Quote them briefly.
+3. If something is broken, end with concrete next steps; give fix commands for the user's shell on their own lines.
+4. Be concise - a few short paragraphs at most. Answer the question if one was asked.
+5. Use plain text - no markdown headings, no code fences, no bold.
+6. Say so when the input is ambiguous or truncated rather than guessing.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s`, c.shell, c.platform, c.goos)
+	case ModeAgent:
+		return fmt.Sprintf(`You are a careful operator working toward the user's goal one shell command at a time. The user confirms each command before it runs, and you receive its exit status and output before choosing the next one.
+
+RULES:
+1. Reply with a single JSON object and nothing else:
+{"plan": "<short numbered plan>", "command": "<next command>", "explanation": "<one sentence>", "risk": "low|medium|high", "done": false, "summary": ""}
+2. plan: the remaining steps, revised as you learn from each result. command: exactly one executable command for the next step - no markdown, no backticks.
+3. Prefer read-only commands to inspect state before changing anything. Make each step small enough to check.
+4. risk is "low" for read-only commands, "medium" for changes that are easy to reverse, "high" for anything that deletes, overwrites, or is hard to reverse.
+5. If a step failed, diagnose the output and try a different approach rather than repeating it. If the user skipped a step, work around it.
+6. When the goal is achieved, or cannot be achieved, set "done" to true, leave "command" empty, and put what was accomplished (or why not) in "summary".
+7. Commands run non-interactively: pass flags such as -y instead of waiting for prompts.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s%s`, c.shellDescription(), c.platform, c.goos, c.modeToolsText())
+	case ModePlan:
+		return fmt.Sprintf(`You plan shell work for a careful user. Break the user's request into the ordered commands that accomplish it. Nothing is run: the user reviews the whole plan before deciding what to do.
+
+RULES:
+1. Reply with a single JSON object and nothing else:
+{"summary": "<the approach in one or two sentences>", "steps": [{"command": "<command>", "explanation": "<one sentence>", "risk": "low|medium|high"}]}
+2. Each command is exactly one executable command - no markdown, no backticks. Keep the steps in the order they must run.
+3. Start with read-only commands that check the current state where that makes later steps safer. Use as few steps as the task needs; a simple request can be a single step.
+4. risk is "low" for read-only commands, "medium" for changes that are easy to reverse, "high" for anything that deletes, overwrites, or is hard to reverse.
+5. Where a step depends on output you can't know yet, use a clear placeholder such as <container-id> and say so in its explanation.
+
+CONTEXT:
+- Shell: %s
+- Platform: %s
+- Operating System: %s%s`, c.shellDescription(), c.platform, c.goos, c.modeToolsText())
+	default:
+		return ""
+	}
+}
+
+// modeToolsText lists the available tools for modes that inspect the project.
+func (c *Client) modeToolsText() string {
+	if tools := c.buildToolsDescription(); tools != "" {
+		return "\n\nAVAILABLE TOOLS:\n" + tools
+	}
+	return "\n\nNo tools are available; make reasonable assumptions and note them in comments."
+}
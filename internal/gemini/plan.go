@@ -0,0 +1,96 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+	"github.com/nealhardesty/gx/internal/history"
+	"github.com/nealhardesty/gx/internal/telemetry"
+)
+
+// PlanStep is one command of a plan.
+type PlanStep struct {
+	Command string `json:"command"`
+	// Explanation says what the step does in one sentence.
+	Explanation string `json:"explanation"`
+	// Risk is RiskLow, RiskMedium, or RiskHigh.
+	Risk string `json:"risk"`
+}
+
+// Plan is the model's whole approach to a request, returned in ModePlan.
+type Plan struct {
+	// Summary describes the approach in one or two sentences.
+	Summary string     `json:"summary"`
+	Steps   []PlanStep `json:"steps"`
+}
+
+// planSchema is the response schema for Plan.
+func planSchema() *genai.Schema {
+	text := func(description string) *genai.Schema {
+		return &genai.Schema{Type: genai.TypeString, Description: description}
+	}
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"summary": text("The overall approach in one or two sentences."),
+			"steps": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"command":     text("The command for this step."),
+						"explanation": text("One sentence saying what the step does."),
+						"risk":        {Type: genai.TypeString, Enum: []string{RiskLow, RiskMedium, RiskHigh}},
+					},
+					Required: []string{"command", "explanation", "risk"},
+				},
+			},
+		},
+		Required: []string{"summary", "steps"},
+	}
+}
+
+// GeneratePlan returns the ordered commands that accomplish the request,
+// without running any of them. The client must have been created with
+// Config.Mode set to ModePlan.
+func (c *Client) GeneratePlan(ctx context.Context, prompt string, historyContext []history.Entry) (_ Plan, err error) {
+	ctx, span := telemetry.Start(ctx, "gemini.GeneratePlan")
+	defer func() { telemetry.End(span, err) }()
+
+	if c.mode != ModePlan {
+		return Plan{}, fmt.Errorf("plan mode not enabled for this client")
+	}
+	response, err := c.Generate(ctx, prompt, historyContext)
+	if err != nil {
+		return Plan{}, err
+	}
+	plan, ok := decodePlan(response)
+	if !ok {
+		return Plan{}, fmt.Errorf("model did not return a plan")
+	}
+	return plan, nil
+}
+
+// decodePlan parses a plan reply, tolerating code fences. Steps without a
+// command are dropped; it reports false if none are left.
+func decodePlan(response string) (Plan, bool) {
+	var plan Plan
+	if !decodeJSONObject(response, &plan) {
+		return Plan{}, false
+	}
+	steps := plan.Steps[:0]
+	for _, step := range plan.Steps {
+		step.Command, _ = stripMarkdown(step.Command)
+		if step.Command == "" {
+			continue
+		}
+		step.Explanation = strings.TrimSpace(step.Explanation)
+		step.Risk = strings.ToLower(strings.TrimSpace(step.Risk))
+		steps = append(steps, step)
+	}
+	plan.Summary = strings.TrimSpace(plan.Summary)
+	plan.Steps = steps
+	return plan, len(steps) > 0
+}
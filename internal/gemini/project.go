@@ -0,0 +1,218 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+	"google.golang.org/api/transport"
+)
+
+const (
+	// DefaultStateFile is the default path for the cached project state file.
+	DefaultStateFile = ".gxstate"
+	// projectCacheTTL bounds how long a cached project is trusted even if
+	// nothing on disk appears to have changed.
+	projectCacheTTL = 24 * time.Hour
+	// cloudPlatformScope is the OAuth scope required for Vertex AI.
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// projectState is the cached result of resolving the default GCP project.
+// The fingerprint captures the gcloud configuration and ADC files so that
+// `gcloud config set project` or a new login invalidates the cache.
+type projectState struct {
+	Project     string    `json:"project"`
+	Fingerprint string    `json:"fingerprint"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+}
+
+// findCredentials resolves application default credentials natively
+// (GOOGLE_APPLICATION_CREDENTIALS, the gcloud ADC file, or the metadata server)
+// without requiring the gcloud CLI.
+func findCredentials(ctx context.Context) (*google.Credentials, error) {
+	creds, err := transport.Creds(ctx, option.WithScopes(cloudPlatformScope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find application default credentials: %w (run: gcloud auth application-default login, or set GOOGLE_APPLICATION_CREDENTIALS)", err)
+	}
+	return creds, nil
+}
+
+// credentialsProject extracts a project ID from resolved credentials.
+// Service account keys and the metadata server carry a project ID directly;
+// user credentials from `gcloud auth application-default login` only carry a quota project.
+func credentialsProject(creds *google.Credentials) string {
+	if creds == nil {
+		return ""
+	}
+	if creds.ProjectID != "" {
+		return creds.ProjectID
+	}
+	var f struct {
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if len(creds.JSON) > 0 && json.Unmarshal(creds.JSON, &f) == nil {
+		return f.QuotaProjectID
+	}
+	return ""
+}
+
+// resolveProject returns the GCP project to use.
+// GOOGLE_CLOUD_PROJECT takes precedence, then the project carried by the
+// credentials, then the cached project in ~/.gxstate, and finally
+// `gcloud config get-value project` if gcloud is installed (which refreshes the cache).
+func resolveProject(creds *google.Credentials) (string, error) {
+	if project := os.Getenv("GOOGLE_CLOUD_PROJECT"); project != "" {
+		return project, nil
+	}
+
+	if project := credentialsProject(creds); project != "" {
+		return project, nil
+	}
+
+	statePath, err := stateFilePath()
+	if err != nil {
+		return getDefaultProject()
+	}
+
+	fingerprint := gcloudFingerprint()
+	if state, ok := loadProjectState(statePath); ok {
+		if state.Fingerprint == fingerprint && time.Since(state.ResolvedAt) < projectCacheTTL {
+			return state.Project, nil
+		}
+	}
+
+	project, err := getDefaultProject()
+	if err != nil {
+		return "", err
+	}
+
+	// Failing to cache is non-fatal; we'll just shell out again next time
+	_ = saveProjectState(statePath, projectState{
+		Project:     project,
+		Fingerprint: fingerprint,
+		ResolvedAt:  time.Now(),
+	})
+
+	return project, nil
+}
+
+// stateFilePath returns the path to ~/.gxstate.
+func stateFilePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, DefaultStateFile), nil
+}
+
+// loadProjectState reads the cached project state, if any.
+func loadProjectState(path string) (projectState, bool) {
+	var state projectState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, false
+	}
+	if err := json.Unmarshal(data, &state); err != nil || state.Project == "" {
+		return state, false
+	}
+	return state, true
+}
+
+// saveProjectState writes the cached project state.
+func saveProjectState(path string, state projectState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// gcloudConfigDir returns the gcloud configuration directory.
+func gcloudConfigDir() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud")
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".config", "gcloud")
+}
+
+// adcPath returns the path to the application default credentials file.
+func adcPath() string {
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return path
+	}
+	return filepath.Join(gcloudConfigDir(), "application_default_credentials.json")
+}
+
+// gcloudFingerprint summarizes the files that determine the default project
+// and ADC validity: the active configuration, its properties, and the ADC file.
+// Any change in presence or modification time yields a different fingerprint.
+func gcloudFingerprint() string {
+	configDir := gcloudConfigDir()
+
+	activeConfig := "default"
+	if data, err := os.ReadFile(filepath.Join(configDir, "active_config")); err == nil {
+		if name := strings.TrimSpace(string(data)); name != "" {
+			activeConfig = name
+		}
+	}
+	if name := os.Getenv("CLOUDSDK_ACTIVE_CONFIG_NAME"); name != "" {
+		activeConfig = name
+	}
+
+	paths := []string{
+		filepath.Join(configDir, "active_config"),
+		filepath.Join(configDir, "configurations", "config_"+activeConfig),
+		adcPath(),
+	}
+
+	parts := []string{"config=" + activeConfig, "env=" + os.Getenv("CLOUDSDK_CORE_PROJECT")}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			parts = append(parts, path+":missing")
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d", path, info.ModTime().UnixNano()))
+	}
+
+	return strings.Join(parts, "|")
+}
+
+// getDefaultProject gets the default GCP project from gcloud config.
+func getDefaultProject() (string, error) {
+	if _, err := exec.LookPath("gcloud"); err != nil {
+		return "", fmt.Errorf("no project found in credentials and gcloud is not installed (set GOOGLE_CLOUD_PROJECT)")
+	}
+
+	cmd := exec.Command("gcloud", "config", "get-value", "project")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get default project: %w (ensure gcloud is installed and configured)", err)
+	}
+
+	project := strings.TrimSpace(string(output))
+	if project == "" {
+		return "", fmt.Errorf("no default project set (run: gcloud config set project PROJECT_ID)")
+	}
+
+	return project, nil
+}
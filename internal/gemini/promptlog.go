@@ -0,0 +1,203 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// Record types in the prompt log.
+const (
+	recordSystem       = "system"
+	recordUser         = "user"
+	recordModel        = "model"
+	recordToolCall     = "tool_call"
+	recordToolResponse = "tool_response"
+	recordCorrection   = "correction"
+	recordQuestion     = "question"
+	recordAnswer       = "answer"
+)
+
+// promptRecord is one turn of the conversation sent to the model, and one
+// line of the prompt log when GX_PROMPT_OUTPUT_FORMAT=jsonl. The system
+// record names the model and mode; history turns are user and model records
+// with History set.
+type promptRecord struct {
+	Time    time.Time          `json:"time"`
+	Type    string             `json:"type"`
+	Model   string             `json:"model,omitempty"`
+	Mode    string             `json:"mode,omitempty"`
+	Turn    int                `json:"turn,omitempty"`
+	History bool               `json:"history,omitempty"`
+	Tool    string             `json:"tool,omitempty"`
+	Args    map[string]any     `json:"args,omitempty"`
+	Text    string             `json:"text,omitempty"`
+	Error   string             `json:"error,omitempty"`
+	Tools   []history.ToolCall `json:"tools,omitempty"`
+	// Attachments describes the files sent with a user prompt; their
+	// content isn't logged.
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// transcript collects the records of one request (or agent session) so the
+// whole conversation, including tool calls and corrections, can be written
+// to GX_PROMPT_OUTPUT.
+type transcript struct {
+	records []promptRecord
+}
+
+// add appends a record, stamping its time.
+func (l *transcript) add(r promptRecord) {
+	r.Time = time.Now().UTC()
+	l.records = append(l.records, r)
+}
+
+// addText appends a record that carries only text.
+func (l *transcript) addText(recordType, text string) {
+	l.add(promptRecord{Type: recordType, Text: text})
+}
+
+// addHistory appends the history turns seeded into the chat.
+func (l *transcript) addHistory(entries []history.Entry) {
+	for _, entry := range entries {
+		l.add(promptRecord{Type: recordUser, History: true, Text: entry.Prompt, Tools: entry.Tools})
+		l.add(promptRecord{Type: recordModel, History: true, Text: entry.Response})
+	}
+}
+
+// text renders the log in the original free-form layout: sections separated
+// by ---, with consecutive history turns and each turn's tool calls and
+// responses grouped into one section.
+func (l *transcript) text() string {
+	var sections []string
+	for i := 0; i < len(l.records); {
+		r := l.records[i]
+		j := i + 1
+		for j < len(l.records) && sameSection(r, l.records[j]) {
+			j++
+		}
+		group := l.records[i:j]
+		i = j
+
+		var b strings.Builder
+		switch {
+		case r.History:
+			b.WriteString("HISTORY CONTEXT:\n")
+			for _, h := range group {
+				if h.Type == recordUser {
+					fmt.Fprintf(&b, "User: %s\n%s", h.Text, historyToolsNote(history.Entry{Tools: h.Tools}))
+				} else {
+					fmt.Fprintf(&b, "Assistant: %s\n", h.Text)
+				}
+			}
+		case r.Type == recordToolCall:
+			fmt.Fprintf(&b, "TURN %d - MODEL RESPONSE (FUNCTION CALLS):\n", r.Turn)
+			for _, call := range group {
+				argsJSON, _ := json.MarshalIndent(call.Args, "", "  ")
+				fmt.Fprintf(&b, "Function: %s\nArgs: %s\n", call.Tool, string(argsJSON))
+			}
+		case r.Type == recordToolResponse:
+			fmt.Fprintf(&b, "TURN %d - TOOL RESPONSES:\n", r.Turn)
+			for _, response := range group {
+				if response.Error != "" {
+					fmt.Fprintf(&b, "Function: %s - Error: %s\n", response.Tool, response.Error)
+					continue
+				}
+				resultJSON, _ := json.MarshalIndent(response.Text, "", "  ")
+				fmt.Fprintf(&b, "Function: %s\nResult: %s\n", response.Tool, string(resultJSON))
+			}
+		case r.Type == recordModel && r.Turn > 0:
+			fmt.Fprintf(&b, "TURN %d - MODEL RESPONSE (FINAL):\n%s", r.Turn, r.Text)
+		default:
+			fmt.Fprintf(&b, "%s:\n%s", recordLabels[r.Type], r.Text)
+			for _, a := range r.Attachments {
+				fmt.Fprintf(&b, "\n[attached: %s]", a)
+			}
+		}
+		sections = append(sections, b.String())
+	}
+	return strings.Join(sections, "\n---\n\n")
+}
+
+// sameSection reports whether next belongs in the text section started by
+// first: history turns share one section, as do one turn's tool calls and
+// one turn's tool responses.
+func sameSection(first, next promptRecord) bool {
+	if first.History {
+		return next.History
+	}
+	return (first.Type == recordToolCall || first.Type == recordToolResponse) &&
+		next.Type == first.Type && next.Turn == first.Turn
+}
+
+// recordLabels are the section headings of the text layout.
+var recordLabels = map[string]string{
+	recordSystem:     "SYSTEM INSTRUCTION",
+	recordUser:       "USER PROMPT",
+	recordModel:      "MODEL RESPONSE",
+	recordCorrection: "CORRECTION",
+	recordQuestion:   "MODEL QUESTION",
+	recordAnswer:     "USER ANSWER",
+}
+
+// jsonl renders the log as one JSON record per line.
+func (l *transcript) jsonl() ([]byte, error) {
+	var b strings.Builder
+	for _, r := range l.records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String()), nil
+}
+
+// writePromptLog writes the prompt log to a file if GX_PROMPT_OUTPUT is set.
+// If GX_PROMPT_OUTPUT is not set, defaults to ~/.gxprompt.
+// GX_PROMPT_OUTPUT_FORMAT selects text (the default) or jsonl.
+func (c *Client) writePromptLog(t *transcript) {
+	outputPath := os.Getenv("GX_PROMPT_OUTPUT")
+
+	// If not set, default to ~/.gxprompt
+	if outputPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return // Silently fail if we can't get home directory
+		}
+		outputPath = filepath.Join(homeDir, ".gxprompt")
+	} else {
+		// Expand ~ if present in the env var value
+		var err error
+		if outputPath, err = expandHome(outputPath); err != nil {
+			return // Silently fail if we can't get home directory
+		}
+	}
+
+	var content []byte
+	switch format := os.Getenv("GX_PROMPT_OUTPUT_FORMAT"); format {
+	case "jsonl":
+		var err error
+		if content, err = t.jsonl(); err != nil {
+			c.logger.Warn("failed to encode prompt log", "error", err)
+			return
+		}
+	case "", "text":
+		content = []byte(t.text())
+	default:
+		c.logger.Warn("unknown GX_PROMPT_OUTPUT_FORMAT, writing text", "format", format)
+		content = []byte(t.text())
+	}
+
+	// Write to file (create or overwrite)
+	if err := os.WriteFile(outputPath, content, 0644); err != nil {
+		// Silently fail - this is a debugging feature
+		_ = err
+	}
+}
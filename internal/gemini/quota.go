@@ -0,0 +1,63 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// QuotaError reports that Vertex AI rejected a request for exceeding quota
+// (HTTP 429 / RESOURCE_EXHAUSTED), with the project the quota is charged to.
+type QuotaError struct {
+	Project  string
+	Location string
+	Model    string
+	Err      error
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf(`Vertex AI quota exceeded for project %q (model %s in %s)
+
+The project's requests-per-minute or token quota for this model is used up.
+  - Wait a minute and retry; per-minute quotas reset quickly.
+  - Check usage or request an increase: https://console.cloud.google.com/iam-admin/quotas?project=%s
+  - Try another region (GX_LOCATION) or model (GX_MODEL), which have separate quotas.
+  - If a script is calling gx in a loop, lower GX_RATE_LIMIT to cap requests per minute.
+
+Details: %v`, e.Project, e.Model, e.Location, e.Project, e.Err)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return e.Err
+}
+
+// isQuotaError reports whether err is a 429 / RESOURCE_EXHAUSTED response
+// from either the gRPC or the REST transport.
+func isQuotaError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if s, ok := status.FromError(err); ok && s.Code() == codes.ResourceExhausted {
+		return true
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	// Some paths flatten the status into the message
+	msg := err.Error()
+	return strings.Contains(msg, "RESOURCE_EXHAUSTED") || strings.Contains(msg, "Error 429")
+}
+
+// wrapQuotaError turns quota errors into a *QuotaError with remedies.
+func (c *Client) wrapQuotaError(err error) error {
+	if !isQuotaError(err) {
+		return err
+	}
+	return &QuotaError{Project: c.projectID, Location: c.location, Model: c.modelName, Err: err}
+}
@@ -0,0 +1,172 @@
+package gemini
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// maxRecordLine bounds one JSONL record; tool responses can be large.
+const maxRecordLine = 16 << 20
+
+// Recording is a request read back from a JSONL prompt log
+// (GX_PROMPT_OUTPUT_FORMAT=jsonl) so it can be sent again with gx replay.
+type Recording struct {
+	// Model is the model that answered, when the log records it.
+	Model string
+	// Mode is the client mode the request ran in.
+	Mode Mode
+	// Time is when the request was sent.
+	Time     time.Time
+	Prompt   string
+	History  []history.Entry
+	Response string
+	// answers are the user's replies to clarifying questions, in order.
+	answers []string
+	// tools holds the recorded responses for each tool call, keyed by
+	// toolKey, in the order they were made.
+	tools map[string][]promptRecord
+}
+
+// LoadRecording reads the first request from a JSONL prompt log. The
+// recorded response is the model's final answer, with the command taken out
+// of structured replies so it compares with what GenerateResult returns.
+func LoadRecording(path string) (*Recording, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt log: %w", err)
+	}
+	defer f.Close()
+
+	var records []promptRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxRecordLine)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var r promptRecord
+		if err := json.Unmarshal([]byte(text), &r); err != nil || r.Type == "" {
+			return nil, fmt.Errorf("%s:%d is not a JSONL prompt log record (record with GX_PROMPT_OUTPUT_FORMAT=jsonl)", path, line)
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read prompt log: %w", err)
+	}
+	return newRecording(records)
+}
+
+// newRecording assembles the first request of a prompt log.
+func newRecording(records []promptRecord) (*Recording, error) {
+	rec := &Recording{tools: map[string][]promptRecord{}}
+	var pending *history.Entry
+	var calls []promptRecord
+	seenPrompt := false
+	for _, r := range records {
+		switch {
+		case r.Type == recordSystem:
+			rec.Model, rec.Mode = r.Model, Mode(r.Mode)
+		case r.History && r.Type == recordUser:
+			pending = &history.Entry{Prompt: r.Text, Tools: r.Tools}
+		case r.History && r.Type == recordModel && pending != nil:
+			pending.Response = r.Text
+			rec.History = append(rec.History, *pending)
+			pending = nil
+		case r.Type == recordUser:
+			if seenPrompt {
+				// Agent sessions log one user record per step
+				return nil, errors.New("prompt log holds more than one request (agent sessions can't be replayed)")
+			}
+			if len(r.Attachments) > 0 {
+				// Only their names are logged
+				return nil, errors.New("requests with attachments (--image) can't be replayed")
+			}
+			rec.Prompt, rec.Time, seenPrompt = r.Text, r.Time, true
+		case r.Type == recordToolCall:
+			calls = append(calls, r)
+		case r.Type == recordToolResponse:
+			// Responses follow their turn's calls in the same order
+			if len(calls) > 0 {
+				key := toolKey(calls[0].Tool, calls[0].Args)
+				rec.tools[key] = append(rec.tools[key], r)
+				calls = calls[1:]
+			}
+		case r.Type == recordAnswer:
+			rec.answers = append(rec.answers, recordedAnswer(r.Text))
+		case r.Type == recordModel:
+			rec.Response = r.Text
+		}
+	}
+	if !seenPrompt {
+		return nil, errors.New("prompt log has no user prompt")
+	}
+	if rec.Mode == ModeAgent {
+		return nil, errors.New("agent sessions can't be replayed")
+	}
+	if decoded, ok := decodeStructured(rec.Response); ok {
+		rec.Response = decoded.Command
+	}
+	rec.Response = strings.TrimSpace(rec.Response)
+	return rec, nil
+}
+
+// recordedAnswer recovers what the user typed from the logged answer
+// message; "" means the model was told to assume.
+func recordedAnswer(message string) string {
+	prefix, suffix, _ := strings.Cut(answerMessage, "%s")
+	answer, ok := strings.CutPrefix(message, prefix)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSuffix(answer, suffix)
+}
+
+// Answer replies to a clarifying question with the next recorded answer, or
+// "" (let the model assume) once they run out. It fits Config.Clarify.
+func (r *Recording) Answer(question string) (string, error) {
+	if len(r.answers) == 0 {
+		return "", nil
+	}
+	answer := r.answers[0]
+	r.answers = r.answers[1:]
+	return answer, nil
+}
+
+// toolResponse returns the recorded response to a tool call with the same
+// name and arguments, consuming it. It reports false for calls the
+// recording doesn't have, which then run live.
+func (r *Recording) toolResponse(name string, args map[string]any) (promptRecord, bool) {
+	if r == nil {
+		return promptRecord{}, false
+	}
+	key := toolKey(name, args)
+	responses := r.tools[key]
+	if len(responses) == 0 {
+		return promptRecord{}, false
+	}
+	r.tools[key] = responses[1:]
+	return responses[0], true
+}
+
+// toolKey identifies a tool call by name and arguments. JSON encoding sorts
+// map keys, and numbers decode from the log as float64 just as they arrive
+// from the model, so equal calls get equal keys.
+func toolKey(name string, args map[string]any) string {
+	if len(args) == 0 {
+		return name + " {}"
+	}
+	encoded, _ := json.Marshal(args)
+	return name + " " + string(encoded)
+}
@@ -0,0 +1,131 @@
+package gemini
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+const (
+	// DefaultTemperature keeps output close to deterministic.
+	DefaultTemperature = 0.1
+	// DefaultTopP is the default nucleus sampling threshold.
+	DefaultTopP = 0.95
+	// DefaultCandidateCount is the default number of candidates requested.
+	DefaultCandidateCount = 1
+	// candidateTemperatureStep is how much each additional candidate's
+	// temperature is raised so samples actually differ.
+	candidateTemperatureStep = 0.35
+	// maxCandidateTemperature caps temperature-varied samples.
+	maxCandidateTemperature = 1.0
+)
+
+// Sampling holds generation sampling parameters.
+// CandidateCount is the number of temperature-varied samples to generate
+// (chat sessions only ever return one candidate per request).
+// Nil fields fall back to GX_TEMPERATURE, GX_TOP_P, GX_TOP_K, GX_CANDIDATES,
+// and GX_MAX_OUTPUT_TOKENS, then to the package defaults
+// (TopK and MaxOutputTokens default to the model's own settings).
+type Sampling struct {
+	Temperature     *float32
+	TopP            *float32
+	TopK            *int32
+	CandidateCount  *int32
+	MaxOutputTokens *int32
+}
+
+// ResolveSampling fills unset fields from the environment and defaults.
+func ResolveSampling(s Sampling) Sampling {
+	if s.Temperature == nil {
+		s.Temperature = envFloat32("GX_TEMPERATURE", DefaultTemperature)
+	}
+	if s.TopP == nil {
+		s.TopP = envFloat32("GX_TOP_P", DefaultTopP)
+	}
+	if s.TopK == nil {
+		if v, err := strconv.Atoi(os.Getenv("GX_TOP_K")); err == nil && v > 0 {
+			s.TopK = genai.Ptr(int32(v))
+		}
+	}
+	if s.MaxOutputTokens == nil {
+		if v, err := strconv.Atoi(os.Getenv("GX_MAX_OUTPUT_TOKENS")); err == nil && v > 0 {
+			s.MaxOutputTokens = genai.Ptr(int32(v))
+		}
+	}
+	if s.CandidateCount == nil {
+		n := int32(DefaultCandidateCount)
+		if v, err := strconv.Atoi(os.Getenv("GX_CANDIDATES")); err == nil && v > 0 {
+			n = int32(v)
+		}
+		s.CandidateCount = &n
+	}
+	return s
+}
+
+// String renders the sampling parameters, e.g. for cache keys and debug logs.
+func (s Sampling) String() string {
+	topK := "default"
+	if s.TopK != nil {
+		topK = strconv.Itoa(int(*s.TopK))
+	}
+	return fmt.Sprintf("temperature=%s top_p=%s top_k=%s candidates=%s max_output_tokens=%s",
+		formatFloatPtr(s.Temperature), formatFloatPtr(s.TopP), topK, formatIntPtr(s.CandidateCount), formatIntPtr(s.MaxOutputTokens))
+}
+
+// apply sets the sampling parameters on the model.
+func (s Sampling) apply(model *genai.GenerativeModel) {
+	if s.Temperature != nil {
+		model.SetTemperature(*s.Temperature)
+	}
+	if s.TopP != nil {
+		model.SetTopP(*s.TopP)
+	}
+	if s.TopK != nil {
+		model.SetTopK(*s.TopK)
+	}
+	if s.MaxOutputTokens != nil {
+		model.SetMaxOutputTokens(*s.MaxOutputTokens)
+	}
+}
+
+// candidateTemperature returns the temperature for the i-th sample (0-based):
+// the configured temperature for the first, then increasingly varied ones.
+func (s Sampling) candidateTemperature(i int) float32 {
+	base := float32(DefaultTemperature)
+	if s.Temperature != nil {
+		base = *s.Temperature
+	}
+	t := base + float32(i)*candidateTemperatureStep
+	if t > maxCandidateTemperature {
+		t = maxCandidateTemperature
+	}
+	if t < base {
+		t = base
+	}
+	return t
+}
+
+// envFloat32 reads a float from the environment, falling back to def.
+func envFloat32(key string, def float32) *float32 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 32); err == nil && v >= 0 {
+		f := float32(v)
+		return &f
+	}
+	return &def
+}
+
+func formatFloatPtr(f *float32) string {
+	if f == nil {
+		return "default"
+	}
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+func formatIntPtr(i *int32) string {
+	if i == nil {
+		return "default"
+	}
+	return strconv.Itoa(int(*i))
+}
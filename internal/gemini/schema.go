@@ -0,0 +1,202 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// Risk levels reported by the model for a generated command.
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// Confidence levels the model reports when escalation is enabled.
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// structuredResponse is the JSON object the model returns for a single command.
+type structuredResponse struct {
+	Command           string `json:"command"`
+	Explanation       string `json:"explanation"`
+	Risk              string `json:"risk"`
+	NeedsConfirmation bool   `json:"needs_confirmation"`
+	Undo              string `json:"undo,omitempty"`
+	Question          string `json:"question,omitempty"`
+	// Confidence is only requested when escalation is enabled
+	Confidence string `json:"confidence,omitempty"`
+	// Options are set only when the user asked for several ways to do it
+	Options []structuredOption `json:"options,omitempty"`
+}
+
+// structuredOption is one of several independent commands in a reply.
+type structuredOption struct {
+	Command string `json:"command"`
+	Note    string `json:"note"`
+	Risk    string `json:"risk"`
+}
+
+// commandSchema is the response schema for structuredResponse. The undo,
+// question, and confidence fields are only requested when the matching
+// argument is set.
+func commandSchema(undo, clarify, confidence bool) *genai.Schema {
+	schema := &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"command": {
+				Type:        genai.TypeString,
+				Description: "The executable shell command, with comments only where the instructions allow them.",
+			},
+			"explanation": {
+				Type:        genai.TypeString,
+				Description: "One sentence explaining the key choices (flags, tools).",
+			},
+			"risk": {
+				Type:        genai.TypeString,
+				Enum:        []string{RiskLow, RiskMedium, RiskHigh},
+				Description: "How much damage running the command could do.",
+			},
+			"needs_confirmation": {
+				Type:        genai.TypeBoolean,
+				Description: "Whether the user should review the command before it runs.",
+			},
+			"options": {
+				Type:        genai.TypeArray,
+				Description: "Only when the user asks for several ways or options: each independent command, with command set to the first. Otherwise empty.",
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"command": {Type: genai.TypeString, Description: "One complete command."},
+						"note":    {Type: genai.TypeString, Description: "A short note on when to prefer it."},
+						"risk":    {Type: genai.TypeString, Enum: []string{RiskLow, RiskMedium, RiskHigh}},
+					},
+					Required: []string{"command", "note", "risk"},
+				},
+			},
+		},
+		Required: []string{"command", "explanation", "risk", "needs_confirmation"},
+	}
+	if undo {
+		schema.Properties["undo"] = &genai.Schema{
+			Type:        genai.TypeString,
+			Description: "The single-line command that best reverses the command's effect, or empty if it is read-only or irreversible.",
+		}
+		schema.Required = append(schema.Required, "undo")
+	}
+	if clarify {
+		schema.Properties["question"] = &genai.Schema{
+			Type:        genai.TypeString,
+			Description: "A single question for the user when the request is too ambiguous to answer, with command left empty; otherwise empty.",
+		}
+		schema.Required = append(schema.Required, "question")
+	}
+	if confidence {
+		schema.Properties["confidence"] = &genai.Schema{
+			Type:        genai.TypeString,
+			Enum:        []string{ConfidenceHigh, ConfidenceMedium, ConfidenceLow},
+			Description: "How sure you are that the command does what was asked on this system.",
+		}
+		schema.Required = append(schema.Required, "confidence")
+	}
+	return schema
+}
+
+// structuredInstruction describes the JSON reply. It is needed alongside the
+// response schema because Gemini can't combine a schema with function
+// calling, so with tools enabled the format is only requested in the prompt.
+func structuredInstruction(undo, clarify, confidence bool) string {
+	extraFields := ""
+	extraRules := ""
+	if undo {
+		extraFields += `, "undo": "<inverse command>"`
+		extraRules += `
+- undo: the single-line command that best reverses the command's effect (for example mv the file back, git revert, docker start), or "" if the command is read-only or cannot be undone (for example rm).`
+	}
+	if clarify {
+		extraFields += `, "question": ""`
+		extraRules += `
+- question: normally "". Only when the request is ambiguous in a way that changes which command is right (for example which of several disks, hosts, or branches) and the tools can't settle it, set command to "" and put one short question here, listing the options you found. The user's answer comes back in the next message. Never ask about details you can reasonably assume.`
+	}
+	if confidence {
+		extraFields += `, "confidence": "high|medium|low"`
+		extraRules += `
+- confidence: how sure you are that the command does what was asked on this system; "low" when you are guessing at flags, tool versions, or what the user meant.`
+	}
+	return fmt.Sprintf(`OUTPUT FORMAT:
+Reply with a single JSON object and nothing else:
+{"command": "<shell command>", "explanation": "<one sentence>", "risk": "low|medium|high", "needs_confirmation": true|false%s}
+- command: the executable command exactly as it should be run, following the rules above.
+- explanation: one sentence explaining the key choices (flags, tools).
+- risk: "low" for read-only commands, "medium" for changes that are easy to reverse, "high" for commands that delete, overwrite, or change data or system state in ways that are hard to reverse.
+- needs_confirmation: true when the user should review the command before running it (always for high risk).
+- options: omit it normally. Only when the user asks for several ways or options (for example "three ways to count lines"), add "options": [{"command": "<command>", "note": "<when to prefer it>", "risk": "low|medium|high"}, ...] with one entry per independent command, and set command to the first one. Never use it to split one task into steps.%s`, extraFields, extraRules)
+}
+
+// decodeStructured parses a structured reply, tolerating code fences around
+// the object. It reports false when the reply isn't the expected JSON, in
+// which case the caller treats the whole reply as the command.
+func decodeStructured(response string) (structuredResponse, bool) {
+	var r structuredResponse
+	if !decodeJSONObject(response, &r) || strings.TrimSpace(r.Command) == "" {
+		return structuredResponse{}, false
+	}
+	r.Command = strings.TrimSpace(r.Command)
+	r.Explanation = strings.TrimSpace(r.Explanation)
+	r.Risk = strings.ToLower(strings.TrimSpace(r.Risk))
+	r.Confidence = strings.ToLower(strings.TrimSpace(r.Confidence))
+	if r.Risk == RiskHigh {
+		r.NeedsConfirmation = true
+	}
+	r.Undo = normalizeUndo(r.Undo)
+	r.Options = normalizeOptions(r.Options)
+	return r, true
+}
+
+// decodeJSONObject unmarshals the JSON object in a reply into v, ignoring
+// code fences and any prose around the braces. It reports whether it succeeded.
+func decodeJSONObject(response string, v any) bool {
+	text := strings.TrimSpace(response)
+	if blocks := fencedBlock.FindStringSubmatch(text); blocks != nil {
+		text = strings.TrimSpace(blocks[1])
+	}
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return false
+	}
+	return json.Unmarshal([]byte(text[start:end+1]), v) == nil
+}
+
+// normalizeOptions cleans up the options in a reply, dropping empty ones.
+// Fewer than two options are no choice, so they are dropped too.
+func normalizeOptions(options []structuredOption) []structuredOption {
+	var cleaned []structuredOption
+	for _, o := range options {
+		o.Command, _ = stripMarkdown(o.Command)
+		if o.Command == "" {
+			continue
+		}
+		o.Note = strings.TrimSpace(o.Note)
+		o.Risk = strings.ToLower(strings.TrimSpace(o.Risk))
+		cleaned = append(cleaned, o)
+	}
+	if len(cleaned) < 2 {
+		return nil
+	}
+	return cleaned
+}
+
+// normalizeUndo cleans up an undo hint, returning "" for "none".
+func normalizeUndo(undo string) string {
+	if strings.EqualFold(strings.Trim(undo, " .`"), "none") {
+		return ""
+	}
+	undo, _ = stripMarkdown(undo)
+	return undo
+}
@@ -0,0 +1,73 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// History limits for tool calls: how many are kept per entry, and how much
+// of each result. Enough for "the model saw container X", not whole files.
+const (
+	maxHistoryToolCalls  = 8
+	maxHistoryToolResult = 300
+)
+
+// toolCalls pairs the function calls and responses in a chat's contents
+// into history records, with results truncated.
+func (c *Client) toolCalls(contents []*genai.Content) []history.ToolCall {
+	var calls []history.ToolCall
+	// Responses come back in call order; pending maps a tool name to the
+	// indexes of its calls still waiting for one
+	pending := map[string][]int{}
+	for _, content := range contents {
+		for _, part := range content.Parts {
+			switch p := part.(type) {
+			case genai.FunctionCall:
+				if len(calls) == maxHistoryToolCalls {
+					continue
+				}
+				args := make(map[string]any, len(p.Args))
+				for k, v := range p.Args {
+					args[k] = v
+				}
+				pending[p.Name] = append(pending[p.Name], len(calls))
+				calls = append(calls, history.ToolCall{Call: fmt.Sprintf("%s(%s)", p.Name, c.formatToolArgs(args))})
+			case genai.FunctionResponse:
+				waiting := pending[p.Name]
+				if len(waiting) == 0 {
+					continue
+				}
+				call := &calls[waiting[0]]
+				pending[p.Name] = waiting[1:]
+				if errMsg, ok := p.Response["error"].(string); ok {
+					call.Error = errMsg
+				} else if result, ok := p.Response["result"].(string); ok {
+					call.Result = truncateResult(result, maxHistoryToolResult)
+				}
+			}
+		}
+	}
+	return calls
+}
+
+// historyToolsNote describes the tool calls saved with a history entry, for
+// the context sent with follow-up prompts, or returns "" when there were none.
+func historyToolsNote(entry history.Entry) string {
+	if len(entry.Tools) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Tools called while answering this:\n")
+	for _, t := range entry.Tools {
+		outcome := t.Result
+		if t.Error != "" {
+			outcome = "error: " + t.Error
+		}
+		fmt.Fprintf(&b, "- %s:\n  %s\n", t.Call, strings.ReplaceAll(strings.TrimSpace(outcome), "\n", "\n  "))
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package gemini
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// toolLogEntry is one line of the GX_TOOL_LOG audit log.
+type toolLogEntry struct {
+	Time         time.Time      `json:"time"`
+	Tool         string         `json:"tool"`
+	Args         map[string]any `json:"args"`
+	DurationMS   int64          `json:"duration_ms"`
+	Error        string         `json:"error,omitempty"`
+	ResultBytes  int            `json:"result_bytes"`
+	ResultSHA256 string         `json:"result_sha256,omitempty"`
+	Result       string         `json:"result,omitempty"`
+}
+
+// logToolCall appends a tool call to the JSONL file named by GX_TOOL_LOG, a
+// record of what the model looked at on this machine that is kept regardless
+// of the log level. Failures are logged and otherwise ignored.
+func (c *Client) logToolCall(name string, args map[string]any, start time.Time, result string, toolErr error) {
+	path := os.Getenv("GX_TOOL_LOG")
+	if path == "" {
+		return
+	}
+	path, err := expandHome(path)
+	if err != nil {
+		c.logger.Warn("failed to resolve GX_TOOL_LOG", "error", err)
+		return
+	}
+
+	entry := toolLogEntry{
+		Time:       start.UTC(),
+		Tool:       name,
+		Args:       args,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if toolErr != nil {
+		entry.Error = toolErr.Error()
+	} else {
+		sum := sha256.Sum256([]byte(result))
+		entry.ResultBytes = len(result)
+		entry.ResultSHA256 = hex.EncodeToString(sum[:])
+		// The full result is kept only as a hash and size
+		entry.Result = c.formatToolResult(result)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		c.logger.Warn("failed to encode tool log entry", "error", err)
+		return
+	}
+	// One write per line keeps concurrent gx processes from interleaving
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		c.logger.Warn("failed to open GX_TOOL_LOG", "path", path, "error", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		c.logger.Warn("failed to write GX_TOOL_LOG", "path", path, "error", err)
+	}
+}
+
+// expandHome expands a leading ~ in a path from an environment variable.
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(strings.TrimPrefix(path, "~"), "/")), nil
+}
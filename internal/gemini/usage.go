@@ -0,0 +1,31 @@
+package gemini
+
+import "cloud.google.com/go/vertexai/genai"
+
+// Usage counts the model requests a client has made and the tokens they
+// used, as reported by the API.
+type Usage struct {
+	Requests     int
+	PromptTokens int
+	OutputTokens int
+	TotalTokens  int
+}
+
+// Usage returns the client's usage so far.
+func (c *Client) Usage() Usage {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	return c.usage
+}
+
+// recordUsage adds a response's token counts; candidates run concurrently.
+func (c *Client) recordUsage(resp *genai.GenerateContentResponse) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	c.usage.Requests++
+	if m := resp.UsageMetadata; m != nil {
+		c.usage.PromptTokens += int(m.PromptTokenCount)
+		c.usage.OutputTokens += int(m.CandidatesTokenCount)
+		c.usage.TotalTokens += int(m.TotalTokenCount)
+	}
+}
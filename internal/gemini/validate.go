@@ -0,0 +1,277 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/nealhardesty/gx/internal/shell"
+)
+
+// maxCorrections is the number of corrective follow-up turns sent when a
+// response doesn't meet the requested output constraints.
+const maxCorrections = 2
+
+// oneLinerCorrection is sent when --one-liner output spans multiple lines.
+const oneLinerCorrection = "Your answer spans multiple lines. Rewrite it as a single-line command " +
+	"(chain steps with && or ; and avoid line continuations). Return only that one line."
+
+// markdownCorrection is sent when markdown can't be stripped cleanly.
+const markdownCorrection = "Your answer contained markdown. Return only the raw shell command - " +
+	"no code fences, no backticks, no prompt characters, no explanation."
+
+// posixCorrection is sent when --posix output uses features POSIX sh lacks.
+const posixCorrection = "Your command isn't POSIX sh: %s. Rewrite it using only POSIX sh features " +
+	"so it runs in dash and BusyBox ash. Return only the command."
+
+// quotingCorrection is sent when the command's quoting is broken or expands
+// something the user asked for literally.
+const quotingCorrection = "Your command has a quoting problem: %s. Fix the quoting and escaping " +
+	"without changing what the command does. Return only the corrected command."
+
+var (
+	// fencedBlock matches a ``` fenced code block with an optional language tag.
+	fencedBlock = regexp.MustCompile("(?s)```[A-Za-z0-9_+-]*[ \t]*\n?(.*?)```")
+	// promptPrefix matches shell prompt characters copied into the answer
+	// ("$ " and "PS C:\> "). "# " is left alone since it's a comment, and a
+	// bare "> " is left alone since it's a valid redirection.
+	promptPrefix = regexp.MustCompile(`^(\$|PS [^>]*>)\s+`)
+	// proseLead matches introductory prose lines such as "Here is the command:".
+	proseLead = regexp.MustCompile(`(?i)^(here('s| is| are)|this (command|will)|you can|use the following|to [a-z]).*:\s*$`)
+)
+
+// stripMarkdown removes code fences, inline backticks, shell prompt prefixes,
+// and surrounding prose from a response. It reports whether the result is
+// clean enough to use; if not, the caller should re-prompt.
+func stripMarkdown(response string) (string, bool) {
+	cleaned := strings.TrimSpace(response)
+
+	// Prefer the contents of fenced blocks, discarding prose around them
+	if blocks := fencedBlock.FindAllStringSubmatch(cleaned, -1); len(blocks) > 0 {
+		var parts []string
+		for _, block := range blocks {
+			if body := strings.TrimSpace(block[1]); body != "" {
+				parts = append(parts, body)
+			}
+		}
+		cleaned = strings.Join(parts, "\n")
+	}
+
+	// A whole answer wrapped in single backticks
+	if len(cleaned) > 1 && strings.HasPrefix(cleaned, "`") && strings.HasSuffix(cleaned, "`") && strings.Count(cleaned, "`") == 2 {
+		cleaned = strings.TrimSpace(cleaned[1 : len(cleaned)-1])
+	}
+
+	var lines []string
+	for i, line := range strings.Split(cleaned, "\n") {
+		// Drop a leading "Here is the command:" style line
+		if i == 0 && proseLead.MatchString(strings.TrimSpace(line)) {
+			continue
+		}
+		lines = append(lines, promptPrefix.ReplaceAllString(line, ""))
+	}
+	cleaned = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	ok := cleaned != "" && !strings.Contains(cleaned, "```")
+	return cleaned, ok
+}
+
+// cleanResponse strips markdown from a response, re-prompting when it
+// can't be cleaned up locally.
+func (c *Client) cleanResponse(ctx context.Context, chat *genai.ChatSession, result string, promptLog *transcript) (string, error) {
+	for attempt := 1; ; attempt++ {
+		cleaned, ok := stripMarkdown(result)
+		if cleaned != strings.TrimSpace(result) {
+			c.logger.Debug("stripped markdown from response")
+		}
+		if ok {
+			return cleaned, nil
+		}
+		if attempt > maxCorrections {
+			return "", invalidf("model kept returning markdown instead of a command after %d attempts", maxCorrections)
+		}
+		c.logger.Info("response contains markdown, re-prompting", "attempt", attempt)
+		var err error
+		result, err = c.correct(ctx, chat, markdownCorrection, promptLog)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// proseCorrection is sent when the response reads like an explanation.
+const proseCorrection = "That was an explanation, not a command. Reply with only the executable " +
+	"shell command that accomplishes the request. If it truly cannot be done with a command, " +
+	"say so in a single shell comment."
+
+// shellTokens are characters and words that rarely appear in prose but are
+// common in commands.
+var shellTokens = regexp.MustCompile(`[|<>$=;&{}\[\]\\` + "`" + `]|(^|\s)--?[A-Za-z]|/[A-Za-z.]`)
+
+// looksLikeProse reports whether a response reads like an explanation rather
+// than a command: most non-comment lines are sentences with no shell tokens.
+// Responses made entirely of comments are allowed (see rule 7).
+func looksLikeProse(response string) bool {
+	var code, prose int
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(strings.ToUpper(line), "REM ") {
+			continue
+		}
+		code++
+		if isSentence(line) {
+			prose++
+		}
+	}
+	return code > 0 && prose*2 > code
+}
+
+// isSentence reports whether a line looks like an English sentence.
+func isSentence(line string) bool {
+	words := strings.Fields(line)
+	if len(words) < 5 || shellTokens.MatchString(line) {
+		return false
+	}
+	first := line[0]
+	last := line[len(line)-1]
+	return first >= 'A' && first <= 'Z' && (last == '.' || last == ':' || last == '!' || last == '?' || last == ',')
+}
+
+// ensureCommand sends one corrective turn when the response is prose, and
+// gives up with an error if the retry is still prose.
+func (c *Client) ensureCommand(ctx context.Context, chat *genai.ChatSession, result string, promptLog *transcript) (string, error) {
+	if !looksLikeProse(result) {
+		return result, nil
+	}
+
+	c.logger.Info("response looks like an explanation, re-prompting")
+	retry, err := c.correct(ctx, chat, proseCorrection, promptLog)
+	if err != nil {
+		return "", err
+	}
+	retry, _ = stripMarkdown(retry)
+	if looksLikeProse(retry) {
+		return "", invalidf("model returned an explanation instead of a command:\n%s", retry)
+	}
+	return retry, nil
+}
+
+// quotingProblem describes what is wrong with a POSIX command's quoting: a
+// quote or substitution left open, or a literal from the prompt (see
+// shell.Literals) that the shell would expand. It returns "" when nothing is.
+func quotingProblem(command string, literals []string) string {
+	if open := shell.UnbalancedQuote(command); open != "" {
+		return open
+	}
+	if expanded := shell.ExpandedLiterals(command, literals); len(expanded) > 0 {
+		return fmt.Sprintf("the shell would expand %s, which was asked for literally (use single quotes or escape it)", strings.Join(expanded, ", "))
+	}
+	return ""
+}
+
+// checkQuoting verifies the command's quoting before it is staged and sends
+// one corrective turn when it is broken. A problem that survives the retry
+// is returned as a warning rather than an error, since the check can't
+// parse everything a shell accepts. PowerShell, cmd, and fish, whose
+// quoting rules differ from POSIX, are not checked.
+func (c *Client) checkQuoting(ctx context.Context, chat *genai.ChatSession, result, prompt string, promptLog *transcript) (string, string, error) {
+	if shell.IsPowerShell(c.shell) || c.shell == "cmd" || c.shell == "fish" {
+		return result, "", nil
+	}
+	literals := shell.Literals(prompt)
+	problem := quotingProblem(result, literals)
+	if problem == "" {
+		return result, "", nil
+	}
+
+	c.logger.Info("quoting problem, re-prompting", "problem", problem)
+	retry, err := c.correct(ctx, chat, fmt.Sprintf(quotingCorrection, problem), promptLog)
+	if err != nil {
+		return "", "", err
+	}
+	retry, _ = stripMarkdown(retry)
+	if retry == "" || looksLikeProse(retry) {
+		return result, problem, nil
+	}
+	return retry, quotingProblem(retry, literals), nil
+}
+
+// isMultiLine reports whether a response has more than one non-empty line.
+func isMultiLine(response string) bool {
+	lines := 0
+	for _, line := range strings.Split(response, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines++
+		}
+	}
+	return lines > 1
+}
+
+// enforceOneLiner re-prompts until the response is a single line or the
+// correction budget is spent.
+func (c *Client) enforceOneLiner(ctx context.Context, chat *genai.ChatSession, result string, promptLog *transcript) (string, error) {
+	for attempt := 1; isMultiLine(result); attempt++ {
+		if attempt > maxCorrections {
+			return "", invalidf("model did not produce a single-line command after %d attempts", maxCorrections)
+		}
+		c.logger.Info("response spans multiple lines, re-prompting", "attempt", attempt)
+		var err error
+		result, err = c.correct(ctx, chat, oneLinerCorrection, promptLog)
+		if err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
+
+// posixProblem describes what keeps a command from running in POSIX sh:
+// bashisms, or a parse error from dash or another strict shell.
+func posixProblem(ctx context.Context, command string) string {
+	if bashisms := shell.Bashisms(command); len(bashisms) > 0 {
+		return "it uses " + strings.Join(bashisms, ", ")
+	}
+	if complaint := shell.POSIXSyntaxError(ctx, command); complaint != "" {
+		return "a POSIX shell rejects it (" + complaint + ")"
+	}
+	return ""
+}
+
+// enforcePOSIX re-prompts until the response is POSIX sh or the
+// correction budget is spent.
+func (c *Client) enforcePOSIX(ctx context.Context, chat *genai.ChatSession, result string, promptLog *transcript) (string, error) {
+	for attempt := 1; ; attempt++ {
+		problem := posixProblem(ctx, result)
+		if problem == "" {
+			return result, nil
+		}
+		if attempt > maxCorrections {
+			return "", invalidf("model did not produce a POSIX sh command after %d attempts: %s", maxCorrections, problem)
+		}
+		c.logger.Info("command is not POSIX sh, re-prompting", "attempt", attempt, "problem", problem)
+		var err error
+		result, err = c.correct(ctx, chat, fmt.Sprintf(posixCorrection, problem), promptLog)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// correct sends a corrective follow-up turn and processes the new response.
+func (c *Client) correct(ctx context.Context, chat *genai.ChatSession, message string, promptLog *transcript) (string, error) {
+	promptLog.addText(recordCorrection, message)
+	resp, err := c.send(ctx, chat, 0, genai.Text(message))
+	if err != nil {
+		return "", fmt.Errorf("failed to send correction: %w", err)
+	}
+	result, err := c.processResponse(ctx, chat, resp, promptLog)
+	if err == nil && c.structured {
+		// Keep the original explanation and risk; only the command is corrected
+		if decoded, ok := decodeStructured(result); ok {
+			result = decoded.Command
+		}
+	}
+	return result, err
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -22,6 +23,24 @@ const (
 type Entry struct {
 	Prompt   string `json:"prompt"`
 	Response string `json:"response"`
+	// Undo is the closest inverse of Response, when one was generated (--undo).
+	Undo string `json:"undo,omitempty"`
+	// Risk is the model's risk rating for Response (low, medium, high), if any.
+	Risk string `json:"risk,omitempty"`
+	// Tools are the tool calls the model made while answering, so follow-up
+	// prompts know what it already looked at.
+	Tools []ToolCall `json:"tools,omitempty"`
+	// Machine is set when Response was generated for another machine, to the
+	// option that chose it (--os linux, --target nas); gx -x won't run it.
+	Machine string `json:"machine,omitempty"`
+}
+
+// ToolCall records one tool call and a truncated copy of its result.
+type ToolCall struct {
+	// Call is the tool and its arguments, e.g. ps(filter="docker").
+	Call   string `json:"call"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
 }
 
 // Manager handles reading and writing history.
@@ -92,19 +111,36 @@ func (m *Manager) Save(entries []Entry) error {
 
 // Append adds a new entry to the history and saves it.
 func (m *Manager) Append(prompt, response string) error {
+	return m.AppendEntry(Entry{
+		Prompt:   prompt,
+		Response: response,
+	})
+}
+
+// AppendEntry adds a complete entry (including any undo hint) to the history and saves it.
+func (m *Manager) AppendEntry(entry Entry) error {
 	entries, err := m.Load()
 	if err != nil {
 		entries = []Entry{}
 	}
 
-	entries = append(entries, Entry{
-		Prompt:   prompt,
-		Response: response,
-	})
+	entries = append(entries, entry)
 
 	return m.Save(entries)
 }
 
+// Last returns the most recent history entry.
+func (m *Manager) Last() (Entry, error) {
+	entries, err := m.Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, fmt.Errorf("history is empty (run gx with a prompt first)")
+	}
+	return entries[len(entries)-1], nil
+}
+
 // GetRecentContext returns the last n entries for context (typically 2-3).
 func (m *Manager) GetRecentContext(n int) ([]Entry, error) {
 	entries, err := m.Load()
@@ -119,9 +155,44 @@ func (m *Manager) GetRecentContext(n int) ([]Entry, error) {
 	return entries[len(entries)-n:], nil
 }
 
+// Staged is a staged command and what gx knew about it when it was staged,
+// so gx -x can check it however it got there.
+type Staged struct {
+	Command string
+	// Risk is the model's risk rating for Command (low, medium, high), if any.
+	Risk string
+	// Machine is set when Command was generated for another machine, to the
+	// option that chose it (--os linux, --target nas); gx -x won't run it.
+	Machine string
+	// Shell is the --shell Command was generated for, if any; gx -x runs
+	// it there, or not at all when that shell isn't available.
+	Shell string
+}
+
+// stagedHeader starts the lines above the command in the staging file that
+// record Staged's other fields, such as "# gx: risk=high". A file without
+// them is just the command.
+const stagedHeader = "# gx: "
+
 // StageCommand writes a command to the staging file.
 func (m *Manager) StageCommand(command string) error {
-	if err := os.WriteFile(m.stagingPath, []byte(command), 0600); err != nil {
+	return m.Stage(Staged{Command: command})
+}
+
+// Stage writes a command, and what is known about it, to the staging file.
+func (m *Manager) Stage(staged Staged) error {
+	var b strings.Builder
+	for _, field := range []struct{ key, value string }{
+		{"machine", staged.Machine},
+		{"risk", staged.Risk},
+		{"shell", staged.Shell},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "%s%s=%s\n", stagedHeader, field.key, field.value)
+		}
+	}
+	b.WriteString(staged.Command)
+	if err := os.WriteFile(m.stagingPath, []byte(b.String()), 0600); err != nil {
 		return fmt.Errorf("failed to stage command: %w", err)
 	}
 	return nil
@@ -129,14 +200,36 @@ func (m *Manager) StageCommand(command string) error {
 
 // GetStagedCommand reads the staged command.
 func (m *Manager) GetStagedCommand() (string, error) {
+	staged, err := m.GetStaged()
+	return staged.Command, err
+}
+
+// GetStaged reads the staged command and what was recorded with it.
+func (m *Manager) GetStaged() (Staged, error) {
 	data, err := os.ReadFile(m.stagingPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", fmt.Errorf("no staged command found (run gx with a prompt first)")
+			return Staged{}, fmt.Errorf("no staged command found (run gx with a prompt first)")
+		}
+		return Staged{}, fmt.Errorf("failed to read staged command: %w", err)
+	}
+	var staged Staged
+	rest := string(data)
+	for strings.HasPrefix(rest, stagedHeader) {
+		line, after, _ := strings.Cut(rest, "\n")
+		key, value, _ := strings.Cut(strings.TrimPrefix(line, stagedHeader), "=")
+		switch key {
+		case "machine":
+			staged.Machine = value
+		case "risk":
+			staged.Risk = value
+		case "shell":
+			staged.Shell = value
 		}
-		return "", fmt.Errorf("failed to read staged command: %w", err)
+		rest = after
 	}
-	return string(data), nil
+	staged.Command = rest
+	return staged, nil
 }
 
 // Clear removes both history and staging files.
@@ -154,6 +247,32 @@ func (m *Manager) Clear() error {
 	return nil
 }
 
+// Check reports problems with the history and staging files that Load and
+// GetStagedCommand would otherwise hide, such as corrupted JSON or bad permissions.
+// Missing files are fine.
+func (m *Manager) Check() error {
+	data, err := os.ReadFile(m.historyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read %s: %w", m.historyPath, err)
+	}
+	if err == nil {
+		var entries []Entry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("%s is corrupted and will be reset on the next save: %w", m.historyPath, err)
+		}
+	}
+
+	if _, err := os.ReadFile(m.stagingPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read %s: %w", m.stagingPath, err)
+	}
+	return nil
+}
+
+// HistoryPath returns the path to the history file.
+func (m *Manager) HistoryPath() string {
+	return m.historyPath
+}
+
 // StagingPath returns the path to the staging file.
 func (m *Manager) StagingPath() string {
 	return m.stagingPath
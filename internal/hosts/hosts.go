@@ -0,0 +1,160 @@
+// Package hosts loads named profiles of remote machines from ~/.gxhosts, so
+// `gx --target nas` generates for that machine's OS, shell, and tools
+// without describing them in every prompt.
+package hosts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/toml"
+)
+
+// DefaultFile is the default path for host profiles.
+const DefaultFile = ".gxhosts"
+
+// Profile describes a machine commands are generated for. The file is TOML
+// with one table per host:
+//
+//	[nas]
+//	description = "Synology NAS in the closet"
+//	os = "linux"
+//	arch = "amd64"
+//	shell = "ash"
+//	package_manager = "opkg"
+//	tools = ["docker", "rsync"]
+//	notes = "Shares are under /volume1. No sudo; log in as admin."
+type Profile struct {
+	Name        string
+	Description string
+	// OS is required: linux, darwin, or windows
+	OS             string
+	Arch           string
+	Shell          string
+	PackageManager string
+	// Tools are notable programs installed on the host
+	Tools []string
+	Notes string
+}
+
+// osNames are the values os accepts.
+var osNames = map[string]bool{"linux": true, "darwin": true, "windows": true}
+
+// Load reads ~/.gxhosts. A missing file has no profiles.
+func Load() (map[string]Profile, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return LoadFile(filepath.Join(homeDir, DefaultFile))
+}
+
+// LoadFile reads a host profile file. A missing file has no profiles.
+func LoadFile(path string) (map[string]Profile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]Profile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host profiles: %w", err)
+	}
+	table, err := toml.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	profiles := make(map[string]Profile, len(table))
+	for name, value := range table {
+		host, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: %s must be a [%s] table", path, name, name)
+		}
+		p, err := decode(name, host)
+		if err != nil {
+			return nil, fmt.Errorf("%s: [%s]: %w", path, name, err)
+		}
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// decode checks a host table against the known keys; unknown keys are
+// errors so a misspelled setting doesn't silently drop out of the prompt.
+func decode(name string, table map[string]any) (Profile, error) {
+	p := Profile{Name: name}
+	for key, value := range table {
+		if key == "tools" {
+			items, ok := value.([]any)
+			if !ok {
+				return p, errors.New("tools must be an array of strings")
+			}
+			for _, item := range items {
+				s, ok := item.(string)
+				if !ok {
+					return p, errors.New("tools must be an array of strings")
+				}
+				p.Tools = append(p.Tools, s)
+			}
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return p, fmt.Errorf("%s must be a string", key)
+		}
+		s = strings.TrimSpace(s)
+		switch key {
+		case "description":
+			p.Description = s
+		case "os":
+			p.OS = s
+		case "arch":
+			p.Arch = s
+		case "shell":
+			p.Shell = s
+		case "package_manager":
+			p.PackageManager = s
+		case "notes":
+			p.Notes = s
+		default:
+			return p, fmt.Errorf("unknown key %q (expected description, os, arch, shell, package_manager, tools, or notes)", key)
+		}
+	}
+	if !osNames[p.OS] {
+		return p, fmt.Errorf("os must be linux, darwin, or windows, not %q", p.OS)
+	}
+	return p, nil
+}
+
+// Names returns the profile names, sorted, for messages.
+func Names(profiles map[string]Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Context describes the host for the system prompt, one "- " line per
+// setting. OS, arch, and shell are left to the platform and shell lines.
+func (p Profile) Context() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "- Target host: %s", p.Name)
+	if p.Description != "" {
+		fmt.Fprintf(&b, " (%s)", p.Description)
+	}
+	b.WriteString("; the command will be pasted into a shell there, not run on this machine")
+	if p.PackageManager != "" {
+		fmt.Fprintf(&b, "\n- Package manager: %s", p.PackageManager)
+	}
+	if len(p.Tools) > 0 {
+		fmt.Fprintf(&b, "\n- Installed tools: %s", strings.Join(p.Tools, ", "))
+	}
+	if p.Notes != "" {
+		fmt.Fprintf(&b, "\n- Host notes: %s", p.Notes)
+	}
+	return b.String()
+}
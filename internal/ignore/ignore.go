@@ -0,0 +1,192 @@
+// Package ignore matches paths against .gxignore files, which use gitignore
+// syntax to keep paths away from the model's tools.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the ignore file read from the repository root.
+const FileName = ".gxignore"
+
+// Matcher holds the patterns of one ignore file.
+type Matcher struct {
+	// base is the directory patterns are relative to
+	base  string
+	rules []rule
+}
+
+// rule is one pattern line.
+type rule struct {
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Load reads an ignore file. A missing file is a nil Matcher, which ignores
+// nothing.
+func Load(path string) (*Matcher, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	m := &Matcher{base: base}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		r, ok, err := parseRule(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// parseRule parses one line, reporting false for blank lines and comments.
+func parseRule(line string) (rule, bool, error) {
+	// Trailing spaces are ignored unless escaped
+	line = strings.TrimRight(line, " \t")
+	if strings.HasSuffix(line, "\\") {
+		line += " "
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false, nil
+	}
+
+	r := rule{pattern: line}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return rule{}, false, nil
+	}
+
+	// A slash anywhere but the end anchors the pattern to the base
+	// directory; otherwise it matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := globToRegexp(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %q: %w", r.pattern, err)
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// globToRegexp translates gitignore glob syntax: * and ? stay within one
+// path component, ** spans any number of them, and [...] is a class.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Ignored reports whether an absolute path is excluded, either itself or
+// through an excluded parent directory (as in git, a file can't be
+// re-included once its directory is excluded). Paths outside the base
+// directory are never ignored.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(m.base, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i < len(parts); i++ {
+		if m.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.match(strings.Join(parts, "/"), isDir)
+}
+
+// match applies the rules to a slash-separated relative path; the last
+// matching rule decides.
+func (m *Matcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Patterns returns the pattern lines, for cache keys and diagnostics.
+func (m *Matcher) Patterns() []string {
+	if m == nil {
+		return nil
+	}
+	patterns := make([]string, len(m.rules))
+	for i, r := range m.rules {
+		patterns[i] = r.pattern
+	}
+	return patterns
+}
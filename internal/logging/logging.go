@@ -0,0 +1,79 @@
+// Package logging configures structured, leveled logging for gx.
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options controls how the logger is configured.
+type Options struct {
+	// Verbose lowers the default level to info (tool call tracing).
+	Verbose bool
+	// Debug lowers the level to debug and includes timestamps.
+	Debug bool
+}
+
+// New creates a logger that writes to stderr.
+// The level defaults to warn, is lowered by Verbose, overridden by GX_LOG_LEVEL,
+// and forced to debug by Debug.
+func New(opts Options) *slog.Logger {
+	level := slog.LevelWarn
+	if opts.Verbose {
+		level = slog.LevelInfo
+	}
+	if envLevel, ok := ParseLevel(os.Getenv("GX_LOG_LEVEL")); ok {
+		level = envLevel
+	}
+	if opts.Debug {
+		level = slog.LevelDebug
+	}
+
+	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// Timestamps are noise for interactive use; keep them only when debugging
+			if a.Key == slog.TimeKey && len(groups) == 0 && !opts.Debug {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	return slog.New(handler)
+}
+
+// Discard returns a logger that drops all records.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// ParseLevel parses a level name (debug, info, warn, error).
+func ParseLevel(s string) (slog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+// NewRequestID returns a short random identifier used to correlate log
+// records from a single invocation.
+func NewRequestID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
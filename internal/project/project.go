@@ -0,0 +1,185 @@
+// Package project loads per-project settings from a .gxrc or .gx.toml in
+// the repository, so a team can share instructions, tool limits, and
+// default flags for gx.
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/toml"
+)
+
+// FileNames are the project config files, in order of preference.
+var FileNames = []string{".gxrc", ".gx.toml"}
+
+// Config is a project config file. Both names use the same TOML format:
+//
+//	# Added to the system prompt for every request in this project
+//	instructions = """
+//	We deploy with `make deploy`, never kubectl directly.
+//	"""
+//	# Applied unless the command line sets the same flag
+//	flags = ["--comments", "-m", "smart"]
+//
+//	[tools]
+//	# The only paths file tools may read, relative to this file
+//	allow = ["src", "docs", "Makefile"]
+//
+// Instructions and flags come with the repository, so they only apply once
+// the file is trusted (see Trusted); the tool limits only narrow what gx
+// may read and always apply.
+type Config struct {
+	// Path is the absolute path of the file the config was read from.
+	Path string
+	// Hash is the SHA-256 of the file's contents, in hex.
+	Hash         string
+	Instructions string
+	Flags        []string
+	// AllowPaths are absolute; nil means tools may read anywhere.
+	AllowPaths []string
+}
+
+// Find looks for a project config in dir and its parents, stopping at the
+// repository root (the first directory with a .git entry). It returns nil
+// when there is none, including outside a repository, so a stray ~/.gxrc
+// doesn't apply everywhere.
+func Find(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := Root(dir)
+	if !ok {
+		return nil, nil
+	}
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				return Load(path)
+			}
+		}
+		if dir == root {
+			return nil, nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Root returns the repository root for dir: the nearest directory, dir
+// included, with a .git entry (a directory, or a file in worktrees and
+// submodules).
+func Root(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// Load reads a project config file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config: %w", err)
+	}
+	table, err := toml.Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cfg, err := decode(table, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	cfg.Hash = hex.EncodeToString(sum[:])
+	return cfg, nil
+}
+
+// decode checks the parsed file against the known keys; unknown keys are
+// errors so a misspelled tool limit can't silently allow everything.
+func decode(table map[string]any, root string) (*Config, error) {
+	cfg := &Config{}
+	for key, value := range table {
+		var err error
+		switch key {
+		case "instructions":
+			s, ok := value.(string)
+			if !ok {
+				return nil, errors.New("instructions must be a string")
+			}
+			cfg.Instructions = strings.TrimSpace(s)
+		case "flags":
+			cfg.Flags, err = stringList(key, value)
+		case "tools":
+			err = decodeTools(cfg, value, root)
+		default:
+			err = fmt.Errorf("unknown key %q (expected instructions, flags, or [tools])", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// decodeTools reads the [tools] table.
+func decodeTools(cfg *Config, value any, root string) error {
+	tools, ok := value.(map[string]any)
+	if !ok {
+		return errors.New("tools must be a table")
+	}
+	for key, value := range tools {
+		if key != "allow" {
+			return fmt.Errorf("unknown key tools.%s (expected allow)", key)
+		}
+		paths, err := stringList("tools.allow", value)
+		if err != nil {
+			return err
+		}
+		// An empty list allows nothing, which differs from no list at all
+		cfg.AllowPaths = []string{}
+		for _, p := range paths {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(root, p)
+			}
+			cfg.AllowPaths = append(cfg.AllowPaths, filepath.Clean(p))
+		}
+	}
+	return nil
+}
+
+// stringList converts an array value to strings.
+func stringList(key string, value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of strings", key)
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of strings", key)
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
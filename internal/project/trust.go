@@ -0,0 +1,72 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultTrustFile is the default path, relative to the home directory, for
+// the project configs the user has trusted with `gx trust`.
+const DefaultTrustFile = ".gxtrust"
+
+// Trusted reports whether the user trusted this file with its current
+// contents. A project config arrives with the repository, so its
+// instructions and flags only apply once someone has read it; any edit
+// makes it untrusted again.
+func (c *Config) Trusted() bool {
+	trusted, err := loadTrust()
+	return err == nil && trusted[c.Path] == c.Hash
+}
+
+// Trust records the file's current contents as trusted.
+func (c *Config) Trust() error {
+	trusted, err := loadTrust()
+	if err != nil {
+		return err
+	}
+	trusted[c.Path] = c.Hash
+	path, err := trustPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save trusted project configs: %w", err)
+	}
+	return nil
+}
+
+// loadTrust reads ~/.gxtrust, a map from config path to the SHA-256 of the
+// trusted contents. A missing file trusts nothing.
+func loadTrust() (map[string]string, error) {
+	path, err := trustPath()
+	if err != nil {
+		return nil, err
+	}
+	trusted := map[string]string{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted project configs: %w", err)
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return trusted, nil
+}
+
+// trustPath returns the path to ~/.gxtrust.
+func trustPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, DefaultTrustFile), nil
+}
@@ -0,0 +1,116 @@
+// Package ratelimit provides a client-side limit on model requests per minute,
+// shared by every gx process through a small state file.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRateLimitFile is the default path for the request log.
+	DefaultRateLimitFile = ".gxratelimit"
+	// DefaultLimit is the default number of requests allowed per window.
+	DefaultLimit = 30
+	// window is the sliding window the limit applies to.
+	window = time.Minute
+)
+
+// ExceededError is returned when the limit has been reached.
+type ExceededError struct {
+	Limit int
+	// RetryAfter is how long until the oldest request leaves the window.
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("local rate limit reached: %d requests in the last minute; retry in %s (raise or disable with GX_RATE_LIMIT, 0 = off)",
+		e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// Limiter enforces a maximum number of requests per minute across processes.
+// Each gx invocation is a separate process, so requests are recorded in
+// ~/.gxratelimit; concurrent processes may occasionally overshoot slightly.
+type Limiter struct {
+	path  string
+	limit int
+	// mu serializes concurrent requests within this process (--candidates)
+	mu sync.Mutex
+}
+
+// New creates a limiter. The limit can be overridden with GX_RATE_LIMIT
+// (requests per minute); 0 disables limiting.
+func New() (*Limiter, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	limit := DefaultLimit
+	if envLimit := os.Getenv("GX_RATE_LIMIT"); envLimit != "" {
+		if n, err := strconv.Atoi(envLimit); err == nil && n >= 0 {
+			limit = n
+		}
+	}
+
+	return &Limiter{
+		path:  filepath.Join(homeDir, DefaultRateLimitFile),
+		limit: limit,
+	}, nil
+}
+
+// Acquire records a request, or returns an *ExceededError without recording
+// it when the limit has been reached. A nil Limiter allows everything.
+func (l *Limiter) Acquire() error {
+	if l == nil || l.limit == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	recent := l.load(now)
+	if len(recent) >= l.limit {
+		return &ExceededError{
+			Limit:      l.limit,
+			RetryAfter: recent[len(recent)-l.limit].Add(window).Sub(now),
+		}
+	}
+
+	recent = append(recent, now)
+	data, err := json.Marshal(recent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limit state: %w", err)
+	}
+	// Failing to record is non-fatal; the request just isn't counted
+	_ = os.WriteFile(l.path, data, 0600)
+	return nil
+}
+
+// load returns the request times within the window ending at now, oldest first.
+func (l *Limiter) load(now time.Time) []time.Time {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil
+	}
+
+	var times []time.Time
+	if err := json.Unmarshal(data, &times); err != nil {
+		// If the file is corrupted, start fresh
+		return nil
+	}
+
+	var recent []time.Time
+	for _, t := range times {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
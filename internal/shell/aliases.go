@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// customizationsTimeout bounds the interactive shell that lists aliases
+	// and functions, so a slow rc file can't hold up a request for long.
+	customizationsTimeout = 3 * time.Second
+	// maxCustomizations caps the aliases and the functions listed, each.
+	maxCustomizations = 100
+	// maxAliasValue truncates long alias definitions.
+	maxAliasValue = 100
+	// aliasesMarker and functionsMarker delimit the listing in the shell's
+	// output, which may also hold whatever the rc file prints.
+	aliasesMarker   = "__gx_aliases__"
+	functionsMarker = "__gx_functions__"
+	endMarker       = "__gx_end__"
+)
+
+// listCustomizations are the scripts that print a shell's aliases and then
+// its function names, between the markers.
+var listCustomizations = map[string]string{
+	"bash": "echo " + aliasesMarker + "; alias; echo " + functionsMarker + "; compgen -A function; echo " + endMarker,
+	"zsh":  "echo " + aliasesMarker + "; alias; echo " + functionsMarker + "; print -rl -- ${(k)functions}; echo " + endMarker,
+}
+
+// secretAlias matches alias definitions that look like they carry a
+// credential; they are left out.
+var secretAlias = regexp.MustCompile(`(?i)token|secret|password|passwd|api_?key|bearer|authorization`)
+
+// Alias is a shell alias and the text it expands to.
+type Alias struct {
+	Name  string
+	Value string
+}
+
+// Customizations are the aliases and functions the user's interactive shell
+// defines, from their rc files.
+type Customizations struct {
+	Aliases   []Alias
+	Functions []string
+}
+
+// Empty reports whether no aliases or functions were found.
+func (c Customizations) Empty() bool {
+	return len(c.Aliases) == 0 && len(c.Functions) == 0
+}
+
+// String lists the aliases as alias definitions, then the function names
+// on one line.
+func (c Customizations) String() string {
+	var b strings.Builder
+	for _, a := range c.Aliases {
+		fmt.Fprintf(&b, "alias %s='%s'\n", a.Name, strings.ReplaceAll(a.Value, "'", `'\''`))
+	}
+	if len(c.Functions) > 0 {
+		fmt.Fprintf(&b, "functions: %s\n", strings.Join(c.Functions, ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// LoadCustomizations starts shellPath (the user's $SHELL) as an interactive
+// shell, so it reads its rc file, and lists the aliases and functions it
+// defines. Function names starting with _ (completion helpers) are skipped.
+// bash and zsh are supported.
+func LoadCustomizations(ctx context.Context, shellPath string) (Customizations, error) {
+	script, ok := listCustomizations[filepath.Base(shellPath)]
+	if !ok {
+		return Customizations{}, fmt.Errorf("listing aliases isn't supported for %s (use bash or zsh)", filepath.Base(shellPath))
+	}
+	ctx, cancel := context.WithTimeout(ctx, customizationsTimeout)
+	defer cancel()
+	// No stdin keeps the shell from waiting on the terminal; its job
+	// control warnings go to stderr, which is discarded
+	out, err := exec.CommandContext(ctx, shellPath, "-ic", script).Output()
+	if ctx.Err() != nil {
+		return Customizations{}, fmt.Errorf("%s took longer than %s to start", shellPath, customizationsTimeout)
+	}
+	if err != nil && !strings.Contains(string(out), endMarker) {
+		return Customizations{}, fmt.Errorf("failed to list aliases: %w", err)
+	}
+	return parseCustomizations(string(out)), nil
+}
+
+// parseCustomizations reads the listing between the markers. bash prints
+// aliases as "alias ll='ls -l'", zsh as "ll='ls -l'".
+func parseCustomizations(out string) Customizations {
+	var c Customizations
+	section := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch line {
+		case aliasesMarker, functionsMarker:
+			section = line
+			continue
+		case endMarker:
+			section = ""
+			continue
+		}
+		switch section {
+		case aliasesMarker:
+			name, value, ok := strings.Cut(strings.TrimPrefix(line, "alias "), "=")
+			if !ok || name == "" || len(c.Aliases) >= maxCustomizations || secretAlias.MatchString(value) {
+				continue
+			}
+			value = unquoteAlias(value)
+			if len(value) > maxAliasValue {
+				value = value[:maxAliasValue] + "..."
+			}
+			c.Aliases = append(c.Aliases, Alias{Name: name, Value: value})
+		case functionsMarker:
+			name := strings.TrimSpace(line)
+			if name == "" || strings.HasPrefix(name, "_") || len(c.Functions) >= maxCustomizations {
+				continue
+			}
+			c.Functions = append(c.Functions, name)
+		}
+	}
+	return c
+}
+
+// unquoteAlias undoes the single quoting shells print alias values with,
+// including the close, escape, and reopen sequence for an embedded quote.
+func unquoteAlias(value string) string {
+	if len(value) < 2 || value[0] != '\'' || value[len(value)-1] != '\'' {
+		return value
+	}
+	return strings.ReplaceAll(value[1:len(value)-1], `'\''`, "'")
+}
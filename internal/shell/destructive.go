@@ -0,0 +1,139 @@
+package shell
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Destruction is a part of a command that deletes or overwrites data.
+type Destruction struct {
+	// Reason is a short description, e.g. "rm -r" or "git reset --hard".
+	Reason string
+	// Target is the path, device, or resource affected, or "" when there
+	// is no single one.
+	Target string
+}
+
+// wipePrograms destroy the contents of the device or file they are given.
+var wipePrograms = map[string]bool{"wipefs": true, "shred": true, "blkdiscard": true, "mkswap": true}
+
+// DestructiveAction returns the first part of command that deletes data or
+// overwrites it in a way that can't be undone, such as rm -r, dd to a
+// device, mkfs, or git reset --hard. It reports false when none does.
+func DestructiveAction(command string) (Destruction, bool) {
+	for _, seg := range Segments(Split(command)) {
+		argv := commandArgs(seg)
+		if len(argv) == 0 {
+			continue
+		}
+		if d, ok := destruction(filepath.Base(argv[0].Text), argv[1:]); ok {
+			return d, true
+		}
+	}
+	return Destruction{}, false
+}
+
+// destruction checks one simple command.
+func destruction(name string, args []Word) (Destruction, bool) {
+	ops := operands(args)
+	switch {
+	case name == "rm":
+		recursive := false
+		for _, a := range args {
+			if a.Text == "--recursive" || (isShortFlags(a.Text) && strings.ContainsAny(a.Text, "rR")) {
+				recursive = true
+			}
+		}
+		globbed := false
+		for _, o := range ops {
+			globbed = globbed || o.Glob
+		}
+		if (recursive || globbed) && len(ops) > 0 {
+			reason := "rm"
+			if recursive {
+				reason = "rm -r"
+			}
+			return Destruction{Reason: reason, Target: ops[0].Text}, true
+		}
+	case name == "dd":
+		for _, a := range args {
+			if target, ok := strings.CutPrefix(a.Text, "of="); ok {
+				return Destruction{Reason: "dd", Target: target}, true
+			}
+		}
+	case name == "mkfs" || strings.HasPrefix(name, "mkfs.") || wipePrograms[name]:
+		d := Destruction{Reason: name}
+		if len(ops) > 0 {
+			d.Target = ops[len(ops)-1].Text
+		}
+		return d, true
+	case name == "find":
+		for _, a := range args {
+			if a.Text == "-delete" {
+				d := Destruction{Reason: "find -delete"}
+				if len(ops) > 0 {
+					d.Target = ops[0].Text
+				}
+				return d, true
+			}
+		}
+	case name == "git" && len(ops) > 0:
+		return gitDestruction(ops[0].Text, args)
+	case (name == "docker" || name == "podman") && len(ops) > 1:
+		switch sub := ops[0].Text + " " + ops[1].Text; sub {
+		case "system prune", "volume prune":
+			return Destruction{Reason: name + " " + sub}, true
+		case "volume rm":
+			d := Destruction{Reason: name + " " + sub}
+			if len(ops) > 2 {
+				d.Target = ops[2].Text
+			}
+			return d, true
+		}
+	case name == "kubectl" && len(ops) > 0 && ops[0].Text == "delete":
+		// The kind and name; later operands may be option values
+		var target []string
+		for _, o := range ops[1:min(len(ops), 3)] {
+			target = append(target, o.Text)
+		}
+		return Destruction{Reason: "kubectl delete", Target: strings.Join(target, " ")}, true
+	case name == "terraform" && len(ops) > 0 && ops[0].Text == "destroy":
+		return Destruction{Reason: "terraform destroy"}, true
+	}
+	return Destruction{}, false
+}
+
+// gitDestruction checks git subcommands that throw away work.
+func gitDestruction(sub string, args []Word) (Destruction, bool) {
+	for _, a := range args {
+		switch {
+		case sub == "reset" && a.Text == "--hard":
+			return Destruction{Reason: "git reset --hard"}, true
+		case sub == "clean" && (a.Text == "--force" || (isShortFlags(a.Text) && strings.Contains(a.Text, "f"))):
+			return Destruction{Reason: "git clean -f"}, true
+		case sub == "push" && (a.Text == "--force" || a.Text == "-f" || strings.HasPrefix(a.Text, "--force-with-lease")):
+			return Destruction{Reason: "git push --force"}, true
+		}
+	}
+	return Destruction{}, false
+}
+
+// operands returns the arguments that aren't options. Everything after "--"
+// is an operand.
+func operands(args []Word) []Word {
+	var ops []Word
+	for i, a := range args {
+		if a.Text == "--" {
+			return append(ops, args[i+1:]...)
+		}
+		if !strings.HasPrefix(a.Text, "-") || a.Text == "-" {
+			ops = append(ops, a)
+		}
+	}
+	return ops
+}
+
+// isShortFlags reports whether s is a bundle of short options such as -rf.
+func isShortFlags(s string) bool {
+	return len(s) > 1 && s[0] == '-' && s[1] != '-'
+}
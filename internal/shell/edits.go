@@ -0,0 +1,365 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileEdit is a file that a command modifies: an in-place sed/perl/awk edit,
+// a tee target, or a stdout redirection.
+type FileEdit struct {
+	// Word is the command-line word naming the file. A glob word yields one
+	// FileEdit per matching file.
+	Word Word
+	// Path is the file path with ~ expanded.
+	Path string
+}
+
+// Segments splits words into simple commands at control operators.
+func Segments(words []Word) [][]Word {
+	var segments [][]Word
+	var cur []Word
+	for _, w := range words {
+		if w.IsControl() {
+			if len(cur) > 0 {
+				segments = append(segments, cur)
+			}
+			cur = nil
+			continue
+		}
+		cur = append(cur, w)
+	}
+	if len(cur) > 0 {
+		segments = append(segments, cur)
+	}
+	return segments
+}
+
+// CommandNames returns the program run by each simple command in command,
+// skipping variable assignments and wrappers such as sudo, env, and nice.
+func CommandNames(command string) []string {
+	var names []string
+	for _, seg := range Segments(Split(command)) {
+		if argv := commandArgs(seg); len(argv) > 0 {
+			names = append(names, filepath.Base(argv[0].Text))
+		}
+	}
+	return names
+}
+
+// FileEdits returns the files command writes to. It returns an error when a
+// target cannot be determined before running the command, such as a path built
+// from a variable or a heredoc whose body would be misread.
+func FileEdits(command string) ([]FileEdit, error) {
+	var targets []Word
+	for _, seg := range Segments(Split(command)) {
+		for i, w := range seg {
+			if !w.IsRedirect() {
+				continue
+			}
+			if w.Text == "<<" {
+				return nil, fmt.Errorf("heredocs are not supported")
+			}
+			if i+1 >= len(seg) || seg[i+1].Op || !writesStdout(w.Text, seg[i+1].Text) {
+				continue
+			}
+			targets = append(targets, seg[i+1])
+		}
+
+		argv := commandArgs(seg)
+		if len(argv) == 0 {
+			continue
+		}
+		args := argv[1:]
+		switch filepath.Base(argv[0].Text) {
+		case "sed", "gsed":
+			targets = append(targets, sedFiles(args)...)
+		case "perl":
+			targets = append(targets, perlFiles(args)...)
+		case "awk", "gawk":
+			targets = append(targets, awkFiles(args)...)
+		case "tee", "sponge":
+			for _, a := range args {
+				if !strings.HasPrefix(a.Text, "-") {
+					targets = append(targets, a)
+				}
+			}
+		}
+	}
+
+	var edits []FileEdit
+	for _, w := range targets {
+		if strings.HasPrefix(w.Text, "/dev/") {
+			continue
+		}
+		if w.Dynamic {
+			return nil, fmt.Errorf("target %q depends on shell expansion", w.Text)
+		}
+		path := w.Text
+		if !w.Quoted && (path == "~" || strings.HasPrefix(path, "~/")) {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = home + path[1:]
+			}
+		}
+		if !w.Glob {
+			edits = append(edits, FileEdit{Word: w, Path: path})
+			continue
+		}
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", w.Text, err)
+		}
+		for _, m := range matches {
+			edits = append(edits, FileEdit{Word: w, Path: m})
+		}
+	}
+
+	return edits, nil
+}
+
+// writesStdout reports whether redirection op (with its following word)
+// sends standard output to a file rather than duplicating a descriptor.
+func writesStdout(op, target string) bool {
+	switch op {
+	case ">", ">>", ">|", "1>", "1>>", "1>|", "&>", "&>>":
+		return true
+	case ">&":
+		// ">& file" is bash shorthand for &>; ">&2" duplicates a descriptor
+		return !isDigits(target) && target != "-"
+	}
+	return false
+}
+
+// commandArgs strips redirections, leading assignments, and wrapper commands
+// from a simple command, leaving the program and its arguments.
+func commandArgs(seg []Word) []Word {
+	var argv []Word
+	for i := 0; i < len(seg); i++ {
+		if seg[i].IsRedirect() {
+			i++ // skip the redirection target
+			continue
+		}
+		argv = append(argv, seg[i])
+	}
+
+	for len(argv) > 0 && isAssignment(argv[0]) {
+		argv = argv[1:]
+	}
+
+	for len(argv) > 0 {
+		switch filepath.Base(argv[0].Text) {
+		case "sudo", "doas", "env", "nice", "nohup", "time", "command", "exec", "stdbuf":
+			argv = skipWrapperOptions(argv[1:])
+		default:
+			return argv
+		}
+	}
+	return argv
+}
+
+// skipWrapperOptions drops options and assignments that follow a wrapper
+// command (sudo -u root, env FOO=1, nice -n 10).
+func skipWrapperOptions(args []Word) []Word {
+	for len(args) > 0 {
+		a := args[0].Text
+		switch {
+		case a == "-u" || a == "-g" || a == "-n" || a == "-C" || a == "-o":
+			if len(args) < 2 {
+				return nil
+			}
+			args = args[2:]
+		case strings.HasPrefix(a, "-") || isAssignment(args[0]):
+			args = args[1:]
+		default:
+			return args
+		}
+	}
+	return args
+}
+
+// isAssignment reports whether w is a NAME=value variable assignment.
+func isAssignment(w Word) bool {
+	eq := strings.IndexByte(w.Text, '=')
+	if eq <= 0 {
+		return false
+	}
+	for i := 0; i < eq; i++ {
+		if !isNameChar(w.Text[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sedFiles returns the files a sed invocation edits in place, or nil when
+// it only writes to stdout.
+func sedFiles(args []Word) []Word {
+	inPlace, scriptGiven := false, false
+	var operands []Word
+	for i := 0; i < len(args); i++ {
+		a := args[i].Text
+		switch {
+		case a == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case a == "-i":
+			inPlace = true
+			// BSD sed takes the backup suffix as a separate, often empty, word
+			if i+1 < len(args) && args[i+1].Text == "" {
+				i++
+			}
+		case a == "--expression" || a == "--file":
+			scriptGiven = true
+			i++
+		case strings.HasPrefix(a, "--expression=") || strings.HasPrefix(a, "--file="):
+			scriptGiven = true
+		case strings.HasPrefix(a, "--in-place"):
+			inPlace = true
+		case strings.HasPrefix(a, "--"):
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			// Short option cluster such as -Ei, -i.bak, or -ne
+			for k := 1; k < len(a); k++ {
+				switch a[k] {
+				case 'i':
+					inPlace = true
+					k = len(a) // the rest is a backup suffix
+				case 'e', 'f':
+					scriptGiven = true
+					if k == len(a)-1 {
+						i++
+					}
+					k = len(a)
+				case 'l':
+					if k == len(a)-1 {
+						i++
+					}
+					k = len(a)
+				}
+			}
+		default:
+			operands = append(operands, args[i])
+		}
+	}
+
+	if !inPlace {
+		return nil
+	}
+	if !scriptGiven && len(operands) > 0 {
+		operands = operands[1:]
+	}
+	return operands
+}
+
+// perlFiles returns the files a perl -i invocation edits in place.
+func perlFiles(args []Word) []Word {
+	inPlace, scriptGiven := false, false
+	var operands []Word
+	for i := 0; i < len(args); i++ {
+		a := args[i].Text
+		switch {
+		case a == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			for k := 1; k < len(a); k++ {
+				switch a[k] {
+				case 'i':
+					inPlace = true
+					k = len(a)
+				case 'e', 'E':
+					scriptGiven = true
+					if k == len(a)-1 {
+						i++
+					}
+					k = len(a)
+				case 'M', 'm', 'I':
+					if k == len(a)-1 {
+						i++
+					}
+					k = len(a)
+				case 'F', 'l', '0', 'x', 'C', 'd', 'D':
+					k = len(a)
+				}
+			}
+		default:
+			operands = append(operands, args[i])
+		}
+	}
+
+	if !inPlace {
+		return nil
+	}
+	if !scriptGiven && len(operands) > 0 {
+		operands = operands[1:]
+	}
+	return operands
+}
+
+// awkFiles returns the files a gawk -i inplace invocation edits.
+func awkFiles(args []Word) []Word {
+	inPlace, programGiven := false, false
+	var operands []Word
+	for i := 0; i < len(args); i++ {
+		a := args[i].Text
+		next := ""
+		if i+1 < len(args) {
+			next = args[i+1].Text
+		}
+		switch {
+		case a == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case a == "-i" || a == "--include":
+			inPlace = inPlace || next == "inplace"
+			i++
+		case a == "-iinplace" || a == "--include=inplace":
+			inPlace = true
+		case a == "-f" || a == "--file":
+			programGiven = true
+			i++
+		case a == "-v" || a == "-F" || a == "--assign" || a == "--field-separator":
+			i++
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+		case isAssignment(args[i]):
+		default:
+			operands = append(operands, args[i])
+		}
+	}
+
+	if !inPlace {
+		return nil
+	}
+	if !programGiven && len(operands) > 0 {
+		operands = operands[1:]
+	}
+	return operands
+}
+
+// StripSudo removes sudo and doas (with their options) from the start of each
+// simple command, e.g. to rehearse a command against user-owned copies.
+func StripSudo(command string) string {
+	type span struct{ start, end int }
+	var spans []span
+	for _, seg := range Segments(Split(command)) {
+		i := 0
+		for i < len(seg) && isAssignment(seg[i]) {
+			i++
+		}
+		if i >= len(seg) {
+			continue
+		}
+		if name := filepath.Base(seg[i].Text); name != "sudo" && name != "doas" {
+			continue
+		}
+		if rest := skipWrapperOptions(seg[i+1:]); len(rest) > 0 {
+			spans = append(spans, span{seg[i].Start, rest[0].Start})
+		}
+	}
+
+	for i := len(spans) - 1; i >= 0; i-- {
+		command = command[:spans[i].start] + command[spans[i].end:]
+	}
+	return command
+}
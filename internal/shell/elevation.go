@@ -0,0 +1,148 @@
+package shell
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// rootPrograms are programs that always need root to do anything useful.
+var rootPrograms = map[string]bool{
+	"mount": true, "umount": true, "swapon": true, "swapoff": true,
+	"useradd": true, "userdel": true, "usermod": true, "groupadd": true, "groupdel": true, "groupmod": true,
+	"modprobe": true, "insmod": true, "rmmod": true,
+	"iptables": true, "ip6tables": true, "nft": true, "ufw": true,
+	"fdisk": true, "parted": true, "mkswap": true, "visudo": true, "chroot": true,
+	"update-grub": true, "grub-install": true, "update-initramfs": true, "dpkg-reconfigure": true,
+	"reboot": true, "shutdown": true, "poweroff": true, "halt": true,
+}
+
+// rootSubcommands are programs that need root only for some subcommands.
+var rootSubcommands = map[string]map[string]bool{
+	"apt":       {"install": true, "remove": true, "purge": true, "upgrade": true, "full-upgrade": true, "dist-upgrade": true, "autoremove": true, "update": true},
+	"apt-get":   {"install": true, "remove": true, "purge": true, "upgrade": true, "dist-upgrade": true, "autoremove": true, "update": true},
+	"dnf":       {"install": true, "remove": true, "erase": true, "upgrade": true, "update": true, "downgrade": true, "autoremove": true},
+	"yum":       {"install": true, "remove": true, "erase": true, "upgrade": true, "update": true, "downgrade": true, "autoremove": true},
+	"zypper":    {"install": true, "in": true, "remove": true, "rm": true, "update": true, "up": true, "dist-upgrade": true, "dup": true},
+	"apk":       {"add": true, "del": true, "upgrade": true, "update": true},
+	"snap":      {"install": true, "remove": true, "refresh": true},
+	"systemctl": {"start": true, "stop": true, "restart": true, "reload": true, "enable": true, "disable": true, "mask": true, "unmask": true, "daemon-reload": true},
+	"service":   {"start": true, "stop": true, "restart": true, "reload": true},
+	"dpkg":      {"-i": true, "--install": true, "-r": true, "--remove": true, "-P": true, "--purge": true},
+	"sysctl":    {"-w": true, "--write": true, "-p": true, "--load": true},
+}
+
+// systemDirs are directories only root can normally write to.
+var systemDirs = []string{"/etc/", "/usr/", "/boot/", "/opt/", "/lib/", "/lib64/", "/bin/", "/sbin/", "/var/", "/root/"}
+
+// elevators are wrappers that already run their command as root.
+var elevators = map[string]bool{"sudo": true, "doas": true, "pkexec": true, "run0": true}
+
+// ElevationReason returns a short description of the first part of command
+// that needs root (e.g. "apt-get install", "writes /etc/hosts"), or "" when
+// none does. Commands already run through sudo or doas are not reported.
+func ElevationReason(command string) string {
+	for _, seg := range Segments(Split(command)) {
+		if isElevated(seg) {
+			continue
+		}
+		argv := commandArgs(seg)
+		if len(argv) == 0 {
+			continue
+		}
+		name := filepath.Base(argv[0].Text)
+		if rootPrograms[name] {
+			return name
+		}
+		if subs, ok := rootSubcommands[name]; ok {
+			if reason := subcommandReason(name, subs, argv[1:]); reason != "" {
+				return reason
+			}
+		}
+		if name == "pacman" {
+			for _, a := range argv[1:] {
+				if pacmanChanges(a.Text) {
+					return "pacman " + a.Text
+				}
+			}
+		}
+	}
+
+	// Writes into system directories, via redirection, tee, or sed -i
+	edits, _ := FileEdits(command)
+	for _, e := range edits {
+		if inSystemDir(e.Path) && !editElevated(command, e) {
+			return "writes " + e.Path
+		}
+	}
+	return ""
+}
+
+// subcommandReason returns "name sub" when one of args is a root-only
+// subcommand or flag. systemctl --user never needs root.
+func subcommandReason(name string, subs map[string]bool, args []Word) string {
+	for _, a := range args {
+		if name == "systemctl" && a.Text == "--user" {
+			return ""
+		}
+	}
+	for _, a := range args {
+		if subs[a.Text] {
+			return name + " " + a.Text
+		}
+	}
+	return ""
+}
+
+// pacmanChanges reports whether a pacman operation changes packages: -R and
+// -U always do, -S does unless it only searches or shows info (-Ss, -Si, ...).
+func pacmanChanges(op string) bool {
+	switch {
+	case strings.HasPrefix(op, "-R"), strings.HasPrefix(op, "-U"):
+		return true
+	case strings.HasPrefix(op, "-S"):
+		return !strings.ContainsAny(op[2:], "silg")
+	}
+	return false
+}
+
+// isElevated reports whether a simple command is run through sudo or similar.
+func isElevated(seg []Word) bool {
+	for _, w := range seg {
+		if w.IsRedirect() || isAssignment(w) {
+			continue
+		}
+		return elevators[filepath.Base(w.Text)]
+	}
+	return false
+}
+
+// editElevated reports whether the simple command containing an edit runs
+// through sudo (e.g. `echo x | sudo tee /etc/hosts`). Redirections are
+// performed by the calling shell, so `sudo echo x > /etc/hosts` is not.
+func editElevated(command string, e FileEdit) bool {
+	for _, seg := range Segments(Split(command)) {
+		for i, w := range seg {
+			if w.Start != e.Word.Start {
+				continue
+			}
+			if i > 0 && seg[i-1].IsRedirect() {
+				return false
+			}
+			return isElevated(seg)
+		}
+	}
+	return false
+}
+
+// inSystemDir reports whether path is inside a directory only root can write.
+func inSystemDir(path string) bool {
+	if !filepath.IsAbs(path) || strings.HasPrefix(path, "/var/tmp/") {
+		return false
+	}
+	for _, dir := range systemDirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
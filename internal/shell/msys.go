@@ -0,0 +1,86 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// MSYS describes a POSIX environment on Windows (MSYS2, Git Bash, or
+// Cygwin). gx itself is a native Windows program there, but the user's shell
+// is bash with Unix tools and its own path conventions.
+type MSYS struct {
+	// Name is the platform reported to the model: "msys2", "git-bash", or "cygwin".
+	Name string
+	// DriveRoot prefixes drive letters in paths: "/" (/c/Users) or
+	// "/cygdrive/" (/cygdrive/c/Users).
+	DriveRoot string
+	// Shell is the Windows path of the shell that runs commands, or "" when
+	// it can't be found.
+	Shell string
+}
+
+var (
+	msysOnce sync.Once
+	msys     MSYS
+	msysOK   bool
+)
+
+// DetectMSYS reports whether gx runs inside MSYS2, Git Bash, or Cygwin,
+// from MSYSTEM and `uname -s`. The result is cached.
+func DetectMSYS() (MSYS, bool) {
+	msysOnce.Do(func() {
+		if runtime.GOOS != "windows" {
+			return
+		}
+		uname := ""
+		if out, err := exec.Command("uname", "-s").Output(); err == nil {
+			uname = strings.TrimSpace(string(out))
+		}
+		_, pacmanErr := exec.LookPath("pacman")
+		msys, msysOK = classifyMSYS(os.Getenv("MSYSTEM"), uname, pacmanErr == nil)
+		if msysOK {
+			msys.Shell = msysShell(os.Getenv("SHELL"))
+		}
+	})
+	return msys, msysOK
+}
+
+// classifyMSYS names the environment from MSYSTEM (MINGW64, UCRT64, MSYS,
+// ...), the kernel name uname reports (MINGW64_NT-10.0, CYGWIN_NT-10.0,
+// ...), and whether pacman is installed, which tells MSYS2 from Git Bash:
+// both set MSYSTEM, but Git for Windows ships without a package manager.
+func classifyMSYS(msystem, uname string, hasPacman bool) (MSYS, bool) {
+	switch upper := strings.ToUpper(uname); {
+	case strings.HasPrefix(upper, "CYGWIN"):
+		return MSYS{Name: "cygwin", DriveRoot: "/cygdrive/"}, true
+	case msystem != "" || strings.HasPrefix(upper, "MINGW") || strings.HasPrefix(upper, "MSYS"):
+		if hasPacman {
+			return MSYS{Name: "msys2", DriveRoot: "/"}, true
+		}
+		return MSYS{Name: "git-bash", DriveRoot: "/"}, true
+	}
+	return MSYS{}, false
+}
+
+// msysShell finds the Windows path of the shell named by $SHELL (a POSIX
+// path such as /usr/bin/bash, which Windows can't run directly) on PATH,
+// skipping System32's bash.exe, which starts WSL instead.
+func msysShell(posixShell string) string {
+	name := "bash"
+	if posixShell != "" {
+		name = filepath.Base(filepath.ToSlash(posixShell))
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if strings.Contains(strings.ToLower(dir), `\windows\system32`) {
+			continue
+		}
+		if path, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return path
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package shell
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// syntaxCheckTimeout bounds the POSIX shell's parse of a command.
+const syntaxCheckTimeout = 2 * time.Second
+
+// posixShells are strict POSIX shells that can parse a command with -n,
+// most faithful first.
+var posixShells = [][]string{{"dash"}, {"posh"}, {"busybox", "sh"}}
+
+// bashismCommands are builtins and keywords POSIX sh doesn't have, in
+// command position, with the portable replacement.
+var bashismCommands = map[string]string{
+	"[[":        "[[ ]] (use [ ])",
+	"function":  "the function keyword (use name() { ...; })",
+	"source":    "source (use .)",
+	"declare":   "declare",
+	"typeset":   "typeset",
+	"let":       "let (use $((...)))",
+	"shopt":     "shopt",
+	"mapfile":   "mapfile",
+	"readarray": "readarray",
+	"select":    "select",
+	"pushd":     "pushd (use cd)",
+	"popd":      "popd (use cd)",
+	"coproc":    "coproc",
+}
+
+// bashismOperators are operators Split recognizes that POSIX sh lacks.
+var bashismOperators = map[string]string{
+	"&>":  "&> (use >file 2>&1)",
+	"&>>": "&>> (use >>file 2>&1)",
+	"|&":  "|& (use 2>&1 |)",
+}
+
+// bashismPattern is a construct found in the command text. inDouble is set
+// when it also counts inside double quotes, as parameter expansions do.
+type bashismPattern struct {
+	re       *regexp.Regexp
+	what     string
+	inDouble bool
+}
+
+var bashismPatterns = []bashismPattern{
+	{regexp.MustCompile(`\$'`), "$'...' quoting (use printf)", false},
+	{regexp.MustCompile(`<<<`), "<<< here-strings (use echo ... |)", false},
+	{regexp.MustCompile(`[<>]\(`), "<(...) process substitution", false},
+	{regexp.MustCompile(`(^|[^$(])\(\(`), "(( )) arithmetic commands (use [ $((...)) -ne 0 ])", false},
+	{regexp.MustCompile(`\b[A-Za-z_][A-Za-z0-9_]*\+?=\(`), "arrays", false},
+	{regexp.MustCompile(`\{[^{}\s]*(\.\.|,)[^{}\s]*\}`), "brace expansion (list the words, or use seq)", false},
+	{regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*(,,?|\^\^?)`), "${var,,} and ${var^^} case conversion (use tr)", true},
+	{regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*:( -)?[0-9]`), "${var:offset} substrings (use cut or expr)", true},
+	{regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*//?[^}]`), "${var/a/b} substitution (use sed)", true},
+	{regexp.MustCompile(`\$\{!`), "${!var} indirection", true},
+	{regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\[`), "array subscripts", true},
+	{regexp.MustCompile(`\$(RANDOM|BASH_[A-Z]+|BASHPID|PIPESTATUS|EPOCHSECONDS|SECONDS)\b`), "bash variables ($RANDOM, $PIPESTATUS, ...)", true},
+}
+
+// Bashisms returns the constructs in a command that POSIX sh (dash,
+// BusyBox ash) doesn't support, each with a portable alternative where
+// there is one. Like Split, it is a scanner rather than a full parser.
+func Bashisms(command string) []string {
+	var found []string
+	seen := map[string]bool{}
+	add := func(what string) {
+		if !seen[what] {
+			seen[what] = true
+			found = append(found, what)
+		}
+	}
+
+	words := Split(command)
+	commandPosition := true
+	for i, w := range words {
+		switch {
+		case w.Op:
+			if what, ok := bashismOperators[w.Text]; ok {
+				add(what)
+			}
+			commandPosition = w.IsControl()
+			continue
+		case commandPosition && !w.Quoted:
+			if what, ok := bashismCommands[w.Text]; ok {
+				add(what)
+			}
+			if w.Text == "echo" && i+1 < len(words) && strings.HasPrefix(words[i+1].Text, "-e") {
+				add("echo -e (use printf)")
+			}
+		case w.Text == "==" && !w.Quoted:
+			add("== in tests (use =)")
+		}
+		// Keywords keep the next word in command position (if, then, !, ...)
+		commandPosition = commandPosition && !w.Quoted && isKeyword(w.Text)
+	}
+
+	states, _ := scanQuotes(command)
+	for _, p := range bashismPatterns {
+		for _, m := range p.re.FindAllStringIndex(command, -1) {
+			if s := states[m[0]]; s == unquoted || (p.inDouble && s == doubleQuoted) {
+				add(p.what)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// isKeyword reports whether word is a reserved word or builtin prefix after
+// which a command name follows.
+func isKeyword(word string) bool {
+	switch word {
+	case "if", "then", "else", "elif", "do", "while", "until", "!", "{", "time", "exec", "command", "nohup", "sudo", "env", "xargs":
+		return true
+	}
+	return false
+}
+
+// POSIXSyntaxError parses command with the first strict POSIX shell
+// installed (dash, posh, or BusyBox sh) without running it, and returns its
+// complaint, or "" when it parses or no such shell is installed.
+func POSIXSyntaxError(ctx context.Context, command string) string {
+	for _, argv := range posixShells {
+		if _, err := exec.LookPath(argv[0]); err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, syntaxCheckTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, argv[0], append(argv[1:], "-n")...)
+		cmd.Stdin = strings.NewReader(command)
+		out, err := cmd.CombinedOutput()
+		if err == nil || ctx.Err() != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return ""
+}
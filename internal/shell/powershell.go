@@ -0,0 +1,78 @@
+// Package shell provides shell detection shared by prompt building and command execution.
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PowerShell describes the PowerShell edition gx targets.
+type PowerShell struct {
+	// Executable is "pwsh" (PowerShell 7+) or "powershell" (Windows PowerShell 5.1).
+	Executable string
+	// Major is the major version, or 0 if unknown.
+	Major int
+}
+
+var (
+	powerShellOnce sync.Once
+	powerShell     PowerShell
+)
+
+// DetectPowerShell returns the PowerShell to generate for and execute with.
+// PowerShell 7 (pwsh) is preferred whenever it is installed, even if gx was
+// launched from Windows PowerShell, since modern cmdlets and operators
+// (&&, ||, ?:, ForEach-Object -Parallel) fail under 5.1.
+// The result is computed once per process.
+func DetectPowerShell() PowerShell {
+	powerShellOnce.Do(func() {
+		powerShell = detectPowerShell()
+	})
+	return powerShell
+}
+
+func detectPowerShell() PowerShell {
+	if path, err := exec.LookPath("pwsh"); err == nil {
+		return PowerShell{Executable: "pwsh", Major: pwshMajorVersion(path)}
+	}
+	return PowerShell{Executable: "powershell", Major: 5}
+}
+
+// pwshMajorVersion determines the pwsh major version, first from the
+// install layout (…\PowerShell\7\pwsh.exe or PSModulePath entries such as
+// …\PowerShell\7\Modules), then by asking pwsh itself.
+func pwshMajorVersion(path string) int {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if major, err := strconv.Atoi(filepath.Base(filepath.Dir(path))); err == nil && major > 0 {
+		return major
+	}
+
+	for _, entry := range filepath.SplitList(os.Getenv("PSModulePath")) {
+		parts := strings.FieldsFunc(entry, func(r rune) bool { return r == '\\' || r == '/' })
+		for i := 0; i+1 < len(parts); i++ {
+			if strings.EqualFold(parts[i], "PowerShell") {
+				if major, err := strconv.Atoi(parts[i+1]); err == nil && major > 0 {
+					return major
+				}
+			}
+		}
+	}
+
+	out, err := exec.Command(path, "-NoProfile", "-NonInteractive", "-Command", "$PSVersionTable.PSVersion.Major").Output()
+	if err != nil {
+		return 0
+	}
+	major, _ := strconv.Atoi(strings.TrimSpace(string(out)))
+	return major
+}
+
+// IsPowerShell reports whether name refers to PowerShell (either edition).
+func IsPowerShell(name string) bool {
+	return name == "powershell" || name == "pwsh"
+}
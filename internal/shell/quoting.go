@@ -0,0 +1,200 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// quoteState is the quoting in effect at a byte of a command line.
+type quoteState int
+
+const (
+	unquoted quoteState = iota
+	singleQuoted
+	doubleQuoted
+	// escaped is a byte following a backslash outside single quotes
+	escaped
+)
+
+// scanQuotes returns the quoting state of every byte in command, and a
+// description of the first quote or expansion left open, or "" when all
+// are closed. It follows the same rules as Split. It is a scan, not a
+// parser: $'...' strings, arithmetic, and case patterns are read as plain
+// quotes and parentheses, so it can misjudge them.
+func scanQuotes(command string) ([]quoteState, string) {
+	states := make([]quoteState, len(command))
+	open := ""
+	for i := 0; i < len(command); i++ {
+		switch c := command[i]; {
+		case c == '\\':
+			if i+1 < len(command) {
+				i++
+				states[i] = escaped
+			}
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				end = len(command) - i - 1
+				if open == "" {
+					open = fmt.Sprintf("unterminated single quote at %q", excerpt(command, i))
+				}
+			}
+			for j := i; j <= i+end+1 && j < len(command); j++ {
+				states[j] = singleQuoted
+			}
+			i += end + 1
+		case c == '"':
+			start := i
+			states[i] = doubleQuoted
+			for i++; i < len(command) && command[i] != '"'; i++ {
+				states[i] = doubleQuoted
+				if command[i] == '\\' && i+1 < len(command) {
+					i++
+					states[i] = escaped
+				}
+			}
+			if i < len(command) {
+				states[i] = doubleQuoted
+			} else if open == "" {
+				open = fmt.Sprintf("unterminated double quote at %q", excerpt(command, start))
+			}
+		case c == '#' && (i == 0 || command[i-1] == ' ' || command[i-1] == '\t' || command[i-1] == '\n'):
+			// A comment runs to the end of the line, quotes and all
+			for i < len(command) && command[i] != '\n' {
+				i++
+			}
+		case c == '$' || c == '`':
+			if end := skipExpansion(command, i); end == len(command) && open == "" && expansionOpen(command, i) {
+				open = fmt.Sprintf("unterminated %s at %q", expansionName(command, i), excerpt(command, i))
+			}
+		}
+	}
+	return states, open
+}
+
+// expansionOpen reports whether the $(...), ${...}, or backtick expansion at
+// i is never closed.
+func expansionOpen(command string, i int) bool {
+	if command[i] == '`' {
+		return strings.Count(command[i:], "`")-strings.Count(command[i:], "\\`") < 2
+	}
+	if i+1 >= len(command) || (command[i+1] != '(' && command[i+1] != '{') {
+		return false
+	}
+	closer := ")"
+	if command[i+1] == '{' {
+		closer = "}"
+	}
+	return !strings.HasSuffix(command, closer)
+}
+
+// expansionName describes the expansion at i.
+func expansionName(command string, i int) string {
+	switch {
+	case command[i] == '`':
+		return "backtick substitution"
+	case strings.HasPrefix(command[i:], "${"):
+		return "${...} expansion"
+	}
+	return "$(...) substitution"
+}
+
+// excerpt returns up to 20 bytes of command starting at i, for messages.
+func excerpt(command string, i int) string {
+	s := command[i:]
+	if len(s) > 20 {
+		s = s[:20] + "..."
+	}
+	return s
+}
+
+// UnbalancedQuote describes the first quote or $(...), ${...}, or backtick
+// expansion a POSIX command leaves open, or returns "" when there is none.
+// Heredocs are skipped: their bodies don't follow quoting rules.
+func UnbalancedQuote(command string) string {
+	if strings.Contains(command, "<<") {
+		return ""
+	}
+	_, open := scanQuotes(command)
+	return open
+}
+
+// literalInPrompt matches text the user quoted in a request: 'x', "x", or `x`.
+var literalInPrompt = regexp.MustCompile("'([^']+)'|\"([^\"]+)\"|`([^`]+)`")
+
+// literalCue and literalCueAfter match words around quoted text that say
+// it is meant literally ("the literal string '$HOME'", "'*' verbatim"), as
+// opposed to quoted for emphasis, where "delete the '*.tmp' files" wants
+// the glob expanded.
+var (
+	literalCue      = regexp.MustCompile(`(?i)\b(literal|literally|string|text|verbatim|exact|exactly)\b[^'"` + "`" + `]{0,12}$`)
+	literalCueAfter = regexp.MustCompile(`(?i)^\s*(literally|verbatim|exactly)\b`)
+)
+
+// variableRef matches a shell variable or expansion in a literal.
+var variableRef = regexp.MustCompile(`\$[A-Za-z_{(]`)
+
+// Literals returns the strings a user quoted in a request and called
+// literal ("grep for the literal string '$HOME'") that contain something
+// the shell would expand, a variable or a glob, and so must reach the
+// command escaped.
+func Literals(prompt string) []string {
+	var literals []string
+	for _, m := range literalInPrompt.FindAllStringSubmatchIndex(prompt, -1) {
+		if !literalCue.MatchString(prompt[:m[0]]) && !literalCueAfter.MatchString(prompt[m[1]:]) {
+			continue
+		}
+		literal := strings.Trim(prompt[m[0]:m[1]], "'\"`")
+		if variableRef.MatchString(literal) || strings.ContainsAny(literal, "*?[") {
+			literals = append(literals, literal)
+		}
+	}
+	return literals
+}
+
+// ExpandedLiterals returns the literals (see Literals) that appear in a
+// POSIX command where the shell would expand them: a variable outside
+// single quotes, or a glob character with no quoting at all. A literal the
+// command doesn't contain verbatim is not reported; it may be escaped
+// differently or not used.
+func ExpandedLiterals(command string, literals []string) []string {
+	states, open := scanQuotes(command)
+	if open != "" {
+		return nil
+	}
+	var expanded []string
+	for _, literal := range literals {
+		for offset := 0; ; {
+			i := strings.Index(command[offset:], literal)
+			if i < 0 {
+				break
+			}
+			i += offset
+			if literalExpands(command, states, i, len(literal)) {
+				expanded = append(expanded, literal)
+				break
+			}
+			offset = i + 1
+		}
+	}
+	return expanded
+}
+
+// literalExpands reports whether the shell expands part of the n bytes of
+// command at i.
+func literalExpands(command string, states []quoteState, i, n int) bool {
+	for j := i; j < i+n; j++ {
+		switch command[j] {
+		case '$', '`':
+			if states[j] == unquoted || states[j] == doubleQuoted {
+				return true
+			}
+		case '*', '?', '[':
+			if states[j] == unquoted {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package shell
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SedHasSideEffects reports whether a sed invocation in command can run
+// commands or write files other than its in-place targets: the e, w, and W
+// commands, the s///e and s///w flags, or a script that can't be inspected
+// because it comes from a file or a shell expansion.
+func SedHasSideEffects(command string) bool {
+	for _, seg := range Segments(Split(command)) {
+		argv := commandArgs(seg)
+		if len(argv) == 0 {
+			continue
+		}
+		switch filepath.Base(argv[0].Text) {
+		case "sed", "gsed":
+		default:
+			continue
+		}
+		scripts, ok := sedScripts(argv[1:])
+		if !ok {
+			return true
+		}
+		for _, s := range scripts {
+			if !sedScriptSafe(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sedScripts returns the script words of a sed invocation, following the
+// same option rules as sedFiles. ok is false when a script is read from a
+// file or built by shell expansion.
+func sedScripts(args []Word) (scripts []string, ok bool) {
+	add := func(w Word) bool {
+		if w.Dynamic {
+			return false
+		}
+		scripts = append(scripts, w.Text)
+		return true
+	}
+
+	var operands []Word
+	scriptGiven := false
+	for i := 0; i < len(args); i++ {
+		a := args[i].Text
+		switch {
+		case a == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case a == "-i":
+			if i+1 < len(args) && args[i+1].Text == "" {
+				i++
+			}
+		case a == "--file" || strings.HasPrefix(a, "--file="):
+			return nil, false
+		case a == "--expression":
+			scriptGiven = true
+			if i+1 < len(args) && !add(args[i+1]) {
+				return nil, false
+			}
+			i++
+		case strings.HasPrefix(a, "--expression="):
+			scriptGiven = true
+			if args[i].Dynamic {
+				return nil, false
+			}
+			scripts = append(scripts, strings.TrimPrefix(a, "--expression="))
+		case strings.HasPrefix(a, "--"):
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			for k := 1; k < len(a); k++ {
+				switch a[k] {
+				case 'i':
+					k = len(a)
+				case 'f':
+					return nil, false
+				case 'e':
+					scriptGiven = true
+					if k < len(a)-1 {
+						if args[i].Dynamic {
+							return nil, false
+						}
+						scripts = append(scripts, a[k+1:])
+					} else if i+1 < len(args) {
+						if !add(args[i+1]) {
+							return nil, false
+						}
+						i++
+					}
+					k = len(a)
+				case 'l':
+					if k == len(a)-1 {
+						i++
+					}
+					k = len(a)
+				}
+			}
+		default:
+			operands = append(operands, args[i])
+		}
+	}
+
+	if !scriptGiven && len(operands) > 0 && !add(operands[0]) {
+		return nil, false
+	}
+	return scripts, true
+}
+
+// sedScriptSafe scans a sed script for commands and s flags that execute
+// programs or write files. It errs on the side of calling a script unsafe.
+func sedScriptSafe(script string) bool {
+	n := len(script)
+	// toEnd skips an argument that runs to the end of the line
+	toEnd := func(i int) int {
+		for i < n && script[i] != '\n' {
+			i++
+		}
+		return i
+	}
+	// toSeparator skips a label or number that ends at ; or a newline
+	toSeparator := func(i int) int {
+		for i < n && script[i] != ';' && script[i] != '\n' && script[i] != '}' {
+			i++
+		}
+		return i
+	}
+
+	for i := 0; i < n; {
+		c := script[i]
+		switch {
+		case strings.IndexByte(" \t\n;{}!,", c) >= 0,
+			c >= '0' && c <= '9', c == '$', c == '~', c == '+':
+			i++
+		case c == '/':
+			i = skipDelimited(script, i+1, '/')
+			for i < n && (script[i] == 'I' || script[i] == 'M') {
+				i++
+			}
+		case c == '\\':
+			if i+1 >= n {
+				return false
+			}
+			i = skipDelimited(script, i+2, script[i+1])
+		case c == 'e' || c == 'w' || c == 'W':
+			return false
+		case c == 's' || c == 'y':
+			if i+1 >= n {
+				return false
+			}
+			d := script[i+1]
+			i = skipDelimited(script, i+2, d)
+			i = skipDelimited(script, i, d)
+			if c == 'y' {
+				continue
+			}
+			for ; i < n && strings.IndexByte(";\n}", script[i]) < 0; i++ {
+				if script[i] == 'e' || script[i] == 'w' {
+					return false
+				}
+			}
+		case c == '#' || strings.IndexByte("aicrR:", c) >= 0:
+			i = toEnd(i + 1)
+		case strings.IndexByte("btTqQlLv", c) >= 0:
+			i = toSeparator(i + 1)
+		default:
+			i++
+		}
+	}
+	return true
+}
+
+// skipDelimited returns the index just past the next unescaped delim at or
+// after i, or len(s) when there is none.
+func skipDelimited(s string, i int, delim byte) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case delim:
+			return i + 1
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,122 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionTimeout bounds `<shell> --version`.
+const versionTimeout = 2 * time.Second
+
+// versionNumber matches the first dotted version in --version output:
+// "GNU bash, version 3.2.57(1)-release", "zsh 5.9 (x86_64-apple-darwin23.0)",
+// "fish, version 3.6.1".
+var versionNumber = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// versionedShells are the shells whose --version is parsed.
+var versionedShells = map[string]bool{"bash": true, "zsh": true, "fish": true}
+
+// Version is the version of a bash, zsh, or fish.
+type Version struct {
+	Name  string
+	Major int
+	Minor int
+	// Full is the version as printed, e.g. "3.2.57"
+	Full string
+}
+
+var versions sync.Map // shell name -> Version
+
+// DetectVersion returns the version of the named shell (bash, zsh, or fish),
+// running $SHELL when it is that shell and the one on PATH otherwise. The
+// result is cached per process.
+func DetectVersion(name string) (Version, bool) {
+	if !versionedShells[name] {
+		return Version{}, false
+	}
+	if v, ok := versions.Load(name); ok {
+		return v.(Version), v.(Version).Full != ""
+	}
+	v := detectVersion(name)
+	versions.Store(name, v)
+	return v, v.Full != ""
+}
+
+func detectVersion(name string) Version {
+	path := os.Getenv("SHELL")
+	if filepath.Base(path) != name {
+		path = name
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil && path != name {
+		// $SHELL may be a POSIX path Windows can't run (MSYS2, Cygwin)
+		out, err = exec.CommandContext(ctx, name, "--version").Output()
+	}
+	if err != nil {
+		return Version{Name: name}
+	}
+	return parseVersion(name, string(out))
+}
+
+// parseVersion reads the first version number in --version output.
+func parseVersion(name, out string) Version {
+	m := versionNumber.FindStringSubmatch(out)
+	if m == nil {
+		return Version{Name: name}
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return Version{Name: name, Major: major, Minor: minor, Full: strings.TrimSuffix(m[0], ".")}
+}
+
+// before reports whether v is older than major.minor.
+func (v Version) before(major, minor int) bool {
+	return v.Major < major || (v.Major == major && v.Minor < minor)
+}
+
+// Constraints describes what commands for this shell version must avoid or
+// keep in mind, to follow the name and version in the prompt, or "" when
+// there is nothing notable. It matters most for macOS, which still ships
+// bash 3.2.
+func (v Version) Constraints() string {
+	var missing []string
+	switch v.Name {
+	case "bash":
+		if v.before(4, 0) {
+			missing = append(missing, "associative arrays", "mapfile/readarray", "${var,,}/${var^^}", "globstar", "|&", "&>>", "coproc")
+		}
+		if v.before(4, 3) {
+			missing = append(missing, "namerefs", "negative array indexes")
+		}
+		if v.before(4, 4) {
+			missing = append(missing, "${var@Q}")
+		}
+		if v.before(5, 0) {
+			missing = append(missing, "$EPOCHSECONDS")
+		}
+		if len(missing) == 0 {
+			return ""
+		}
+		return "no " + strings.Join(missing, ", ") + "; use while-read loops, tr, and find instead"
+	case "zsh":
+		return "arrays are 1-indexed and unquoted $var is not word-split; unmatched globs are errors unless quoted"
+	case "fish":
+		notes := "not POSIX: use set for variables, (cmd) for substitution, and no heredocs"
+		if v.before(3, 0) {
+			notes += "; no && or ||, use '; and' and '; or'"
+		} else if v.before(3, 4) {
+			notes += "; no $(cmd)"
+		}
+		return notes
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package shell
+
+import "strings"
+
+// Word is a shell word or operator from a command line, with its byte span
+// in the original command so callers can rewrite individual words.
+type Word struct {
+	// Text is the word with quotes and escapes removed.
+	Text string
+	// Start and End are the byte offsets of the word in the command.
+	Start, End int
+	// Op marks control and redirection operators (|, &&, ;, >, 2>, ...).
+	Op bool
+	// Quoted is set when any part of the word was quoted or escaped.
+	Quoted bool
+	// Dynamic is set when the word contains $ expansions or backticks,
+	// so its value is only known at run time.
+	Dynamic bool
+	// Glob is set when the word contains unquoted *, ? or [.
+	Glob bool
+}
+
+// operators is ordered longest first so the longest match wins.
+var operators = []string{
+	"&>>", "&&", "&>", "||", "|&", ";;", ">>", ">|", ">&", "<<", "<&", "<>",
+	"|", "&", ";", "(", ")", ">", "<", "\n",
+}
+
+// Split splits a POSIX shell command line into words and operators.
+// It understands quoting, escapes, $(...) / ${...} / backtick expansions,
+// comments, and fd-prefixed redirections such as 2> and 2>&1. It is not a
+// full parser: heredoc bodies and compound commands come back as plain words.
+func Split(command string) []Word {
+	var words []Word
+	var cur *Word
+	var sb strings.Builder
+
+	flush := func(end int) {
+		if cur == nil {
+			return
+		}
+		cur.Text = sb.String()
+		cur.End = end
+		words = append(words, *cur)
+		cur = nil
+		sb.Reset()
+	}
+	begin := func(i int) {
+		if cur == nil {
+			cur = &Word{Start: i}
+		}
+	}
+
+	for i := 0; i < len(command); {
+		c := command[i]
+		switch {
+		case c == ' ' || c == '\t':
+			flush(i)
+			i++
+		case c == '#' && cur == nil:
+			for i < len(command) && command[i] != '\n' {
+				i++
+			}
+		case c == '\\':
+			begin(i)
+			cur.Quoted = true
+			if i+1 < len(command) && command[i+1] != '\n' {
+				sb.WriteByte(command[i+1])
+			}
+			i += 2
+		case c == '\'':
+			begin(i)
+			cur.Quoted = true
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				sb.WriteString(command[i+1:])
+				i = len(command)
+				break
+			}
+			sb.WriteString(command[i+1 : i+1+end])
+			i += end + 2
+		case c == '"':
+			begin(i)
+			cur.Quoted = true
+			i++
+			for i < len(command) && command[i] != '"' {
+				switch command[i] {
+				case '\\':
+					if i+1 < len(command) && strings.IndexByte("$`\"\\\n", command[i+1]) >= 0 {
+						if command[i+1] != '\n' {
+							sb.WriteByte(command[i+1])
+						}
+						i += 2
+						continue
+					}
+				case '$', '`':
+					cur.Dynamic = true
+				}
+				sb.WriteByte(command[i])
+				i++
+			}
+			i++ // closing quote
+		case c == '$' || c == '`':
+			begin(i)
+			cur.Dynamic = true
+			end := skipExpansion(command, i)
+			sb.WriteString(command[i:end])
+			i = end
+		default:
+			if op := matchOperator(command[i:]); op != "" {
+				// Digits directly before a redirection are its fd (2>, 2>&1)
+				if cur != nil && !cur.Quoted && !cur.Dynamic && (op[0] == '>' || op[0] == '<') &&
+					cur.Start+sb.Len() == i && isDigits(sb.String()) {
+					start := cur.Start
+					cur = nil
+					sb.Reset()
+					words = append(words, Word{Text: command[start:i] + op, Start: start, End: i + len(op), Op: true})
+				} else {
+					flush(i)
+					words = append(words, Word{Text: op, Start: i, End: i + len(op), Op: true})
+				}
+				i += len(op)
+				continue
+			}
+			begin(i)
+			if c == '*' || c == '?' || c == '[' {
+				cur.Glob = true
+			}
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	flush(len(command))
+
+	return words
+}
+
+// matchOperator returns the operator at the start of s, if any.
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// skipExpansion returns the offset just past the $ or backtick expansion at i.
+func skipExpansion(command string, i int) int {
+	if command[i] == '`' {
+		for j := i + 1; j < len(command); j++ {
+			if command[j] == '\\' {
+				j++
+				continue
+			}
+			if command[j] == '`' {
+				return j + 1
+			}
+		}
+		return len(command)
+	}
+
+	if i+1 >= len(command) {
+		return i + 1
+	}
+	switch open := command[i+1]; open {
+	case '(', '{':
+		closer := byte(')')
+		if open == '{' {
+			closer = '}'
+		}
+		depth := 0
+		for j := i + 1; j < len(command); j++ {
+			switch command[j] {
+			case open:
+				depth++
+			case closer:
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(command)
+	}
+
+	j := i + 1
+	if strings.IndexByte("@*#?$!-0123456789", command[j]) >= 0 {
+		return j + 1
+	}
+	for j < len(command) && isNameChar(command[j]) {
+		j++
+	}
+	return j
+}
+
+// IsControl reports whether w separates simple commands (|, &&, ;, ...).
+func (w Word) IsControl() bool {
+	if !w.Op {
+		return false
+	}
+	switch w.Text {
+	case "|", "||", "|&", "&&", "&", ";", ";;", "(", ")", "\n":
+		return true
+	}
+	return false
+}
+
+// IsRedirect reports whether w is a redirection operator (>, 2>>, <, ...).
+func (w Word) IsRedirect() bool {
+	return w.Op && !w.IsControl()
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
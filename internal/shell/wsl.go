@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// wslConf is where WSL reads the drive mount root ([automount] root).
+const wslConf = "/etc/wsl.conf"
+
+var (
+	// quotedWindowsPath matches a quoted drive path, which may contain
+	// spaces: "C:\Program Files\app".
+	quotedWindowsPath = regexp.MustCompile(`(["'])([A-Za-z]):[\\/]([^"']*)(["'])`)
+	// windowsPath matches an unquoted drive path, which ends at whitespace.
+	windowsPath = regexp.MustCompile(`(^|[\s(=])([A-Za-z]):[\\/]([^\s"']*)`)
+	// wslSharePath matches a path into a distro from Windows:
+	// \\wsl$\Ubuntu\home\me or \\wsl.localhost\Ubuntu\home\me.
+	wslSharePath = regexp.MustCompile(`(?i)\\\\wsl(?:\$|\.localhost)\\[^\\\s"']+((?:\\[^\s"']*)?)`)
+)
+
+// IsWSL reports whether gx runs under WSL, judging by the kernel release.
+func IsWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	data, err := exec.Command("uname", "-r").Output()
+	if err != nil {
+		return false
+	}
+	release := strings.ToLower(string(data))
+	return strings.Contains(release, "microsoft") || strings.Contains(release, "wsl")
+}
+
+// WSLMountRoot returns the directory Windows drives are mounted under in
+// WSL, "/mnt/" unless /etc/wsl.conf sets [automount] root.
+func WSLMountRoot() string {
+	root := "/mnt/"
+	f, err := os.Open(wslConf)
+	if err != nil {
+		return root
+	}
+	defer f.Close()
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && section == "automount" && strings.TrimSpace(key) == "root" {
+			if value = strings.Trim(strings.TrimSpace(value), `"`); value != "" {
+				root = strings.TrimSuffix(value, "/") + "/"
+			}
+		}
+	}
+	return root
+}
+
+// WSLPaths rewrites the Windows paths in text (C:\Users\me, D:/data, or
+// \\wsl$\Ubuntu\home\me) as the paths WSL sees, the way `wslpath -u` would:
+// /mnt/c/Users/me, /mnt/d/data, /home/me. mountRoot is WSLMountRoot().
+// Trailing punctuation of an unquoted path is kept out of it.
+func WSLPaths(text, mountRoot string) string {
+	text = wslSharePath.ReplaceAllStringFunc(text, func(m string) string {
+		rest := wslSharePath.FindStringSubmatch(m)[1]
+		if rest == "" {
+			return "/"
+		}
+		return strings.ReplaceAll(rest, `\`, "/")
+	})
+	text = quotedWindowsPath.ReplaceAllStringFunc(text, func(m string) string {
+		g := quotedWindowsPath.FindStringSubmatch(m)
+		if g[1] != g[4] {
+			return m
+		}
+		return g[1] + drivePath(mountRoot, g[2], g[3]) + g[4]
+	})
+	return windowsPath.ReplaceAllStringFunc(text, func(m string) string {
+		g := windowsPath.FindStringSubmatch(m)
+		path := strings.TrimRight(g[3], ".,;:!?)")
+		return g[1] + drivePath(mountRoot, g[2], path) + g[3][len(path):]
+	})
+}
+
+// drivePath joins a drive letter and the rest of a Windows path under the
+// mount root.
+func drivePath(mountRoot, drive, rest string) string {
+	return mountRoot + strings.ToLower(drive) + "/" + strings.ReplaceAll(rest, `\`, "/")
+}
@@ -0,0 +1,112 @@
+package suggest
+
+// snippet is a common request with its answer on each platform. Phrasings
+// are matched like history prompts; a platform without a command is skipped.
+type snippet struct {
+	phrases []string
+	// posix is used on Linux and macOS unless linux or darwin overrides it
+	posix   string
+	linux   string
+	darwin  string
+	windows string
+}
+
+// command returns the snippet's command for goos, or "" if it has none.
+func (s snippet) command(goos string) string {
+	switch goos {
+	case "windows":
+		return s.windows
+	case "linux":
+		if s.linux != "" {
+			return s.linux
+		}
+	case "darwin":
+		if s.darwin != "" {
+			return s.darwin
+		}
+	}
+	return s.posix
+}
+
+// snippets are everyday requests whose answer doesn't depend on the
+// directory or on files the model would need to look at.
+var snippets = []snippet{
+	{
+		phrases: []string{"disk usage", "free disk space", "disk space left", "how full are my disks"},
+		posix:   "df -h",
+		windows: "Get-PSDrive -PSProvider FileSystem",
+	},
+	{
+		phrases: []string{"listening ports", "open ports", "which ports are listening"},
+		linux:   "ss -tlnp",
+		darwin:  "lsof -nP -iTCP -sTCP:LISTEN",
+		windows: "Get-NetTCPConnection -State Listen",
+	},
+	{
+		phrases: []string{"memory usage", "free memory", "how much memory is free"},
+		linux:   "free -h",
+		darwin:  "vm_stat",
+		windows: "Get-CimInstance Win32_OperatingSystem | Select-Object FreePhysicalMemory, TotalVisibleMemorySize",
+	},
+	{
+		phrases: []string{"ip address", "local ip address", "my ip address"},
+		linux:   "ip -brief address",
+		darwin:  "ipconfig getifaddr en0",
+		windows: "Get-NetIPAddress -AddressFamily IPv4",
+	},
+	{
+		phrases: []string{"public ip address", "external ip address", "my public ip"},
+		posix:   "curl -s https://ifconfig.me",
+		windows: "Invoke-RestMethod https://ifconfig.me",
+	},
+	{
+		phrases: []string{"os version", "operating system version", "which os version"},
+		linux:   "cat /etc/os-release",
+		darwin:  "sw_vers",
+		windows: "Get-ComputerInfo -Property OsName, OsVersion",
+	},
+	{
+		phrases: []string{"kernel version", "which kernel"},
+		posix:   "uname -r",
+	},
+	{
+		phrases: []string{"uptime", "how long has the system been up", "system uptime"},
+		posix:   "uptime",
+		windows: "(Get-Date) - (Get-CimInstance Win32_OperatingSystem).LastBootUpTime",
+	},
+	{
+		phrases: []string{"current directory", "where am i", "working directory"},
+		posix:   "pwd",
+		windows: "Get-Location",
+	},
+	{
+		phrases: []string{"size of current directory", "directory size", "how big is this directory"},
+		posix:   "du -sh .",
+		windows: "(Get-ChildItem -Recurse -File | Measure-Object -Property Length -Sum).Sum / 1MB",
+	},
+	{
+		phrases: []string{"largest files", "biggest files", "largest files here"},
+		posix:   "du -ah . | sort -rh | head -n 20",
+		windows: "Get-ChildItem -Recurse -File | Sort-Object Length -Descending | Select-Object -First 20 FullName, Length",
+	},
+	{
+		phrases: []string{"current git branch", "which git branch am i on", "git branch name"},
+		posix:   "git branch --show-current",
+		windows: "git branch --show-current",
+	},
+	{
+		phrases: []string{"recent git commits", "last 10 git commits", "git log short"},
+		posix:   "git log --oneline -n 10",
+		windows: "git log --oneline -n 10",
+	},
+	{
+		phrases: []string{"undo last git commit", "undo last commit keep changes"},
+		posix:   "git reset --soft HEAD~1",
+		windows: "git reset --soft HEAD~1",
+	},
+	{
+		phrases: []string{"running docker containers", "list docker containers", "docker containers running"},
+		posix:   "docker ps",
+		windows: "docker ps",
+	},
+}
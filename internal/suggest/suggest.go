@@ -0,0 +1,129 @@
+// Package suggest finds a command for a prompt locally, from history or a
+// built-in snippet set, so gx can offer it before the model answers.
+package suggest
+
+import (
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/nealhardesty/gx/internal/gemini"
+	"github.com/nealhardesty/gx/internal/history"
+)
+
+// MinScore is the similarity a prompt needs to a history prompt or snippet
+// phrasing for the match to be offered. Matches are offered in place of a
+// model answer, so only near-repeats qualify.
+const MinScore = 0.75
+
+// Sources of a Match.
+const (
+	SourceHistory = "history"
+	SourceSnippet = "snippet"
+)
+
+// Match is a locally found command.
+type Match struct {
+	Command string
+	// Risk is the model's rating of a history command; snippets are all low.
+	Risk string
+	// Source is SourceHistory or SourceSnippet.
+	Source string
+	// Prompt is the history prompt or snippet phrasing that matched.
+	Prompt string
+	Score  float64
+}
+
+// Find returns the best match for prompt among the history entries (newest
+// last, as history.Manager.Load returns them) and the snippets for this
+// platform, if one scores at least MinScore. History wins ties, and newer
+// entries win over older ones, since they reflect what the user ran last.
+func Find(prompt string, entries []history.Entry) (Match, bool) {
+	words := tokenize(prompt)
+	if len(words) == 0 {
+		return Match{}, false
+	}
+
+	var best Match
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !usable(e.Response) {
+			continue
+		}
+		if score := similarity(words, tokenize(e.Prompt)); score > best.Score {
+			best = Match{Command: e.Response, Risk: e.Risk, Source: SourceHistory, Prompt: e.Prompt, Score: score}
+		}
+	}
+	for _, s := range snippets {
+		command := s.command(runtime.GOOS)
+		if command == "" {
+			continue
+		}
+		for _, phrase := range s.phrases {
+			if score := similarity(words, tokenize(phrase)); score > best.Score {
+				best = Match{Command: command, Risk: gemini.RiskLow, Source: SourceSnippet, Prompt: phrase, Score: score}
+			}
+		}
+	}
+	return best, best.Score >= MinScore
+}
+
+// usable reports whether a history response is a command worth offering
+// again, rather than empty or only comments (a declined request).
+func usable(response string) bool {
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// stopWords carry no meaning for matching a request to a command.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "me": true, "my": true, "i": true,
+	"please": true, "can": true, "you": true, "how": true, "do": true,
+	"what": true, "is": true, "are": true, "of": true, "to": true, "for": true,
+	"in": true, "on": true, "this": true, "that": true, "with": true,
+	"and": true, "it": true, "all": true, "get": true, "give": true,
+	// Request verbs: "show disk usage" and "disk usage" ask for the same thing
+	"show": true, "list": true, "display": true, "check": true, "see": true,
+	"tell": true, "print": true,
+}
+
+// tokenize lowercases s and returns its distinct words, without stop words
+// or single letters, and with a plural s dropped so "files" and "file" match.
+func tokenize(s string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		// Single letters are mostly left from contractions ("what's")
+		if stopWords[w] || (len(w) == 1 && unicode.IsLetter(rune(w[0]))) {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = strings.TrimSuffix(w, "s")
+		}
+		words[w] = true
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two word sets: shared words over all
+// words. Reordered or lightly reworded prompts score high; prompts that add
+// a filter or a path ("... in /var/log") don't, since the command would
+// differ.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
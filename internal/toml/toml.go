@@ -0,0 +1,387 @@
+// Package toml decodes the subset of TOML that config files use.
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse decodes the parts of TOML that config files use: tables, arrays of
+// tables, dotted and quoted keys, strings (basic, literal, multi-line),
+// numbers, booleans, arrays, and inline tables. Dates are kept as strings.
+func Parse(input string) (map[string]any, error) {
+	p := &tomlParser{s: input, line: 1}
+	root := map[string]any{}
+	current := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		var err error
+		if strings.HasPrefix(p.rest(), "[[") {
+			current, err = p.tableHeader(root, true)
+		} else if p.peek() == '[' {
+			current, err = p.tableHeader(root, false)
+		} else {
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+	}
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) eof() bool    { return p.pos >= len(p.s) }
+func (p *tomlParser) rest() string { return p.s[p.pos:] }
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *tomlParser) advance(n int) {
+	p.line += strings.Count(p.s[p.pos:p.pos+n], "\n")
+	p.pos += n
+}
+
+// skipSpace skips spaces and tabs on the current line.
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.advance(1)
+	}
+}
+
+// skipBlank skips whitespace, newlines, and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.advance(1)
+		case '#':
+			end := strings.IndexByte(p.rest(), '\n')
+			if end < 0 {
+				end = len(p.rest())
+			}
+			p.advance(end)
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine expects only a comment or whitespace before the next line.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		p.skipBlank()
+		return nil
+	}
+	if !p.eof() && p.peek() != '\n' && p.peek() != '\r' {
+		return fmt.Errorf("unexpected %q after value", p.peek())
+	}
+	return nil
+}
+
+// tableHeader parses [a.b] or [[a.b]] and returns the table it names.
+func (p *tomlParser) tableHeader(root map[string]any, array bool) (map[string]any, error) {
+	open, close := "[", "]"
+	if array {
+		open, close = "[[", "]]"
+	}
+	p.advance(len(open))
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !strings.HasPrefix(p.rest(), close) {
+		return nil, fmt.Errorf("expected %s", close)
+	}
+	p.advance(len(close))
+	if err := p.endOfLine(); err != nil {
+		return nil, err
+	}
+
+	parent, err := descend(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	if array {
+		table := map[string]any{}
+		list, _ := parent[last].([]any)
+		parent[last] = append(list, table)
+		return table, nil
+	}
+	return descend(parent, []string{last})
+}
+
+// descend walks to the table at keys, creating tables as needed. A key
+// holding an array of tables continues into its last element.
+func descend(table map[string]any, keys []string) (map[string]any, error) {
+	for _, k := range keys {
+		switch v := table[k].(type) {
+		case nil:
+			next := map[string]any{}
+			table[k] = next
+			table = next
+		case map[string]any:
+			table = v
+		case []any:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("key %q is not a table", k)
+			}
+			last, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key %q is not a table", k)
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("key %q is not a table", k)
+		}
+	}
+	return table, nil
+}
+
+// keyValue parses key = value into table.
+func (p *tomlParser) keyValue(table map[string]any) error {
+	if err := p.assign(table); err != nil {
+		return err
+	}
+	return p.endOfLine()
+}
+
+// assign parses key = value without the line ending, for inline tables too.
+func (p *tomlParser) assign(table map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() != '=' {
+		return fmt.Errorf("expected = after key %q", strings.Join(keys, "."))
+	}
+	p.advance(1)
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	parent[keys[len(keys)-1]] = value
+	return nil
+}
+
+// key parses a dotted key of bare and quoted parts.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '"', '\'':
+			k, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.advance(1)
+			}
+			if p.pos == start {
+				return nil, fmt.Errorf("expected a key")
+			}
+			keys = append(keys, p.s[start:p.pos])
+		}
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.advance(1)
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses any TOML value.
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(",]}#\r\n", rune(p.peek())) {
+		p.advance(1)
+	}
+	raw := strings.TrimSpace(p.s[start:p.pos])
+	switch raw {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "":
+		return nil, fmt.Errorf("expected a value")
+	}
+	number := strings.ReplaceAll(raw, "_", "")
+	if n, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return f, nil
+	}
+	// Dates, times, inf, and nan
+	return raw, nil
+}
+
+// str parses a basic, literal, or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	quote := p.s[p.pos : p.pos+1]
+	if strings.HasPrefix(p.rest(), quote+quote+quote) {
+		delim := quote + quote + quote
+		p.advance(3)
+		end := strings.Index(p.rest(), delim)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated multi-line string")
+		}
+		body := strings.TrimPrefix(strings.TrimPrefix(p.rest()[:end], "\r"), "\n")
+		p.advance(end + 3)
+		if quote == "'" {
+			return body, nil
+		}
+		return unescapeTOML(body)
+	}
+
+	p.advance(1)
+	for i := p.pos; i < len(p.s) && p.s[i] != '\n'; i++ {
+		if quote == `"` && p.s[i] == '\\' {
+			i++
+			continue
+		}
+		if p.s[i:i+1] == quote {
+			body := p.s[p.pos:i]
+			p.advance(i + 1 - p.pos)
+			if quote == "'" {
+				return body, nil
+			}
+			return unescapeTOML(body)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// unescapeTOML expands escapes in a basic string, including line-ending
+// backslashes in multi-line strings.
+func unescapeTOML(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'u', 'U':
+			size := 4
+			if c == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			i += size
+		case ' ', '\t', '\r', '\n':
+			// Line-ending backslash: trim up to the next non-whitespace
+			for i+1 < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i+1])) {
+				i++
+			}
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+	return b.String(), nil
+}
+
+// array parses [v, v, ...], which may span lines and contain comments.
+func (p *tomlParser) array() ([]any, error) {
+	p.advance(1)
+	list := []any{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.advance(1)
+			return list, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected , or ] in array")
+		}
+	}
+}
+
+// inlineTable parses {k = v, ...}.
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.advance(1)
+	table := map[string]any{}
+	for {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.advance(1)
+			return table, nil
+		}
+		if err := p.assign(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case '}':
+		default:
+			return nil, fmt.Errorf("expected , or } in inline table")
+		}
+	}
+}
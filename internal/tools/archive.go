@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// maxArchiveEntries caps how many members archive_list reports.
+const maxArchiveEntries = 500
+
+// Magic numbers for the formats archive_list recognizes.
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicZip   = []byte("PK\x03\x04")
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// archiveEntry is one member of an archive.
+type archiveEntry struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// executeArchiveList lists the members of a tar (optionally gzip, bzip2, xz,
+// or zstd compressed), zip, or gzip file without extracting it. The format
+// is detected from the file's contents, not its name.
+func executeArchiveList(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("failed to read archive: %w", err)
+	}
+	header = header[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	var entries []archiveEntry
+	var format string
+	switch {
+	case bytes.HasPrefix(header, magicZip):
+		format = "zip"
+		entries, err = listZip(f)
+	case bytes.HasPrefix(header, magicGzip):
+		format, entries, err = listGzip(f)
+	case bytes.HasPrefix(header, magicBzip2):
+		format = "tar.bz2"
+		entries, err = listTar(bzip2.NewReader(f))
+	case bytes.HasPrefix(header, magicXz), bytes.HasPrefix(header, magicZstd):
+		// No decompressor in the standard library; tar knows how
+		return listWithTar(path)
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		format = "tar"
+		entries, err = listTar(f)
+	default:
+		return "", fmt.Errorf("%s is not a recognized archive (tar, tar.gz, tar.bz2, tar.xz, tar.zst, zip, or gz)", path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s archive: %w", format, err)
+	}
+	return formatArchiveEntries(format, entries), nil
+}
+
+// listGzip lists a gzipped tarball, or reports the single file inside a
+// plain .gz by its original name.
+func listGzip(f *os.File) (string, []archiveEntry, error) {
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "gzip", nil, err
+	}
+	defer gz.Close()
+
+	br := bufio.NewReader(gz)
+	peek, _ := br.Peek(262)
+	if len(peek) >= 262 && string(peek[257:262]) == "ustar" {
+		entries, err := listTar(br)
+		return "tar.gz", entries, err
+	}
+
+	// A single compressed file: its size is only known by decompressing it
+	size, err := io.Copy(io.Discard, br)
+	name := gz.Name
+	if name == "" {
+		name = strings.TrimSuffix(f.Name(), ".gz")
+	}
+	return "gzip", []archiveEntry{{name: name, size: size, mode: 0o644, modTime: gz.ModTime}}, err
+}
+
+// listTar reads tar headers, skipping over member contents.
+func listTar(r io.Reader) ([]archiveEntry, error) {
+	tr := tar.NewReader(r)
+	var entries []archiveEntry
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, archiveEntry{name: h.Name, size: h.Size, mode: h.FileInfo().Mode(), modTime: h.ModTime})
+	}
+}
+
+// listZip reads the zip central directory.
+func listZip(f *os.File) ([]archiveEntry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]archiveEntry, len(zr.File))
+	for i, zf := range zr.File {
+		entries[i] = archiveEntry{name: zf.Name, size: int64(zf.UncompressedSize64), mode: zf.Mode(), modTime: zf.Modified}
+	}
+	return entries, nil
+}
+
+// listWithTar lists compressed tarballs the standard library can't read.
+func listWithTar(path string) (string, error) {
+	output, err := exec.Command("tar", "-tvf", path).Output()
+	if err != nil && len(output) == 0 {
+		return "", fmt.Errorf("failed to list archive with tar: %w", err)
+	}
+	return truncateLines("Format: compressed tar (listed with tar -tv)\n"+string(output), maxProcessOutput), nil
+}
+
+// formatArchiveEntries renders entries like tar -tv, capped at maxArchiveEntries.
+func formatArchiveEntries(format string, entries []archiveEntry) string {
+	var b strings.Builder
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	fmt.Fprintf(&b, "Format: %s, %d entries, %d bytes uncompressed\n", format, len(entries), total)
+	for i, e := range entries {
+		if i == maxArchiveEntries {
+			fmt.Fprintf(&b, "... (%d more entries)\n", len(entries)-i)
+			break
+		}
+		fmt.Fprintf(&b, "%s %10d %s %s\n", e.mode, e.size, e.modTime.Format("2006-01-02 15:04"), e.name)
+	}
+	return truncateLines(b.String(), maxProcessOutput)
+}
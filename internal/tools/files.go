@@ -1,11 +1,28 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // executePwd returns the current working directory.
@@ -17,8 +34,35 @@ func executePwd() (string, error) {
 	return cwd, nil
 }
 
-// executeLs lists files in the given directory.
-func executeLs(path string, recursive bool) (string, error) {
+// ls limits: the default and largest number of entries returned, and how
+// many entries a recursive sorted listing examines before giving up.
+const (
+	defaultLsEntries = 1000
+	maxLsEntries     = 10000
+	maxLsScan        = 100000
+)
+
+// lsOptions narrows and orders an ls listing.
+type lsOptions struct {
+	recursive  bool
+	pattern    string // glob matched against each entry's name
+	maxEntries int
+	sortBy     string // name (default), size, or mtime
+	hidden     bool   // include dot files and directories
+	skip       skipFunc
+}
+
+// lsEntry is one listed file or directory.
+type lsEntry struct {
+	path    string
+	dir     bool
+	size    int64
+	modTime time.Time
+}
+
+// executeLs lists files in the given directory, filtered by name pattern and
+// hidden status, sorted, and capped at opts.maxEntries.
+func executeLs(path string, opts lsOptions) (string, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to access path: %w", err)
@@ -29,9 +73,49 @@ func executeLs(path string, recursive bool) (string, error) {
 		return info.Name(), nil
 	}
 
-	var result strings.Builder
+	if opts.pattern != "" {
+		if _, err := filepath.Match(opts.pattern, ""); err != nil {
+			return "", fmt.Errorf("invalid pattern %q: %w", opts.pattern, err)
+		}
+	}
+	switch opts.sortBy {
+	case "", "name", "size", "mtime":
+	default:
+		return "", fmt.Errorf("invalid sort_by %q (use name, size, or mtime)", opts.sortBy)
+	}
+	if opts.maxEntries <= 0 {
+		opts.maxEntries = defaultLsEntries
+	}
+	opts.maxEntries = min(opts.maxEntries, maxLsEntries)
+
+	// Listings by name can stop once the cap is exceeded; sorted ones need
+	// every entry (up to maxLsScan) to find the largest or newest
+	limit := opts.maxEntries + 1
+	if opts.sortBy == "size" || opts.sortBy == "mtime" {
+		limit = maxLsScan
+	}
 
-	if recursive {
+	var entries []lsEntry
+	stopped := false
+	add := func(rel string, d fs.DirEntry) {
+		if opts.pattern != "" {
+			if ok, _ := filepath.Match(opts.pattern, d.Name()); !ok {
+				return
+			}
+		}
+		info, err := d.Info()
+		if err != nil {
+			return // Skip files we can't stat
+		}
+		entries = append(entries, lsEntry{path: rel, dir: d.IsDir(), size: info.Size(), modTime: info.ModTime()})
+	}
+
+	// skip sees entries as a path argument naming them would
+	skipped := func(rel string, d fs.DirEntry) bool {
+		return opts.skip != nil && opts.skip(filepath.Join(path, rel), d.IsDir())
+	}
+
+	if opts.recursive {
 		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
@@ -43,44 +127,64 @@ func executeLs(path string, recursive bool) (string, error) {
 			if relPath == "." {
 				return nil
 			}
-
-			info, err := d.Info()
-			if err != nil {
-				return nil // Skip files we can't stat
+			if (!opts.hidden && strings.HasPrefix(d.Name(), ".")) || skipped(relPath, d) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
 			}
-
-			prefix := ""
-			if d.IsDir() {
-				prefix = "d "
-			} else {
-				prefix = "- "
+			add(relPath, d)
+			if len(entries) >= limit {
+				stopped = true
+				return filepath.SkipAll
 			}
-			result.WriteString(fmt.Sprintf("%s%s (%d bytes)\n", prefix, relPath, info.Size()))
 			return nil
 		})
 		if err != nil {
 			return "", fmt.Errorf("failed to walk directory: %w", err)
 		}
 	} else {
-		entries, err := os.ReadDir(path)
+		dirEntries, err := os.ReadDir(path)
 		if err != nil {
 			return "", fmt.Errorf("failed to read directory: %w", err)
 		}
-
-		for _, entry := range entries {
-			info, err := entry.Info()
-			if err != nil {
-				continue // Skip files we can't stat
+		for _, entry := range dirEntries {
+			if (!opts.hidden && strings.HasPrefix(entry.Name(), ".")) || skipped(entry.Name(), entry) {
+				continue
 			}
+			add(entry.Name(), entry)
+		}
+	}
+
+	switch opts.sortBy {
+	case "size":
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].size > entries[j].size })
+	case "mtime":
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].modTime.After(entries[j].modTime) })
+	}
 
-			prefix := ""
-			if entry.IsDir() {
-				prefix = "d "
+	var result strings.Builder
+	for i, e := range entries {
+		if i == opts.maxEntries {
+			if stopped {
+				result.WriteString("... (more entries not shown; narrow with pattern or raise max_entries)\n")
 			} else {
-				prefix = "- "
+				fmt.Fprintf(&result, "... (%d more entries not shown; narrow with pattern or raise max_entries)\n", len(entries)-i)
 			}
-			result.WriteString(fmt.Sprintf("%s%s (%d bytes)\n", prefix, entry.Name(), info.Size()))
+			break
+		}
+		prefix := "- "
+		if e.dir {
+			prefix = "d "
 		}
+		if opts.sortBy == "mtime" {
+			fmt.Fprintf(&result, "%s%s (%d bytes, %s)\n", prefix, e.path, e.size, e.modTime.Format("2006-01-02 15:04"))
+		} else {
+			fmt.Fprintf(&result, "%s%s (%d bytes)\n", prefix, e.path, e.size)
+		}
+	}
+	if stopped && limit == maxLsScan {
+		fmt.Fprintf(&result, "(stopped after scanning %d entries; sorting covers only those)\n", maxLsScan)
 	}
 
 	return strings.TrimSuffix(result.String(), "\n"), nil
@@ -110,8 +214,14 @@ func executeStat(path string) (string, error) {
 	), nil
 }
 
-// executeCat reads and returns file contents.
-func executeCat(path string) (string, error) {
+// maxCatSize limits how much of a file cat returns in one call.
+const maxCatSize = 100 * 1024 // 100KB
+
+// executeCat reads and returns file contents. Binary files are summarized by
+// type and size instead, with base64Bytes leading bytes as base64 if asked.
+// With offset (1-based) or limit set, or for files over maxCatSize, it
+// returns a page of lines headed by the range and the total line count.
+func executeCat(path string, base64Bytes, offset, limit int) (string, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to access file: %w", err)
@@ -121,16 +231,197 @@ func executeCat(path string) (string, error) {
 		return "", fmt.Errorf("cannot cat a directory")
 	}
 
-	// Limit file size to prevent reading huge files
-	const maxSize = 100 * 1024 // 100KB
-	if info.Size() > maxSize {
-		return "", fmt.Errorf("file too large (max %d bytes, got %d bytes)", maxSize, info.Size())
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer f.Close()
+
+	// Binary files are summarized rather than dumped, whatever their size
+	sample := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, sample)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	sample = sample[:n]
+	if isBinary(sample) {
+		return describeBinary(info.Size(), sample, base64Bytes), nil
+	}
+
+	if offset > 0 || limit > 0 || info.Size() > maxCatSize {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		return readLines(f, info.Size(), offset, limit)
 	}
 
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	if text, ok := decodeUTF16(content); ok {
+		return text, nil
+	}
 
 	return string(content), nil
 }
+
+// readLines returns up to limit lines starting at line offset (1-based),
+// stopping early at maxCatSize, and counts the file's lines so the model
+// knows how far it can page.
+func readLines(r io.Reader, size int64, offset, limit int) (string, error) {
+	offset = max(offset, 1)
+	var page []string
+	pageSize, total := 0, 0
+	full := false
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			total++
+			if total >= offset && !full && (limit <= 0 || len(page) < limit) {
+				if pageSize+len(line) > maxCatSize {
+					full = true
+				} else {
+					page = append(page, line)
+					pageSize += len(line)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if len(page) == 0 {
+		if offset > total {
+			return fmt.Sprintf("[no lines at offset %d; file has %d lines]", offset, total), nil
+		}
+		return "", fmt.Errorf("line %d alone is larger than %d bytes", offset, maxCatSize)
+	}
+	last := offset + len(page) - 1
+	header := fmt.Sprintf("[lines %d-%d of %d, %d bytes total", offset, last, total, size)
+	if last < total {
+		header += fmt.Sprintf("; continue with offset %d", last+1)
+	}
+	return header + "]\n" + strings.Join(page, ""), nil
+}
+
+// Binary detection: how much of a file cat inspects, and the most leading
+// bytes it will return as base64.
+const (
+	binarySniffLen = 8000
+	maxCatBase64   = 4096
+)
+
+// isBinary reports whether a file's first bytes look like binary data: a
+// NUL byte (as git and grep check) or invalid UTF-8. UTF-16 text with a
+// byte order mark counts as text.
+func isBinary(sample []byte) bool {
+	if _, ok := utf16BOM(sample); ok {
+		return false
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	// The sample may end partway through a multi-byte character
+	for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+	return !utf8.Valid(sample)
+}
+
+// describeBinary summarizes a binary file by type and size, pointing at the
+// tools that can look inside it, with optional base64 of its first bytes.
+func describeBinary(size int64, sample []byte, base64Bytes int) string {
+	kind := http.DetectContentType(sample)
+	switch {
+	case bytes.HasPrefix(sample, sqliteMagic):
+		kind = "SQLite database (use sqlite_schema)"
+	case bytes.HasPrefix(sample, magicZip), bytes.HasPrefix(sample, magicGzip), bytes.HasPrefix(sample, magicBzip2),
+		bytes.HasPrefix(sample, magicXz), bytes.HasPrefix(sample, magicZstd):
+		kind += " (archive; use archive_list)"
+	case bytes.HasPrefix(sample, []byte("\x7fELF")):
+		kind = "ELF executable"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Binary file: %s, %d bytes (contents not shown; checksum gives its hash)", kind, size)
+	if base64Bytes > 0 {
+		// maxCatBase64 is below binarySniffLen, so the sample holds them
+		head := sample[:min(base64Bytes, maxCatBase64, len(sample))]
+		fmt.Fprintf(&b, "\nFirst %d bytes (base64): %s", len(head), base64.StdEncoding.EncodeToString(head))
+	}
+	return b.String()
+}
+
+// utf16BOM reports the byte order of text starting with a UTF-16 byte order mark.
+func utf16BOM(content []byte) (binary.ByteOrder, bool) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xff, 0xfe}):
+		return binary.LittleEndian, true
+	case bytes.HasPrefix(content, []byte{0xfe, 0xff}):
+		return binary.BigEndian, true
+	}
+	return nil, false
+}
+
+// decodeUTF16 converts UTF-16 text with a byte order mark, as Windows tools
+// often write, to UTF-8.
+func decodeUTF16(content []byte) (string, bool) {
+	order, ok := utf16BOM(content)
+	if !ok {
+		return "", false
+	}
+	content = content[2:]
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[i*2:])
+	}
+	return string(utf16.Decode(units)), true
+}
+
+// checksumAlgorithms are the hashes the checksum tool can compute.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// executeChecksum hashes a file, streaming it so large images work, and
+// returns the digest in the "<hex>  <path>" format of sha256sum.
+func executeChecksum(path, algo string) (string, error) {
+	algo = strings.ToLower(strings.ReplaceAll(algo, "-", ""))
+	if algo == "" {
+		algo = "sha256"
+	}
+	newHash, ok := checksumAlgorithms[algo]
+	if !ok {
+		return "", fmt.Errorf("unsupported algorithm %q (use md5, sha1, sha256, or sha512)", algo)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to access file: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("cannot checksum a directory")
+	}
+
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return fmt.Sprintf("%s  %s (%s, %d bytes)", hex.EncodeToString(h.Sum(nil)), path, algo, info.Size()), nil
+}
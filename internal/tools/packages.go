@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// packageManager queries one package database. query returns the installed
+// version, or "" when the package isn't installed.
+type packageManager struct {
+	name    string // shown to the model, e.g. "apt (dpkg)"
+	binary  string
+	goos    string // limits the manager to one platform when set
+	install []string
+	query   func(name string) string
+}
+
+// packageManagers are checked in order; every one present is queried, since
+// a machine often has several (apt and snap, brew and casks).
+var packageManagers = []packageManager{
+	{name: "apt (dpkg)", binary: "dpkg-query", install: []string{"apt-get"}, query: func(name string) string {
+		out, err := exec.Command("dpkg-query", "-W", "-f=${Status}\t${Version}\n", name).Output()
+		// Multi-arch packages print a line per architecture
+		status, version, _ := strings.Cut(firstLine(out), "\t")
+		// Removed packages keep a "deinstall ok config-files" entry
+		if err != nil || !strings.HasSuffix(status, " installed") {
+			return ""
+		}
+		return version
+	}},
+	{name: "rpm", binary: "rpm", install: []string{"dnf", "yum", "zypper"}, query: func(name string) string {
+		out, err := exec.Command("rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}\n", name).Output()
+		if err != nil {
+			return ""
+		}
+		return firstLine(out)
+	}},
+	{name: "apk", binary: "apk", install: []string{"apk"}, query: func(name string) string {
+		out, err := exec.Command("apk", "list", "--installed", name).Output()
+		if err != nil || len(out) == 0 {
+			return ""
+		}
+		// name-1.2.3-r0 arch {origin} (license) [installed]
+		pkg, _, _ := strings.Cut(firstLine(out), " ")
+		return strings.TrimPrefix(pkg, name+"-")
+	}},
+	{name: "pacman", binary: "pacman", install: []string{"pacman"}, query: func(name string) string {
+		out, err := exec.Command("pacman", "-Q", name).Output()
+		if err != nil {
+			return ""
+		}
+		_, version, _ := strings.Cut(firstLine(out), " ")
+		return version
+	}},
+	{name: "snap", binary: "snap", goos: "linux", install: []string{"snap"}, query: func(name string) string {
+		out, err := exec.Command("snap", "list", name).Output()
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if err != nil || len(lines) < 2 {
+			return ""
+		}
+		// Name  Version  Rev  Tracking  Publisher  Notes
+		if fields := strings.Fields(lines[1]); len(fields) > 1 {
+			return fields[1]
+		}
+		return ""
+	}},
+	{name: "brew", binary: "brew", install: []string{"brew"}, query: func(name string) string {
+		// Covers formulae and casks
+		out, err := exec.Command("brew", "list", "--versions", name).Output()
+		if err != nil || len(out) == 0 {
+			return ""
+		}
+		_, version, _ := strings.Cut(firstLine(out), " ")
+		return version
+	}},
+	{name: "winget", binary: "winget", goos: "windows", install: []string{"winget"}, query: func(name string) string {
+		out, err := exec.Command("winget", "list", "--query", name, "--accept-source-agreements", "--disable-interactivity").Output()
+		if err != nil {
+			// winget exits non-zero when nothing matches
+			return ""
+		}
+		// A table of Name, Id, Version, Available, Source; return the matching rows
+		var rows []string
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.Contains(strings.ToLower(line), strings.ToLower(name)) {
+				rows = append(rows, strings.TrimSpace(line))
+			}
+		}
+		return strings.Join(rows, "; ")
+	}},
+}
+
+// executePkgInstalled reports whether a package is installed with each
+// package manager present, and which command installs or upgrades it.
+func executePkgInstalled(name string) (string, error) {
+	var found, checked []string
+	for _, pm := range packageManagers {
+		if pm.goos != "" && pm.goos != runtime.GOOS {
+			continue
+		}
+		if _, err := exec.LookPath(pm.binary); err != nil {
+			continue
+		}
+		label := pm.name
+		for _, cmd := range pm.install {
+			if _, err := exec.LookPath(cmd); err == nil {
+				label = fmt.Sprintf("%s, install with %s", pm.name, cmd)
+				break
+			}
+		}
+		checked = append(checked, label)
+
+		if version := pm.query(name); version != "" {
+			found = append(found, fmt.Sprintf("Installed via %s: %s", pm.name, version))
+		}
+	}
+
+	if len(checked) == 0 {
+		return "", fmt.Errorf("no supported package manager found (dpkg, rpm, apk, pacman, snap, brew, winget)")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Package managers: %s\n", strings.Join(checked, "; "))
+	if len(found) == 0 {
+		fmt.Fprintf(&b, "%s is not installed as a package (it may still be installed manually; check with which)", name)
+	} else {
+		b.WriteString(strings.Join(found, "\n"))
+	}
+	return b.String(), nil
+}
+
+// firstLine returns the first line of command output, trimmed.
+func firstLine(output []byte) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(line)
+}
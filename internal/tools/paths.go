@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nealhardesty/gx/internal/ignore"
+)
+
+// pathArgs are the arguments tools take file system paths in.
+var pathArgs = []string{"path", "file"}
+
+// defaultPaths are the paths tools read when the path argument is omitted.
+var defaultPaths = map[string]string{"ls": "."}
+
+// AllowPaths limits tools that take a path (ls, stat, cat, query, logs with
+// a file, and plugins with a path or file parameter) to the given absolute
+// paths and everything under them. Nil lifts the limit; an empty list
+// allows no paths at all.
+func (r *Registry) AllowPaths(paths []string) {
+	r.allowPaths = paths
+}
+
+// Ignore hides the paths a .gxignore excludes from tools: calls naming
+// them fail, and ls leaves them out of listings. Nil ignores nothing.
+func (r *Registry) Ignore(m *ignore.Matcher) {
+	r.ignore = m
+}
+
+// ignored reports whether the .gxignore excludes path, as given or with
+// symlinks resolved.
+func (r *Registry) ignored(path string, isDir bool) bool {
+	if r.ignore == nil {
+		return false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return r.ignore.Ignored(abs, isDir) || r.ignore.Ignored(resolvePath(abs), isDir)
+}
+
+// checkPaths rejects a call that would read outside the allowed paths or
+// a path the .gxignore excludes. Paths are compared after resolving
+// symlinks, so a link can't lead out.
+func (r *Registry) checkPaths(t tool, args map[string]any) error {
+	if (r.allowPaths == nil && r.ignore == nil) || t.decl.Parameters == nil {
+		return nil
+	}
+	for _, name := range pathArgs {
+		if _, ok := t.decl.Parameters.Properties[name]; !ok {
+			continue
+		}
+		path, _ := args[name].(string)
+		if path = strings.TrimSpace(path); path == "" {
+			if path = defaultPaths[t.decl.Name]; path == "" {
+				continue
+			}
+		}
+		if r.allowPaths != nil && !PathAllowed(path, r.allowPaths) {
+			return fmt.Errorf("%s is outside the paths this project lets tools read (%s)", path, strings.Join(r.allowPaths, ", "))
+		}
+		// Whether the path is a directory only matters for dir/ patterns
+		if r.ignored(path, isDir(path)) {
+			return fmt.Errorf("%s is excluded by %s", path, ignore.FileName)
+		}
+	}
+	return nil
+}
+
+// PathAllowed reports whether path is one of the allowed paths or under
+// one, after resolving symlinks on both sides.
+func PathAllowed(path string, allowed []string) bool {
+	path = resolvePath(path)
+	for _, root := range allowed {
+		rel, err := filepath.Rel(resolvePath(root), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// resolvePath makes path absolute and resolves symlinks where it exists.
+func resolvePath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	return path
+}
@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/vertexai/genai"
+)
+
+// DefaultPluginDir is the directory, relative to the home directory, that
+// holds executable tool plugins. (~/.gx is the staging file, so plugins
+// can't live under it.)
+const DefaultPluginDir = ".gxtools"
+
+// DefaultManifestCache is the file, relative to the home directory, that
+// caches plugin manifests by path and modification time.
+const DefaultManifestCache = ".gxtoolcache"
+
+// Plugin timeouts: answering --gx-manifest happens whenever a plugin is new
+// or has changed, so it must be quick; a tool call may do real work.
+const (
+	pluginManifestTimeout = 5 * time.Second
+	pluginRunTimeout      = 60 * time.Second
+)
+
+// pluginNamePattern matches the function names Gemini accepts.
+var pluginNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]{0,63}$`)
+
+// pluginManifest is what a plugin prints for --gx-manifest. Parameters is a
+// JSON Schema subset: type, description, enum, items, properties, required.
+type pluginManifest struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Summary     string        `json:"summary"`
+	Access      string        `json:"access"` // read-only, mutating (default), or network
+	Parameters  *pluginSchema `json:"parameters"`
+}
+
+type pluginSchema struct {
+	Type        string                   `json:"type"`
+	Description string                   `json:"description"`
+	Enum        []string                 `json:"enum"`
+	Items       *pluginSchema            `json:"items"`
+	Properties  map[string]*pluginSchema `json:"properties"`
+	Required    []string                 `json:"required"`
+}
+
+// schemaTypes maps JSON Schema type names to Gemini types.
+var schemaTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"integer": genai.TypeInteger,
+	"number":  genai.TypeNumber,
+	"boolean": genai.TypeBoolean,
+	"array":   genai.TypeArray,
+	"object":  genai.TypeObject,
+}
+
+// PluginDir returns the tool plugin directory: GX_TOOLS_DIR if set,
+// otherwise ~/.gxtools.
+func PluginDir() string {
+	if dir := os.Getenv("GX_TOOLS_DIR"); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, DefaultPluginDir)
+}
+
+// LoadPlugins registers each executable in dir as a tool. A plugin
+// describes itself when run with --gx-manifest and is then called with its
+// arguments as a JSON object on stdin, its stdout becoming the result.
+// Manifests are cached in ~/.gxtoolcache, so a plugin is only asked again
+// when its file changes. Plugins that fail to load are reported and
+// skipped; a missing directory is not an error.
+func (r *Registry) LoadPlugins(dir string) []error {
+	if !r.enabled || dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return []error{fmt.Errorf("failed to read plugin directory: %w", err)}
+	}
+
+	var paths []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if isExecutable(path) {
+			paths = append(paths, path)
+		}
+	}
+
+	// Manifests load concurrently so slow plugins don't add up
+	cache := readManifestCache()
+	fresh := make([]manifestEntry, len(paths))
+	plugins := make([]tool, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			var manifest []byte
+			manifest, fresh[i], errs[i] = pluginManifestFor(path, cache[path])
+			if errs[i] == nil {
+				plugins[i], errs[i] = loadPlugin(path, manifest)
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	updated := make(map[string]manifestEntry, len(paths))
+	for i, path := range paths {
+		if errs[i] == nil {
+			updated[path] = fresh[i]
+		}
+	}
+	writeManifestCache(cache, updated)
+
+	var loadErrs []error
+	names := map[string]bool{}
+	for _, t := range builtinTools {
+		names[t.decl.Name] = true
+	}
+	for i, p := range plugins {
+		switch {
+		case errs[i] != nil:
+			loadErrs = append(loadErrs, fmt.Errorf("plugin %s: %w", paths[i], errs[i]))
+		case names[p.decl.Name]:
+			loadErrs = append(loadErrs, fmt.Errorf("plugin %s: tool %s is already defined", paths[i], p.decl.Name))
+		default:
+			names[p.decl.Name] = true
+			r.plugins = append(r.plugins, p)
+		}
+	}
+	sort.Slice(r.plugins, func(i, j int) bool { return r.plugins[i].decl.Name < r.plugins[j].decl.Name })
+	return loadErrs
+}
+
+// isExecutable reports whether path is a regular file the user can run.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".bat", ".cmd", ".com":
+			return true
+		}
+		return false
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
+// manifestEntry is a cached --gx-manifest answer. The modification time and
+// size identify the version of the plugin file that gave it.
+type manifestEntry struct {
+	ModTime  int64           `json:"mod_time"`
+	Size     int64           `json:"size"`
+	Manifest json.RawMessage `json:"manifest"`
+}
+
+// pluginManifestFor returns the plugin's manifest: the cached one when the
+// file hasn't changed since it was stored, otherwise the output of running
+// the plugin with --gx-manifest. It also returns the entry to cache.
+func pluginManifestFor(path string, cached manifestEntry) ([]byte, manifestEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, manifestEntry{}, err
+	}
+	current := manifestEntry{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+	if cached.Manifest != nil && cached.ModTime == current.ModTime && cached.Size == current.Size {
+		return cached.Manifest, cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginManifestTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "--gx-manifest").Output()
+	if err != nil {
+		return nil, manifestEntry{}, fmt.Errorf("--gx-manifest failed: %w", err)
+	}
+	if !json.Valid(output) {
+		return nil, manifestEntry{}, fmt.Errorf("invalid manifest: not JSON")
+	}
+	current.Manifest = output
+	return output, current, nil
+}
+
+// manifestCachePath returns the path to ~/.gxtoolcache.
+func manifestCachePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, DefaultManifestCache), nil
+}
+
+// readManifestCache loads the cached manifests. A missing or unreadable
+// cache is empty, which only costs running the plugins again.
+func readManifestCache() map[string]manifestEntry {
+	cache := map[string]manifestEntry{}
+	path, err := manifestCachePath()
+	if err != nil {
+		return cache
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// writeManifestCache stores updated, the manifests of the plugins that just
+// loaded, when they differ from cache. Plugins that were removed or failed
+// drop out.
+func writeManifestCache(cache, updated map[string]manifestEntry) {
+	if reflect.DeepEqual(cache, updated) {
+		return
+	}
+	path, err := manifestCachePath()
+	if err != nil {
+		return
+	}
+	if data, err := json.MarshalIndent(updated, "", "  "); err == nil {
+		_ = os.WriteFile(path, data, 0600)
+	}
+}
+
+// loadPlugin builds a plugin's tool from its --gx-manifest output.
+func loadPlugin(path string, manifest []byte) (tool, error) {
+	var m pluginManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return tool{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if !pluginNamePattern.MatchString(m.Name) {
+		return tool{}, fmt.Errorf("invalid tool name %q (use letters, digits, _ and -)", m.Name)
+	}
+	if m.Description == "" {
+		return tool{}, fmt.Errorf("manifest for %s has no description", m.Name)
+	}
+
+	access := Mutating
+	switch m.Access {
+	case "read-only", "readonly":
+		access = ReadOnly
+	case "network":
+		access = Network
+	case "", "mutating":
+	default:
+		return tool{}, fmt.Errorf("invalid access %q (use read-only, mutating, or network)", m.Access)
+	}
+
+	params := noParams
+	if m.Parameters != nil {
+		var err error
+		if params, err = m.Parameters.toGenai(); err != nil {
+			return tool{}, fmt.Errorf("invalid parameters: %w", err)
+		}
+		if params.Type != genai.TypeObject {
+			return tool{}, fmt.Errorf("parameters must be an object schema")
+		}
+	}
+
+	summary := m.Summary
+	if summary == "" {
+		summary = fmt.Sprintf("%s: %s", m.Name, m.Description)
+	}
+	return tool{
+		decl: &genai.FunctionDeclaration{
+			Name:        m.Name,
+			Description: m.Description,
+			Parameters:  params,
+		},
+		summary: summary + " (plugin)",
+		access:  access,
+		run: func(args map[string]any) (string, error) {
+			return runPlugin(path, args)
+		},
+	}, nil
+}
+
+// toGenai converts a manifest schema to a Gemini schema.
+func (s *pluginSchema) toGenai() (*genai.Schema, error) {
+	t, ok := schemaTypes[s.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %q", s.Type)
+	}
+	schema := &genai.Schema{Type: t, Description: s.Description, Enum: s.Enum, Required: s.Required}
+	if s.Items != nil {
+		items, err := s.Items.toGenai()
+		if err != nil {
+			return nil, err
+		}
+		schema.Items = items
+	}
+	if t == genai.TypeObject {
+		schema.Properties = map[string]*genai.Schema{}
+		for name, prop := range s.Properties {
+			p, err := prop.toGenai()
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", name, err)
+			}
+			schema.Properties[name] = p
+		}
+	}
+	return schema, nil
+}
+
+// runPlugin calls a plugin with its arguments on stdin and returns its stdout.
+func runPlugin(path string, args map[string]any) (string, error) {
+	input, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginRunTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("plugin timed out after %s", pluginRunTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("plugin failed: %s", truncateLines(msg, maxProcessOutput))
+		}
+		return "", fmt.Errorf("plugin failed: %w", err)
+	}
+	return truncateLines(string(output), maxProcessOutput), nil
+}
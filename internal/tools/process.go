@@ -1,40 +1,209 @@
 package tools
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// executePs lists running processes.
-func executePs() (string, error) {
-	var cmd *exec.Cmd
+// psSortColumns maps sort_by values to their ps aux column.
+var psSortColumns = map[string]int{"cpu": 2, "memory": 3}
 
-	switch runtime.GOOS {
-	case "windows":
+// executePs lists running processes, optionally only those whose command
+// contains filter (case-insensitive), sorted by CPU or memory use (highest
+// first), and capped at limit.
+func executePs(filter, sortBy string, limit int) (string, error) {
+	if _, ok := psSortColumns[sortBy]; sortBy != "" && !ok {
+		return "", fmt.Errorf("invalid sort_by %q (use cpu or memory)", sortBy)
+	}
+
+	if runtime.GOOS == "windows" {
 		// Use PowerShell to get process list
+		script := "Get-Process"
+		if filter != "" {
+			script += fmt.Sprintf(" | Where-Object ProcessName -like '*%s*'", psQuote(filter))
+		}
+		switch sortBy {
+		case "cpu":
+			script += " | Sort-Object CPU -Descending"
+		case "memory":
+			script += " | Sort-Object WorkingSet64 -Descending"
+		}
+		if limit > 0 {
+			script += fmt.Sprintf(" | Select-Object -First %d", limit)
+		}
+		script += " | Select-Object Id, ProcessName, CPU, WorkingSet64 | Format-Table -AutoSize | Out-String -Width 200"
+		output, err := exec.Command("powershell", "-Command", script).Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to execute ps: %w", err)
+		}
+		return truncateLines(string(output), maxProcessOutput), nil
+	}
+
+	// ps aux on Linux, macOS, and other Unix-like systems
+	output, err := exec.Command("ps", "aux").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to execute ps: %w", err)
+	}
+	if filter == "" && sortBy == "" && limit <= 0 {
+		return truncateLines(string(output), maxProcessOutput), nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	header, rows := lines[0], lines[1:]
+	if filter != "" {
+		self := strconv.Itoa(os.Getpid())
+		needle := strings.ToLower(filter)
+		var matches []string
+		for _, row := range rows {
+			// USER PID %CPU %MEM VSZ RSS TTY STAT START TIME COMMAND
+			fields := strings.Fields(row)
+			if len(fields) > 10 && fields[1] != self && strings.Contains(strings.ToLower(strings.Join(fields[10:], " ")), needle) {
+				matches = append(matches, row)
+			}
+		}
+		if len(matches) == 0 {
+			return fmt.Sprintf("No processes matching %q", filter), nil
+		}
+		rows = matches
+	}
+	if column, ok := psSortColumns[sortBy]; ok {
+		usage := func(row string) float64 {
+			fields := strings.Fields(row)
+			if len(fields) <= column {
+				return 0
+			}
+			v, _ := strconv.ParseFloat(fields[column], 64)
+			return v
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return usage(rows[i]) > usage(rows[j]) })
+	}
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return truncateLines(header+"\n"+strings.Join(rows, "\n"), maxProcessOutput), nil
+}
+
+// executePgrep lists processes whose name or command line contains name
+// (case-insensitive), one "PID command line" per line.
+func executePgrep(name string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		// CommandLine is empty for processes we can't inspect, so fall back to Name
 		cmd = exec.Command("powershell", "-Command",
-			"Get-Process | Select-Object Id, ProcessName, CPU, WorkingSet64 | Format-Table -AutoSize | Out-String -Width 200")
-	case "darwin":
-		// macOS ps command
-		cmd = exec.Command("ps", "aux")
-	default:
-		// Linux and other Unix-like systems
-		cmd = exec.Command("ps", "aux")
+			"Get-CimInstance Win32_Process | ForEach-Object { \"$($_.ProcessId) $(if ($_.CommandLine) { $_.CommandLine } else { $_.Name })\" }")
+	} else {
+		cmd = exec.Command("ps", "-eo", "pid=,args=")
 	}
 
 	output, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("failed to execute ps: %w", err)
+		return "", fmt.Errorf("failed to list processes: %w", err)
 	}
 
-	// Truncate output if too long
-	result := string(output)
-	const maxLen = 8000
-	if len(result) > maxLen {
-		lines := strings.Split(result, "\n")
+	self := strconv.Itoa(os.Getpid())
+	needle := strings.ToLower(name)
+	var matches []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		pid, _, _ := strings.Cut(line, " ")
+		if line == "" || pid == self || !strings.Contains(strings.ToLower(line[len(pid):]), needle) {
+			continue
+		}
+		matches = append(matches, line)
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("No processes matching %q", name), nil
+	}
+	return truncateLines(strings.Join(matches, "\n"), maxProcessOutput), nil
+}
+
+// executeLsof lists what has a TCP/UDP port open, the files and sockets a
+// process has open, or the processes holding a path open. Exactly one of
+// port, pid, and path is set.
+func executeLsof(port, pid int, path string) (string, error) {
+	if runtime.GOOS == "windows" {
+		return executeLsofWindows(port, pid, path)
+	}
+
+	var args []string
+	var what string
+	switch {
+	case port > 0:
+		args, what = []string{"-nP", "-i", fmt.Sprintf(":%d", port)}, fmt.Sprintf("port %d", port)
+	case pid > 0:
+		args, what = []string{"-nP", "-p", strconv.Itoa(pid)}, fmt.Sprintf("PID %d", pid)
+	default:
+		args, what = []string{"-nP", "--", path}, path
+	}
+
+	if _, err := exec.LookPath("lsof"); err != nil {
+		// Minimal Linux images often ship ss but not lsof
+		if port > 0 && runtime.GOOS == "linux" {
+			output, err := exec.Command("ss", "-tunap", fmt.Sprintf("( sport = :%d )", port)).Output()
+			if err != nil {
+				return "", fmt.Errorf("lsof is not installed and ss failed: %w", err)
+			}
+			return truncateLines(string(output), maxProcessOutput), nil
+		}
+		return "", fmt.Errorf("lsof is not installed")
+	}
+
+	output, err := exec.Command("lsof", args...).Output()
+	if err != nil {
+		// lsof exits 1 with no output when nothing matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(output) == 0 {
+			return fmt.Sprintf("Nothing has %s open", what), nil
+		}
+		if len(output) == 0 {
+			return "", fmt.Errorf("failed to execute lsof: %w", err)
+		}
+	}
+	return truncateLines(string(output), maxProcessOutput), nil
+}
+
+// executeLsofWindows answers port and PID queries with PowerShell; Windows
+// has no built-in way to find which process holds a file open.
+func executeLsofWindows(port, pid int, path string) (string, error) {
+	var script string
+	switch {
+	case port > 0:
+		script = fmt.Sprintf("Get-NetTCPConnection -LocalPort %d -ErrorAction SilentlyContinue | "+
+			"Select-Object LocalAddress, LocalPort, RemoteAddress, RemotePort, State, OwningProcess, "+
+			"@{n='Process';e={(Get-Process -Id $_.OwningProcess).ProcessName}} | Format-Table -AutoSize | Out-String -Width 200", port)
+	case pid > 0:
+		script = fmt.Sprintf("Get-Process -Id %d | Select-Object Id, ProcessName, Path | Format-List | Out-String -Width 200; "+
+			"Get-NetTCPConnection -OwningProcess %d -ErrorAction SilentlyContinue | "+
+			"Select-Object LocalAddress, LocalPort, RemoteAddress, RemotePort, State | Format-Table -AutoSize | Out-String -Width 200", pid, pid)
+	default:
+		return "", fmt.Errorf("finding processes with %s open is not supported on Windows", path)
+	}
+
+	output, err := exec.Command("powershell", "-Command", script).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to query open ports: %w", err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return "Nothing found", nil
+	}
+	return truncateLines(string(output), maxProcessOutput), nil
+}
+
+// maxProcessOutput caps process listings sent to the model.
+const maxProcessOutput = 8000
+
+// truncateLines trims output to at most maxLen bytes, cutting at a line
+// boundary and noting that it did.
+func truncateLines(output string, maxLen int) string {
+	if len(output) > maxLen {
+		lines := strings.Split(output, "\n")
 		var truncated strings.Builder
 		for _, line := range lines {
 			if truncated.Len()+len(line)+1 > maxLen {
@@ -44,10 +213,9 @@ func executePs() (string, error) {
 			truncated.WriteString(line)
 			truncated.WriteString("\n")
 		}
-		result = truncated.String()
+		output = truncated.String()
 	}
-
-	return strings.TrimSpace(result), nil
+	return strings.TrimSpace(output)
 }
 
 // executeUptime returns system uptime information.
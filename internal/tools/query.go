@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nealhardesty/gx/internal/toml"
+)
+
+// maxQueryFileSize caps the files query will parse.
+const maxQueryFileSize = 10 * 1024 * 1024 // 10MB
+
+// executeQuery evaluates a jq-style expression against a JSON, YAML, or TOML
+// file and returns each result as JSON on its own line.
+//
+// Supported expressions are paths (., .a.b, .a["key.with.dots"], .a[0],
+// .a[-1], .a[] to iterate) joined by pipes with the keys and length filters,
+// e.g. `.servers[] | .port` or `.dependencies | keys`.
+func executeQuery(path, expr string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to access file: %w", err)
+	}
+	if info.Size() > maxQueryFileSize {
+		return "", fmt.Errorf("file too large (max %d bytes, got %d bytes)", maxQueryFileSize, info.Size())
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	doc, err := parseStructured(path, content)
+	if err != nil {
+		return "", err
+	}
+
+	results := []any{doc}
+	for _, stage := range strings.Split(expr, "|") {
+		if results, err = applyStage(results, strings.TrimSpace(stage)); err != nil {
+			return "", err
+		}
+	}
+
+	var out []string
+	for _, r := range results {
+		data, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		out = append(out, string(data))
+	}
+	if len(out) == 0 {
+		return "(no results)", nil
+	}
+	return truncateLines(strings.Join(out, "\n"), maxProcessOutput), nil
+}
+
+// parseStructured decodes a file by its extension, trying JSON then YAML
+// (a superset of JSON) when the extension says nothing.
+func parseStructured(path string, content []byte) (any, error) {
+	var doc any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	case ".toml":
+		table, err := toml.Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+		doc = table
+	default:
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("not valid JSON or YAML: %w", err)
+		}
+	}
+	return normalizeKeys(doc), nil
+}
+
+// normalizeKeys converts YAML maps with non-string keys into string-keyed
+// maps so results can be encoded as JSON.
+func normalizeKeys(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			v[k] = normalizeKeys(val)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalizeKeys(val)
+		}
+		return m
+	case []any:
+		for i, val := range v {
+			v[i] = normalizeKeys(val)
+		}
+		return v
+	}
+	return v
+}
+
+// applyStage applies one pipe stage to every current result.
+func applyStage(inputs []any, stage string) ([]any, error) {
+	var outputs []any
+	for _, in := range inputs {
+		switch stage {
+		case "keys":
+			keys, err := keysOf(in)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, keys)
+		case "length":
+			switch v := in.(type) {
+			case map[string]any:
+				outputs = append(outputs, len(v))
+			case []any:
+				outputs = append(outputs, len(v))
+			case string:
+				outputs = append(outputs, len(v))
+			case nil:
+				outputs = append(outputs, 0)
+			default:
+				return nil, fmt.Errorf("length: %s has no length", typeName(in))
+			}
+		default:
+			results, err := evalPath(in, stage)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, results...)
+		}
+	}
+	return outputs, nil
+}
+
+// keysOf returns an object's sorted keys or an array's indexes.
+func keysOf(v any) ([]any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]any, len(keys))
+		for i, k := range keys {
+			out[i] = k
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = i
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("keys: %s has no keys", typeName(v))
+}
+
+// evalPath walks a path expression such as .a.b[0] or .items[].name.
+// Missing keys yield null, as in jq.
+func evalPath(root any, expr string) ([]any, error) {
+	if !strings.HasPrefix(expr, ".") {
+		return nil, fmt.Errorf("unsupported expression %q (use paths like .a.b[0], .items[], and the keys and length filters)", expr)
+	}
+
+	current := []any{root}
+	rest := expr
+	for rest != "" && rest != "." {
+		var step func(any) ([]any, error)
+		switch {
+		case strings.HasPrefix(rest, ".["), strings.HasPrefix(rest, "["):
+			rest = strings.TrimPrefix(strings.TrimPrefix(rest, "."), "[")
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", expr)
+			}
+			index := strings.TrimSpace(rest[:end])
+			rest = rest[end+1:]
+			step = indexStep(index)
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			step = keyStep(key)
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", rest, expr)
+		}
+
+		var next []any
+		for _, v := range current {
+			results, err := step(v)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, results...)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// keyStep looks up an object key.
+func keyStep(key string) func(any) ([]any, error) {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case map[string]any:
+			return []any{v[key]}, nil
+		case nil:
+			return []any{nil}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with %q", typeName(v), key)
+	}
+}
+
+// indexStep handles [] (iterate), [n] (array index, negative from the end),
+// and ["key"] (object key).
+func indexStep(index string) func(any) ([]any, error) {
+	return func(v any) ([]any, error) {
+		if index == "" {
+			switch v := v.(type) {
+			case []any:
+				return v, nil
+			case map[string]any:
+				keys, _ := keysOf(v)
+				out := make([]any, len(keys))
+				for i, k := range keys {
+					out[i] = v[k.(string)]
+				}
+				return out, nil
+			}
+			return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+		}
+		if key, err := strconv.Unquote(index); err == nil {
+			return keyStep(key)(v)
+		}
+		n, err := strconv.Atoi(index)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index [%s]", index)
+		}
+		switch v := v.(type) {
+		case []any:
+			if n < 0 {
+				n += len(v)
+			}
+			if n < 0 || n >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[n]}, nil
+		case nil:
+			return []any{nil}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with a number", typeName(v))
+	}
+}
+
+// typeName names a decoded value's type the way jq does.
+func typeName(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "number"
+}
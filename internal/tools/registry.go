@@ -4,97 +4,676 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"cloud.google.com/go/vertexai/genai"
+
+	"github.com/nealhardesty/gx/internal/ignore"
 )
 
-// Registry holds all available tools and provides dispatch functionality.
-type Registry struct {
-	enabled bool
-}
+// Access classifies what a tool can affect.
+type Access int
 
-// NewRegistry creates a new tool registry.
-func NewRegistry(enabled bool) *Registry {
-	return &Registry{enabled: enabled}
+const (
+	// Mutating tools change files or system state. It is the zero value so
+	// a tool that doesn't declare its access is never offered as read-only.
+	Mutating Access = iota
+	// ReadOnly tools only inspect local files, processes, and system state.
+	ReadOnly
+	// Network tools contact other hosts.
+	Network
+)
+
+// String returns the access class as written in plugin manifests.
+func (a Access) String() string {
+	switch a {
+	case ReadOnly:
+		return "read-only"
+	case Network:
+		return "network"
+	}
+	return "mutating"
 }
 
-// IsEnabled returns whether tools are enabled.
-func (r *Registry) IsEnabled() bool {
-	return r.enabled
+// tool is a registered tool: its Gemini declaration, the one-line summary
+// listed in the system instruction, what it can affect, and its implementation.
+type tool struct {
+	decl    *genai.FunctionDeclaration
+	summary string
+	access  Access
+	run     func(args map[string]any) (string, error)
+	// walk, when set, is used instead of run for tools that list directory
+	// trees; they leave out entries skip reports (.gxignore paths)
+	walk func(args map[string]any, skip skipFunc) (string, error)
 }
 
-// GetToolDefinitions returns the Gemini tool definitions for all available tools.
-func (r *Registry) GetToolDefinitions() []*genai.Tool {
-	if !r.enabled {
-		return nil
-	}
+// skipFunc reports whether a tool should leave a path out of its output.
+type skipFunc func(path string, isDir bool) bool
+
+// noParams is the parameter schema for tools that take no arguments.
+var noParams = &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}}
 
-	return []*genai.Tool{
-		{
-			FunctionDeclarations: []*genai.FunctionDeclaration{
-				{
-					Name:        "pwd",
-					Description: "Get the current working directory",
-					Parameters:  &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}},
+// builtinTools lists every tool in the order it is offered to the model.
+var builtinTools = []tool{
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "pwd",
+			Description: "Get the current working directory",
+			Parameters:  noParams,
+		},
+		summary: "pwd: Get current working directory",
+		access:  ReadOnly,
+		run: func(map[string]any) (string, error) {
+			return executePwd()
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "ls",
+			Description: "List files and directories in a path",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The directory path to list (defaults to current directory)",
+					},
+					"recursive": {
+						Type:        genai.TypeBoolean,
+						Description: "If true, list recursively (like ls -R)",
+					},
+					"pattern": {
+						Type:        genai.TypeString,
+						Description: "Only list entries whose name matches this glob, e.g. *.go",
+					},
+					"max_entries": {
+						Type:        genai.TypeInteger,
+						Description: "The most entries to return (default 1000, max 10000)",
+					},
+					"sort_by": {
+						Type:        genai.TypeString,
+						Enum:        []string{"name", "size", "mtime"},
+						Description: "Order by name (default), size (largest first), or mtime (newest first)",
+					},
+					"hidden": {
+						Type:        genai.TypeBoolean,
+						Description: "Include hidden (dot) files and directories (default true)",
+					},
+				},
+			},
+		},
+		summary: "ls(path, recursive, pattern, max_entries, sort_by, hidden): List files and directories",
+		access:  ReadOnly,
+		walk: func(args map[string]any, skip skipFunc) (string, error) {
+			path, _ := args["path"].(string)
+			if path == "" {
+				path = "."
+			}
+			opts := lsOptions{hidden: true, skip: skip}
+			opts.recursive, _ = args["recursive"].(bool)
+			opts.pattern, _ = args["pattern"].(string)
+			opts.sortBy, _ = args["sort_by"].(string)
+			if hidden, ok := args["hidden"].(bool); ok {
+				opts.hidden = hidden
+			}
+			if maxEntries, ok := args["max_entries"].(float64); ok {
+				opts.maxEntries = int(maxEntries)
+			}
+			return executeLs(path, opts)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "stat",
+			Description: "Get detailed file or directory information",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The file or directory path to stat",
+					},
+				},
+				Required: []string{"path"},
+			},
+		},
+		summary: "stat(path): Get detailed file information",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("stat requires a path argument")
+			}
+			return executeStat(path)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "cat",
+			Description: "Read and return the contents of a file. Files over 100KB, or reads with offset or limit, return a page of lines headed by the line range and total line count. Binary files are summarized by type and size instead",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The file path to read",
+					},
+					"offset": {
+						Type:        genai.TypeInteger,
+						Description: "The first line to return (1-based; default 1)",
+					},
+					"limit": {
+						Type:        genai.TypeInteger,
+						Description: "The most lines to return (default: as many as fit in 100KB)",
+					},
+					"base64_bytes": {
+						Type:        genai.TypeInteger,
+						Description: "For binary files, also return this many leading bytes as base64 (max 4096), e.g. to inspect a header",
+					},
 				},
-				{
-					Name:        "ls",
-					Description: "List files and directories in a path",
-					Parameters: &genai.Schema{
-						Type: genai.TypeObject,
-						Properties: map[string]*genai.Schema{
-							"path": {
-								Type:        genai.TypeString,
-								Description: "The directory path to list (defaults to current directory)",
-							},
-							"recursive": {
-								Type:        genai.TypeBoolean,
-								Description: "If true, list recursively (like ls -R)",
-							},
-						},
+				Required: []string{"path"},
+			},
+		},
+		summary: "cat(path, offset, limit, base64_bytes): Read file contents, paging by line past 100KB; binary files are summarized",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("cat requires a path argument")
+			}
+			offset, _ := args["offset"].(float64)
+			limit, _ := args["limit"].(float64)
+			base64Bytes, _ := args["base64_bytes"].(float64)
+			return executeCat(path, int(base64Bytes), int(offset), int(limit))
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "query",
+			Description: "Evaluate a jq-style expression against a JSON, YAML, or TOML file instead of reading all of it. Supports paths (.a.b, .a[\"key.with.dots\"], .a[0], .a[-1], .a[] to iterate) joined by pipes with the keys and length filters, e.g. .services[] | .ports",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The JSON, YAML, or TOML file",
+					},
+					"expr": {
+						Type:        genai.TypeString,
+						Description: "The expression, e.g. .server.port or .dependencies | keys",
+					},
+				},
+				Required: []string{"path", "expr"},
+			},
+		},
+		summary: "query(path, expr): jq-style lookup in a JSON/YAML/TOML file, e.g. .server.port",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("query requires a path argument")
+			}
+			expr, _ := args["expr"].(string)
+			if strings.TrimSpace(expr) == "" {
+				expr = "."
+			}
+			return executeQuery(path, strings.TrimSpace(expr))
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "checksum",
+			Description: "Compute a file's checksum, e.g. to compare a download against its published hash",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The file to hash",
+					},
+					"algo": {
+						Type:        genai.TypeString,
+						Enum:        []string{"md5", "sha1", "sha256", "sha512"},
+						Description: "The hash algorithm (default sha256)",
+					},
+				},
+				Required: []string{"path"},
+			},
+		},
+		summary: "checksum(path, algo): md5/sha1/sha256/sha512 of a file",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("checksum requires a path argument")
+			}
+			algo, _ := args["algo"].(string)
+			return executeChecksum(path, algo)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "archive_list",
+			Description: "List the members of a tar, tar.gz, tar.bz2, tar.xz, tar.zst, zip, or gz file without extracting it",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The archive file to list",
+					},
+				},
+				Required: []string{"path"},
+			},
+		},
+		summary: "archive_list(path): Members of a tar/zip/gzip archive, without extracting",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("archive_list requires a path argument")
+			}
+			return executeArchiveList(path)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "sqlite_schema",
+			Description: "Show the tables, columns, indexes, views, and triggers of a local SQLite database file as CREATE statements",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"path": {
+						Type:        genai.TypeString,
+						Description: "The SQLite database file",
 					},
 				},
-				{
-					Name:        "stat",
-					Description: "Get detailed file or directory information",
-					Parameters: &genai.Schema{
-						Type: genai.TypeObject,
-						Properties: map[string]*genai.Schema{
-							"path": {
-								Type:        genai.TypeString,
-								Description: "The file or directory path to stat",
-							},
-						},
-						Required: []string{"path"},
+				Required: []string{"path"},
+			},
+		},
+		summary: "sqlite_schema(path): Tables and columns of a SQLite database",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			path, ok := args["path"].(string)
+			if !ok || path == "" {
+				return "", fmt.Errorf("sqlite_schema requires a path argument")
+			}
+			return executeSQLiteSchema(path)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "ps",
+			Description: "List running processes with details, optionally filtered by command and sorted by CPU or memory use to find the top consumers",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"filter": {
+						Type:        genai.TypeString,
+						Description: "Only list processes whose command contains this text (case-insensitive)",
+					},
+					"sort_by": {
+						Type:        genai.TypeString,
+						Enum:        []string{"cpu", "memory"},
+						Description: "Sort by CPU or memory use, highest first",
+					},
+					"limit": {
+						Type:        genai.TypeInteger,
+						Description: "The most processes to return, e.g. 10 for the top ten",
 					},
 				},
-				{
-					Name:        "cat",
-					Description: "Read and return the contents of a file",
-					Parameters: &genai.Schema{
-						Type: genai.TypeObject,
-						Properties: map[string]*genai.Schema{
-							"path": {
-								Type:        genai.TypeString,
-								Description: "The file path to read",
-							},
-						},
-						Required: []string{"path"},
+			},
+		},
+		summary: "ps(filter, sort_by, limit): List running processes, e.g. the top 10 by memory",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			filter, _ := args["filter"].(string)
+			sortBy, _ := args["sort_by"].(string)
+			limit, _ := args["limit"].(float64)
+			return executePs(filter, sortBy, int(limit))
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "pgrep",
+			Description: "Find running processes by name, returning each match's PID and full command line",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"name": {
+						Type:        genai.TypeString,
+						Description: "Text to look for in the process name or command line (case-insensitive), e.g. node or server.js",
 					},
 				},
-				{
-					Name:        "ps",
-					Description: "List running processes with details",
-					Parameters:  &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}},
+				Required: []string{"name"},
+			},
+		},
+		summary: "pgrep(name): Find processes by name, with PIDs and command lines",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			name, ok := args["name"].(string)
+			if !ok || strings.TrimSpace(name) == "" {
+				return "", fmt.Errorf("pgrep requires a name argument")
+			}
+			return executePgrep(strings.TrimSpace(name))
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "lsof",
+			Description: "Show what has a network port open, what files and sockets a process has open, or which processes hold a path open. Give exactly one of port, pid, or path",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"port": {
+						Type:        genai.TypeInteger,
+						Description: "A TCP or UDP port number, e.g. 8080",
+					},
+					"pid": {
+						Type:        genai.TypeInteger,
+						Description: "A process ID",
+					},
+					"path": {
+						Type:        genai.TypeString,
+						Description: "A file, directory, or device path",
+					},
 				},
-				{
-					Name:        "uptime",
-					Description: "Get system uptime information",
-					Parameters:  &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}},
+			},
+		},
+		summary: "lsof(port|pid|path): What has a port or file open, or what a process has open",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			port, _ := args["port"].(float64)
+			pid, _ := args["pid"].(float64)
+			path, _ := args["path"].(string)
+			given := 0
+			for _, set := range []bool{port > 0, pid > 0, path != ""} {
+				if set {
+					given++
+				}
+			}
+			if given != 1 {
+				return "", fmt.Errorf("lsof requires exactly one of port, pid, or path")
+			}
+			return executeLsof(int(port), int(pid), path)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "service",
+			Description: "Get the status of a system service (systemd, OpenRC, SysV init, launchd, or Windows services) and which init system manages it",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"name": {
+						Type:        genai.TypeString,
+						Description: "The service or unit name, e.g. nginx or docker.service",
+					},
 				},
+				Required: []string{"name"},
 			},
 		},
+		summary: "service(name): Status of a service and the init system managing it",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			name, ok := args["name"].(string)
+			if !ok || strings.TrimSpace(name) == "" {
+				return "", fmt.Errorf("service requires a name argument")
+			}
+			return executeService(strings.TrimSpace(name))
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "logs",
+			Description: "Read the most recent lines of a service's log (journalctl, macOS unified log, Windows Event Log), of a log file, or of the system log when neither is given",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"unit": {
+						Type:        genai.TypeString,
+						Description: "The service, unit, or process name whose log to read, e.g. nginx",
+					},
+					"file": {
+						Type:        genai.TypeString,
+						Description: "A log file path to read instead, e.g. /var/log/nginx/error.log",
+					},
+					"lines": {
+						Type:        genai.TypeInteger,
+						Description: "How many of the newest lines to return (default 50, max 500)",
+					},
+				},
+			},
+		},
+		summary: "logs(unit|file, lines): Recent lines of a service log, log file, or the system log",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			unit, _ := args["unit"].(string)
+			file, _ := args["file"].(string)
+			lines, _ := args["lines"].(float64)
+			if unit != "" && file != "" {
+				return "", fmt.Errorf("logs takes a unit or a file, not both")
+			}
+			return executeLogs(strings.TrimSpace(unit), strings.TrimSpace(file), int(lines))
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "mounts",
+			Description: "List mounted filesystems with their device, type, size, and usage, plus block devices including unmounted ones (USB sticks, new disks)",
+			Parameters:  noParams,
+		},
+		summary: "mounts: Mounted filesystems, types, usage, and block devices",
+		access:  ReadOnly,
+		run: func(map[string]any) (string, error) {
+			return executeMounts()
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "crontab",
+			Description: "Show the current user's crontab (Task Scheduler tasks on Windows). Installing a crontab replaces the whole table, so read it before adding or changing entries",
+			Parameters:  noParams,
+		},
+		summary: "crontab: The current user's crontab entries",
+		access:  ReadOnly,
+		run: func(map[string]any) (string, error) {
+			return executeCrontab()
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "pkg_installed",
+			Description: "Check whether a package is installed with each package manager present (dpkg, rpm, apk, pacman, snap, brew, winget), its version, and which command installs it",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"name": {
+						Type:        genai.TypeString,
+						Description: "The package name, e.g. nginx",
+					},
+				},
+				Required: []string{"name"},
+			},
+		},
+		summary: "pkg_installed(name): Whether a package is installed, its version, and the package manager",
+		access:  ReadOnly,
+		run: func(args map[string]any) (string, error) {
+			name, ok := args["name"].(string)
+			if !ok || name == "" {
+				return "", fmt.Errorf("pkg_installed requires a name argument")
+			}
+			return executePkgInstalled(name)
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "whoami",
+			Description: "Get the current user's uid, groups, whether they are root or can use sudo, and their umask",
+			Parameters:  noParams,
+		},
+		summary: "whoami: Current user, groups, sudo access, and umask",
+		access:  ReadOnly,
+		run: func(map[string]any) (string, error) {
+			return executeWhoami()
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "sensors",
+			Description: "Get battery charge, whether AC power is connected, and CPU temperatures where available, with the source of each reading",
+			Parameters:  noParams,
+		},
+		summary: "sensors: Battery percentage, AC state, and CPU temperature",
+		access:  ReadOnly,
+		run: func(map[string]any) (string, error) {
+			return executeSensors()
+		},
+	},
+	{
+		decl: &genai.FunctionDeclaration{
+			Name:        "uptime",
+			Description: "Get system uptime information",
+			Parameters:  noParams,
+		},
+		summary: "uptime: Get system uptime",
+		access:  ReadOnly,
+		run: func(map[string]any) (string, error) {
+			return executeUptime()
+		},
+	},
+}
+
+// Registry holds all available tools and provides dispatch functionality.
+type Registry struct {
+	enabled bool
+	// readOnly limits the registry to ReadOnly tools
+	readOnly bool
+	// plugins are the external tools added by LoadPlugins
+	plugins []tool
+	// allowPaths, when non-nil, are the only paths tools may read (see AllowPaths)
+	allowPaths []string
+	// ignore hides .gxignore paths from tools (see Ignore)
+	ignore *ignore.Matcher
+}
+
+// NewRegistry creates a new tool registry. With readOnly set, only tools
+// that inspect local state are offered; mutating and network tools are left out.
+func NewRegistry(enabled, readOnly bool) *Registry {
+	return &Registry{enabled: enabled, readOnly: readOnly}
+}
+
+// IsEnabled returns whether tools are enabled.
+func (r *Registry) IsEnabled() bool {
+	return r.enabled
+}
+
+// IsReadOnly returns whether only read-only tools are offered.
+func (r *Registry) IsReadOnly() bool {
+	return r.readOnly
+}
+
+// all returns the built-in tools followed by any plugins.
+func (r *Registry) all() []tool {
+	return append(builtinTools[:len(builtinTools):len(builtinTools)], r.plugins...)
+}
+
+// available returns the tools offered to the model.
+func (r *Registry) available() []tool {
+	if !r.enabled {
+		return nil
 	}
+	var available []tool
+	for _, t := range r.all() {
+		if r.readOnly && t.access != ReadOnly {
+			continue
+		}
+		available = append(available, t)
+	}
+	return available
+}
+
+// GetToolDefinitions returns the Gemini tool definitions for all available tools.
+func (r *Registry) GetToolDefinitions() []*genai.Tool {
+	available := r.available()
+	if len(available) == 0 {
+		return nil
+	}
+
+	decls := make([]*genai.FunctionDeclaration, len(available))
+	for i, t := range available {
+		decls[i] = t.decl
+	}
+	return []*genai.Tool{{FunctionDeclarations: decls}}
+}
+
+// Descriptions returns a one-line summary of each available tool, such as
+// "cat(path): Read file contents", for the system instruction.
+func (r *Registry) Descriptions() []string {
+	var descs []string
+	for _, t := range r.available() {
+		descs = append(descs, t.summary)
+	}
+	return descs
+}
+
+// Definitions returns the declarations offered to the model as indented
+// JSON, with parameters in JSON Schema form, for debugging (gx -p).
+func (r *Registry) Definitions() (string, error) {
+	type definition struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Access      string         `json:"access"`
+		Parameters  map[string]any `json:"parameters"`
+	}
+	defs := []definition{}
+	for _, t := range r.available() {
+		defs = append(defs, definition{
+			Name:        t.decl.Name,
+			Description: t.decl.Description,
+			Access:      t.access.String(),
+			Parameters:  jsonSchema(t.decl.Parameters),
+		})
+	}
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode tool definitions: %w", err)
+	}
+	return string(data), nil
+}
+
+// jsonSchema converts a Gemini schema back to JSON Schema, the form plugin
+// manifests use.
+func jsonSchema(s *genai.Schema) map[string]any {
+	if s == nil {
+		return nil
+	}
+	m := map[string]any{}
+	for name, t := range schemaTypes {
+		if t == s.Type {
+			m["type"] = name
+		}
+	}
+	if s.Description != "" {
+		m["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		m["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		m["items"] = jsonSchema(s.Items)
+	}
+	if s.Type == genai.TypeObject {
+		props := map[string]any{}
+		for name, p := range s.Properties {
+			props[name] = jsonSchema(p)
+		}
+		m["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		m["required"] = s.Required
+	}
+	return m
 }
 
 // ExecuteTool executes a tool by name with the given arguments.
@@ -103,35 +682,23 @@ func (r *Registry) ExecuteTool(name string, args map[string]any) (string, error)
 		return "", fmt.Errorf("tools are disabled")
 	}
 
-	switch name {
-	case "pwd":
-		return executePwd()
-	case "ls":
-		path, _ := args["path"].(string)
-		if path == "" {
-			path = "."
+	for _, t := range r.available() {
+		if t.decl.Name == name {
+			if err := r.checkPaths(t, args); err != nil {
+				return "", err
+			}
+			if t.walk != nil {
+				return t.walk(args, r.ignored)
+			}
+			return t.run(args)
 		}
-		recursive, _ := args["recursive"].(bool)
-		return executeLs(path, recursive)
-	case "stat":
-		path, ok := args["path"].(string)
-		if !ok || path == "" {
-			return "", fmt.Errorf("stat requires a path argument")
-		}
-		return executeStat(path)
-	case "cat":
-		path, ok := args["path"].(string)
-		if !ok || path == "" {
-			return "", fmt.Errorf("cat requires a path argument")
+	}
+	for _, t := range r.all() {
+		if t.decl.Name == name {
+			return "", fmt.Errorf("tool %s is not available in read-only mode", name)
 		}
-		return executeCat(path)
-	case "ps":
-		return executePs()
-	case "uptime":
-		return executeUptime()
-	default:
-		return "", fmt.Errorf("unknown tool: %s", name)
 	}
+	return "", fmt.Errorf("unknown tool: %s", name)
 }
 
 // ParseFunctionCall extracts the function name and arguments from a FunctionCall.
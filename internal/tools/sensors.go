@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Linux sysfs roots for power supplies and temperature sensors.
+const (
+	powerSupplyDir = "/sys/class/power_supply"
+	thermalDir     = "/sys/class/thermal"
+	hwmonDir       = "/sys/class/hwmon"
+)
+
+// executeSensors reports battery charge, whether AC power is connected, and
+// CPU temperatures where the platform exposes them, noting where each value
+// came from so the model can build monitoring commands on the same source.
+func executeSensors() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		return sensorsWindows()
+	case "darwin":
+		return sensorsDarwin()
+	}
+	return sensorsLinux()
+}
+
+func sensorsLinux() (string, error) {
+	var power, temps []string
+
+	supplies, _ := filepath.Glob(filepath.Join(powerSupplyDir, "*"))
+	for _, dir := range supplies {
+		name := filepath.Base(dir)
+		switch readSysfs(dir, "type") {
+		case "Battery":
+			line := fmt.Sprintf("Battery %s: %s%%", name, readSysfs(dir, "capacity"))
+			if status := readSysfs(dir, "status"); status != "" {
+				line += ", " + status
+			}
+			power = append(power, line+fmt.Sprintf(" (%s/capacity, status)", dir))
+		case "Mains", "USB":
+			if online := readSysfs(dir, "online"); online != "" {
+				state := "disconnected"
+				if online == "1" {
+					state = "connected"
+				}
+				power = append(power, fmt.Sprintf("AC adapter %s: %s (%s/online)", name, state, dir))
+			}
+		}
+	}
+
+	// hwmon names the chip (coretemp, k10temp, acpitz); thermal zones are the fallback
+	chips, _ := filepath.Glob(filepath.Join(hwmonDir, "hwmon*"))
+	for _, dir := range chips {
+		chip := readSysfs(dir, "name")
+		inputs, _ := filepath.Glob(filepath.Join(dir, "temp*_input"))
+		for _, input := range inputs {
+			label := readSysfs(dir, strings.Replace(filepath.Base(input), "_input", "_label", 1))
+			if label == "" {
+				label = strings.TrimSuffix(filepath.Base(input), "_input")
+			}
+			if c, ok := milliCelsius(readSysfs(input, "")); ok {
+				temps = append(temps, fmt.Sprintf("%s %s: %.1f°C (%s)", chip, label, c, input))
+			}
+		}
+	}
+	if len(temps) == 0 {
+		zones, _ := filepath.Glob(filepath.Join(thermalDir, "thermal_zone*"))
+		for _, dir := range zones {
+			if c, ok := milliCelsius(readSysfs(dir, "temp")); ok {
+				temps = append(temps, fmt.Sprintf("%s: %.1f°C (%s/temp)", readSysfs(dir, "type"), c, dir))
+			}
+		}
+	}
+
+	return formatSensors(power, temps, "no battery or AC adapter found (desktop or VM?)", "no temperature sensors exposed (VMs and containers usually have none)"), nil
+}
+
+func sensorsDarwin() (string, error) {
+	var power []string
+	output, err := exec.Command("pmset", "-g", "batt").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to query pmset: %w", err)
+	}
+	// Now drawing from 'AC Power'
+	//  -InternalBattery-0 (id=...)	85%; charging; 1:02 remaining present: true
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-")); line != "" {
+			power = append(power, line)
+		}
+	}
+	if len(power) > 0 {
+		power[len(power)-1] += " (pmset -g batt)"
+	}
+	return formatSensors(power, nil, "", "CPU temperature requires sudo powermetrics --samplers smc"), nil
+}
+
+func sensorsWindows() (string, error) {
+	script := "Get-CimInstance Win32_Battery | ForEach-Object { \"Battery: $($_.EstimatedChargeRemaining)%, \" + " +
+		"$(if ($_.BatteryStatus -eq 2) { 'AC connected' } else { 'on battery' }) }"
+	output, err := exec.Command("powershell", "-Command", script).Output()
+	if err != nil && len(output) == 0 {
+		return "", fmt.Errorf("failed to query Win32_Battery: %w", err)
+	}
+	var power []string
+	if text := strings.TrimSpace(string(output)); text != "" {
+		power = strings.Split(text, "\n")
+		power[len(power)-1] += " (Win32_Battery)"
+	}
+
+	// ACPI thermal zones need an elevated session and are absent on many machines
+	var temps []string
+	script = "Get-CimInstance -Namespace root/wmi MSAcpi_ThermalZoneTemperature -ErrorAction SilentlyContinue | " +
+		"ForEach-Object { \"$($_.InstanceName): $([math]::Round($_.CurrentTemperature / 10 - 273.15, 1))°C\" }"
+	if output, err := exec.Command("powershell", "-Command", script).Output(); err == nil {
+		if text := strings.TrimSpace(string(output)); text != "" {
+			temps = strings.Split(text, "\n")
+		}
+	}
+	return formatSensors(power, temps, "no battery found (desktop?)", "no thermal zones readable (needs an elevated session, and many machines don't expose them)"), nil
+}
+
+// formatSensors renders the power and temperature sections, explaining
+// why a section is empty.
+func formatSensors(power, temps []string, noPower, noTemps string) string {
+	var b strings.Builder
+	b.WriteString("Power:\n")
+	if len(power) == 0 {
+		power = []string{noPower}
+	}
+	for _, line := range power {
+		fmt.Fprintf(&b, "  %s\n", strings.TrimSpace(line))
+	}
+	b.WriteString("Temperatures:\n")
+	if len(temps) == 0 {
+		temps = []string{noTemps}
+	}
+	for _, line := range temps {
+		fmt.Fprintf(&b, "  %s\n", strings.TrimSpace(line))
+	}
+	return truncateLines(strings.TrimSpace(b.String()), maxProcessOutput)
+}
+
+// readSysfs reads a sysfs attribute (dir/name, or dir itself when name is
+// empty), returning "" when it is missing or unreadable.
+func readSysfs(dir, name string) string {
+	path := dir
+	if name != "" {
+		path = filepath.Join(dir, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// milliCelsius converts a sysfs temperature reading, rejecting the bogus
+// values some sensors report when disconnected.
+func milliCelsius(value string) (float64, bool) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= -40000 || n >= 150000 {
+		return 0, false
+	}
+	return float64(n) / 1000, true
+}
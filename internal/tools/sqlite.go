@@ -0,0 +1,326 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// sqliteMagic starts every SQLite 3 database file.
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// B-tree page types (https://www.sqlite.org/fileformat.html#b_tree_pages).
+const (
+	sqliteInteriorTable = 0x05
+	sqliteLeafTable     = 0x0d
+)
+
+// maxSQLiteRecord is SQLite's default limit on a string or blob
+// (SQLITE_MAX_LENGTH); larger record sizes mean a corrupt file.
+const maxSQLiteRecord = 1_000_000_000
+
+// sqliteObject is one row of sqlite_master.
+type sqliteObject struct {
+	kind, name, sql string
+}
+
+// executeSQLiteSchema lists the tables, indexes, views, and triggers of a
+// SQLite database as their CREATE statements. The schema table is read
+// directly from the file, so no sqlite3 binary or driver is needed and the
+// database is never opened for writing.
+func executeSQLiteSchema(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open database: %w", err)
+	}
+	defer f.Close()
+
+	db := &sqliteFile{f: f}
+	if err := db.readHeader(); err != nil {
+		return "", err
+	}
+	objects, err := db.schema()
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	groups := map[string][]string{}
+	for _, o := range objects {
+		// Internal tables and automatic indexes (which have no SQL) are noise
+		if strings.HasPrefix(o.name, "sqlite_") || o.sql == "" {
+			continue
+		}
+		groups[o.kind] = append(groups[o.kind], strings.TrimSpace(o.sql)+";")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SQLite database, %d tables, %d-byte pages\n", len(groups["table"]), db.pageSize)
+	if info, err := os.Stat(path + "-wal"); err == nil && info.Size() > 0 {
+		fmt.Fprintf(&b, "Note: %s-wal has changes not yet checkpointed; recent schema changes may be missing\n", path)
+	}
+	for _, kind := range [][2]string{{"table", "Tables"}, {"view", "Views"}, {"index", "Indexes"}, {"trigger", "Triggers"}} {
+		if len(groups[kind[0]]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n-- %s\n%s\n", kind[1], strings.Join(groups[kind[0]], "\n"))
+	}
+	if len(groups) == 0 {
+		b.WriteString("(empty database)\n")
+	}
+	return truncateLines(b.String(), maxProcessOutput), nil
+}
+
+// sqliteFile reads pages of a SQLite database file.
+type sqliteFile struct {
+	f        *os.File
+	pageSize int
+	usable   int // page size minus reserved bytes
+	encoding byte
+	visited  map[uint32]bool
+}
+
+func (db *sqliteFile) readHeader() error {
+	header := make([]byte, 100)
+	if _, err := db.f.ReadAt(header, 0); err != nil || !bytes.HasPrefix(header, sqliteMagic) {
+		return fmt.Errorf("%s is not a SQLite 3 database", db.f.Name())
+	}
+	db.pageSize = int(binary.BigEndian.Uint16(header[16:18]))
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	db.usable = db.pageSize - int(header[20])
+	db.encoding = header[59]
+	if db.pageSize < 512 || db.usable < 480 {
+		return fmt.Errorf("%s has an invalid page size", db.f.Name())
+	}
+	return nil
+}
+
+func (db *sqliteFile) page(n uint32) ([]byte, error) {
+	if n == 0 {
+		return nil, fmt.Errorf("invalid page number 0")
+	}
+	buf := make([]byte, db.pageSize)
+	if _, err := db.f.ReadAt(buf, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", n, err)
+	}
+	return buf, nil
+}
+
+// schema reads sqlite_master, the table b-tree rooted at page 1.
+func (db *sqliteFile) schema() ([]sqliteObject, error) {
+	db.visited = map[uint32]bool{}
+	var objects []sqliteObject
+	err := db.walk(1, func(record []any) {
+		if len(record) < 5 {
+			return
+		}
+		kind, _ := record[0].(string)
+		name, _ := record[1].(string)
+		sql, _ := record[4].(string)
+		objects = append(objects, sqliteObject{kind: kind, name: name, sql: sql})
+	})
+	return objects, err
+}
+
+// walk visits every record in the table b-tree rooted at page n.
+func (db *sqliteFile) walk(n uint32, visit func([]any)) error {
+	if db.visited[n] {
+		return fmt.Errorf("page %d is referenced twice", n)
+	}
+	db.visited[n] = true
+
+	page, err := db.page(n)
+	if err != nil {
+		return err
+	}
+	offset := 0
+	if n == 1 {
+		offset = 100 // Page 1 starts with the file header
+	}
+	kind := page[offset]
+	cells := int(binary.BigEndian.Uint16(page[offset+3:]))
+	headerSize := 8
+	if kind == sqliteInteriorTable {
+		headerSize = 12
+	} else if kind != sqliteLeafTable {
+		return fmt.Errorf("page %d is not a table b-tree page", n)
+	}
+
+	pointers := page[offset+headerSize:]
+	if len(pointers) < cells*2 {
+		return fmt.Errorf("page %d has too many cells", n)
+	}
+	for i := 0; i < cells; i++ {
+		cell := int(binary.BigEndian.Uint16(pointers[i*2:]))
+		if cell >= len(page) {
+			return fmt.Errorf("page %d has a cell out of range", n)
+		}
+		if kind == sqliteInteriorTable {
+			if cell+4 > len(page) {
+				return fmt.Errorf("page %d has a cell out of range", n)
+			}
+			if err := db.walk(binary.BigEndian.Uint32(page[cell:]), visit); err != nil {
+				return err
+			}
+			continue
+		}
+		payload, err := db.payload(page[cell:])
+		if err != nil {
+			return fmt.Errorf("page %d: %w", n, err)
+		}
+		record, err := db.record(payload)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", n, err)
+		}
+		visit(record)
+	}
+	if kind == sqliteInteriorTable {
+		return db.walk(binary.BigEndian.Uint32(page[offset+8:]), visit)
+	}
+	return nil
+}
+
+// payload returns a leaf cell's record, following overflow pages for
+// records that don't fit on the page (long CREATE statements).
+func (db *sqliteFile) payload(cell []byte) ([]byte, error) {
+	size, n := sqliteVarint(cell)
+	cell = cell[n:]
+	_, n = sqliteVarint(cell) // rowid
+	cell = cell[n:]
+
+	// A corrupt size would overflow int; nothing in a schema comes close
+	if size > maxSQLiteRecord {
+		return nil, fmt.Errorf("record too large (%d bytes)", size)
+	}
+
+	// Local payload size, per the file format's overflow rules
+	total := int(size)
+	maxLocal := db.usable - 35
+	local := total
+	if total > maxLocal {
+		minLocal := (db.usable-12)*32/255 - 23
+		local = minLocal + (total-minLocal)%(db.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if local > len(cell) {
+		return nil, fmt.Errorf("record extends past the page")
+	}
+	payload := append([]byte(nil), cell[:local]...)
+	if local == total {
+		return payload, nil
+	}
+
+	if local+4 > len(cell) {
+		return nil, fmt.Errorf("record extends past the page")
+	}
+	next := binary.BigEndian.Uint32(cell[local:])
+	for len(payload) < total {
+		if next == 0 || db.visited[next] {
+			return nil, fmt.Errorf("broken overflow chain")
+		}
+		db.visited[next] = true
+		page, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+		next = binary.BigEndian.Uint32(page)
+		chunk := page[4:db.usable]
+		payload = append(payload, chunk[:min(len(chunk), total-len(payload))]...)
+	}
+	return payload, nil
+}
+
+// record decodes a record's columns into int64, float64, string, []byte, or nil.
+func (db *sqliteFile) record(payload []byte) ([]any, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(payload)) {
+		return nil, fmt.Errorf("invalid record header")
+	}
+	header := payload[n:headerSize]
+	body := payload[headerSize:]
+
+	var values []any
+	for len(header) > 0 {
+		serial, n := sqliteVarint(header)
+		header = header[n:]
+
+		// Sizes stay unsigned until checked, since a corrupt serial type
+		// can be any 64-bit value
+		var size uint64
+		switch {
+		case serial >= 12:
+			size = (serial - 12) / 2
+		case serial >= 1 && serial <= 4:
+			size = serial
+		case serial == 5:
+			size = 6
+		case serial == 6 || serial == 7:
+			size = 8
+		}
+		if size > uint64(len(body)) {
+			return nil, fmt.Errorf("record extends past its payload")
+		}
+		data := body[:size]
+		body = body[size:]
+
+		switch {
+		case serial == 0:
+			values = append(values, nil)
+		case serial == 8, serial == 9:
+			values = append(values, int64(serial-8))
+		case serial == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(data)))
+		case serial <= 6:
+			// Big-endian two's complement of 1-8 bytes
+			v := int64(int8(data[0]))
+			for _, b := range data[1:] {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case serial%2 == 1:
+			values = append(values, db.text(data))
+		default:
+			values = append(values, data)
+		}
+	}
+	return values, nil
+}
+
+// text decodes a string in the database's text encoding.
+func (db *sqliteFile) text(data []byte) string {
+	if db.encoding != 2 && db.encoding != 3 {
+		return string(data)
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if db.encoding == 2 {
+			units[i] = binary.LittleEndian.Uint16(data[i*2:])
+		} else {
+			units[i] = binary.BigEndian.Uint16(data[i*2:])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// sqliteVarint decodes SQLite's big-endian variable-length integer,
+// returning the value and the number of bytes read.
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 9; i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
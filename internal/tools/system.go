@@ -0,0 +1,342 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// executeService reports the status of a service using the platform's init
+// system, naming the init system on the first line so the model knows which
+// commands manage it.
+func executeService(name string) (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		script := fmt.Sprintf("Get-Service -Name '%s','*%s*' -ErrorAction SilentlyContinue | Sort-Object -Unique Name | "+
+			"Select-Object Name, Status, StartType, DisplayName | Format-Table -AutoSize | Out-String -Width 200", psQuote(name), psQuote(name))
+		return serviceOutput("Windows Service Control Manager", name, exec.Command("powershell", "-Command", script))
+	case "darwin":
+		output, err := exec.Command("launchctl", "list").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to execute launchctl: %w", err)
+		}
+		lines := strings.Split(string(output), "\n")
+		matches := []string{lines[0]} // PID, Status, Label header
+		for _, line := range lines[1:] {
+			if strings.Contains(strings.ToLower(line), strings.ToLower(name)) {
+				matches = append(matches, line)
+			}
+		}
+		if len(matches) == 1 {
+			return fmt.Sprintf("Init system: launchd\nNo launchd jobs matching %q (system daemons need sudo launchctl list)", name), nil
+		}
+		return "Init system: launchd\n" + truncateLines(strings.Join(matches, "\n"), maxProcessOutput), nil
+	}
+
+	// Like sd_booted(3): systemctl is often installed in containers without systemd running
+	if _, err := os.Stat("/run/systemd/system"); err == nil {
+		status, err := serviceOutput("systemd", name, exec.Command("systemctl", "status", "--no-pager", "--lines=5", "--", name))
+		if err != nil {
+			return "", err
+		}
+		// is-enabled exits non-zero for disabled units but still prints the state
+		if enabled, _ := exec.Command("systemctl", "is-enabled", "--", name).Output(); len(enabled) > 0 {
+			status += "\nEnabled: " + strings.TrimSpace(string(enabled))
+		}
+		return status, nil
+	}
+	if _, err := exec.LookPath("rc-service"); err == nil {
+		return serviceOutput("OpenRC", name, exec.Command("rc-service", name, "status"))
+	}
+	if _, err := exec.LookPath("service"); err == nil {
+		return serviceOutput("SysV init", name, exec.Command("service", name, "status"))
+	}
+	return "", fmt.Errorf("no supported init system found (systemctl, rc-service, service)")
+}
+
+// serviceOutput runs a status query. Status commands exit non-zero for
+// stopped or failed services, so output is returned whenever there is any.
+func serviceOutput(initSystem, name string, cmd *exec.Cmd) (string, error) {
+	output, err := cmd.CombinedOutput()
+	text := strings.TrimSpace(string(output))
+	if text == "" {
+		var exitErr *exec.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return "", fmt.Errorf("failed to query %s: %w", initSystem, err)
+		}
+		text = fmt.Sprintf("No service matching %q", name)
+	}
+	return fmt.Sprintf("Init system: %s\n%s", initSystem, truncateLines(text, maxProcessOutput)), nil
+}
+
+// psQuote escapes s for a single-quoted PowerShell string.
+func psQuote(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
+
+// executeMounts lists mounted filesystems with their types and usage, plus
+// the block devices (including unmounted ones, such as a freshly plugged-in
+// USB stick) where the platform can list them.
+func executeMounts() (string, error) {
+	type section struct {
+		title string
+		cmd   *exec.Cmd
+	}
+	var sections []section
+	switch runtime.GOOS {
+	case "windows":
+		sections = []section{{"Volumes", exec.Command("powershell", "-Command",
+			"Get-Volume | Where-Object DriveLetter | Select-Object DriveLetter, FileSystemLabel, FileSystem, DriveType, HealthStatus, "+
+				"@{n='SizeGB';e={[math]::Round($_.Size/1GB,1)}}, @{n='FreeGB';e={[math]::Round($_.SizeRemaining/1GB,1)}} | "+
+				"Format-Table -AutoSize | Out-String -Width 200")}}
+	case "darwin":
+		sections = []section{
+			{"Mounted filesystems", exec.Command("df", "-h")},
+			{"Disks", exec.Command("diskutil", "list")},
+		}
+	default:
+		// Pseudo filesystems only add noise
+		sections = []section{
+			{"Mounted filesystems", exec.Command("df", "-hT", "-x", "tmpfs", "-x", "devtmpfs", "-x", "squashfs", "-x", "overlay")},
+			{"Block devices", exec.Command("lsblk", "-o", "NAME,SIZE,TYPE,FSTYPE,MOUNTPOINT,LABEL,MODEL,TRAN")},
+		}
+	}
+
+	var result []string
+	for _, s := range sections {
+		output, err := s.cmd.Output()
+		if err != nil && len(output) == 0 {
+			// lsblk and diskutil are optional; df alone still answers most questions
+			if len(result) > 0 {
+				continue
+			}
+			return "", fmt.Errorf("failed to list filesystems: %w", err)
+		}
+		result = append(result, s.title+":\n"+strings.TrimSpace(string(output)))
+	}
+	return truncateLines(strings.Join(result, "\n\n"), maxProcessOutput), nil
+}
+
+// executeCrontab returns the current user's crontab, or their Task
+// Scheduler tasks on Windows. Installing a crontab replaces the whole table,
+// so the model needs the existing entries to add or change one safely.
+func executeCrontab() (string, error) {
+	if runtime.GOOS == "windows" {
+		output, err := exec.Command("powershell", "-Command", "Get-ScheduledTask -TaskPath '\\' -ErrorAction SilentlyContinue | "+
+			"Select-Object TaskName, State, @{n='Action';e={($_.Actions | ForEach-Object { \"$($_.Execute) $($_.Arguments)\" }) -join '; '}} | "+
+			"Format-Table -AutoSize -Wrap | Out-String -Width 200").Output()
+		if err != nil && len(output) == 0 {
+			return "", fmt.Errorf("failed to list scheduled tasks: %w", err)
+		}
+		if strings.TrimSpace(string(output)) == "" {
+			return "Task Scheduler: no tasks in the root folder", nil
+		}
+		return "Task Scheduler (root folder):\n" + truncateLines(strings.TrimSpace(string(output)), maxProcessOutput), nil
+	}
+
+	if _, err := exec.LookPath("crontab"); err != nil {
+		return "", fmt.Errorf("crontab is not installed")
+	}
+	output, err := exec.Command("crontab", "-l").CombinedOutput()
+	text := strings.TrimSpace(string(output))
+	if err != nil {
+		// crontab -l exits 1 with "no crontab for <user>" when there isn't one
+		if strings.Contains(strings.ToLower(text), "no crontab") {
+			return "No crontab installed for the current user", nil
+		}
+		if text == "" {
+			return "", fmt.Errorf("failed to read crontab: %w", err)
+		}
+		return "", fmt.Errorf("failed to read crontab: %s", text)
+	}
+	if text == "" {
+		return "Crontab is empty", nil
+	}
+	return "Current crontab (crontab -l):\n" + truncateLines(text, maxProcessOutput), nil
+}
+
+// sudoGroups grant sudo (or admin) rights on common distributions and macOS.
+var sudoGroups = map[string]bool{"sudo": true, "wheel": true, "admin": true}
+
+// executeWhoami reports the current user, their groups, whether they can
+// elevate, and the umask, so generated commands use sudo and file modes
+// correctly.
+func executeWhoami() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to look up current user: %w", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		output, err := exec.Command("powershell", "-Command",
+			"([Security.Principal.WindowsPrincipal][Security.Principal.WindowsIdentity]::GetCurrent()).IsInRole([Security.Principal.WindowsBuiltInRole]::Administrator)").Output()
+		elevated := "unknown"
+		if err == nil {
+			elevated = strings.ToLower(strings.TrimSpace(string(output)))
+		}
+		return fmt.Sprintf("User: %s\nSID: %s\nHome: %s\nElevated (Administrator): %s", u.Username, u.Uid, u.HomeDir, elevated), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "User: %s (uid=%s, gid=%s)\nHome: %s\n", u.Username, u.Uid, u.Gid, u.HomeDir)
+
+	var groups []string
+	canSudo := os.Geteuid() == 0
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			name := id
+			if g, err := user.LookupGroupId(id); err == nil {
+				name = g.Name
+			}
+			groups = append(groups, fmt.Sprintf("%s(%s)", name, id))
+			canSudo = canSudo || sudoGroups[name]
+		}
+	}
+	fmt.Fprintf(&b, "Groups: %s\n", strings.Join(groups, " "))
+
+	switch {
+	case os.Geteuid() == 0:
+		b.WriteString("Root: yes (sudo not needed)\n")
+	case canSudo:
+		b.WriteString("Sudo: yes (member of an admin group)\n")
+	default:
+		b.WriteString("Sudo: not via group membership (sudoers may still grant it)\n")
+	}
+	if umask := currentUmask(); umask != "" {
+		fmt.Fprintf(&b, "Umask: %s\n", umask)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// currentUmask reads the umask without changing it: from /proc on Linux,
+// otherwise from a shell, which inherits it.
+func currentUmask() string {
+	if status, err := os.ReadFile("/proc/self/status"); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			if value, ok := strings.CutPrefix(line, "Umask:"); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	output, err := exec.Command("sh", "-c", "umask").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Log tool limits: the default and maximum number of lines, and the most
+// output sent to the model (the newest lines are kept).
+const (
+	defaultLogLines = 50
+	maxLogLines     = 500
+	maxLogOutput    = 8000
+)
+
+// syslogFiles are checked in order for the system log when there is no journal.
+var syslogFiles = []string{"/var/log/syslog", "/var/log/messages", "/var/log/system.log"}
+
+// executeLogs returns the last lines of a service's log (journalctl, the
+// macOS unified log, or the Windows Event Log), of a log file, or of the
+// system log when neither unit nor file is given.
+func executeLogs(unit, file string, lines int) (string, error) {
+	if lines <= 0 {
+		lines = defaultLogLines
+	}
+	lines = min(lines, maxLogLines)
+
+	if file != "" {
+		return tailFile(file, lines)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		filter := "LogName='System','Application'"
+		if unit != "" {
+			filter += fmt.Sprintf("; ProviderName='%s'", psQuote(unit))
+		}
+		cmd = exec.Command("powershell", "-Command", fmt.Sprintf("Get-WinEvent -FilterHashtable @{%s} -MaxEvents %d -ErrorAction SilentlyContinue | "+
+			"Sort-Object TimeCreated | Format-Table -AutoSize -Wrap TimeCreated, Id, LevelDisplayName, ProviderName, Message | Out-String -Width 200", filter, lines))
+	case "darwin":
+		args := []string{"show", "--last", "1h", "--style", "compact"}
+		if unit != "" {
+			args = append(args, "--predicate", fmt.Sprintf("process == %q OR subsystem CONTAINS %q", unit, unit))
+		}
+		cmd = exec.Command("log", args...)
+	default:
+		if _, err := os.Stat("/run/systemd/system"); err != nil {
+			if unit != "" {
+				return "", fmt.Errorf("no journal available; pass the service's log file instead")
+			}
+			for _, f := range syslogFiles {
+				if _, err := os.Stat(f); err == nil {
+					return tailFile(f, lines)
+				}
+			}
+			return "", fmt.Errorf("no journal or system log file found")
+		}
+		args := []string{"--no-pager", "-o", "short-iso", "-n", strconv.Itoa(lines)}
+		if unit != "" {
+			args = append(args, "-u", unit)
+		}
+		cmd = exec.Command("journalctl", args...)
+	}
+
+	output, err := cmd.Output()
+	if err != nil && len(output) == 0 {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+	if strings.TrimSpace(string(output)) == "" {
+		return "No log entries found", nil
+	}
+	return lastLines(string(output), lines, maxLogOutput), nil
+}
+
+// tailFile returns the last lines of a file, reading only its end.
+func tailFile(path string, lines int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to access log: %w", err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+
+	// Read about 1KB per requested line from the end, enough for all but very long lines
+	offset := max(info.Size()-int64(lines)*1024, 0)
+	content := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(content, offset); err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read log: %w", err)
+	}
+	return lastLines(string(content), lines, maxLogOutput), nil
+}
+
+// lastLines keeps the last n lines of text, dropping older lines until it
+// fits in maxLen bytes.
+func lastLines(text string, n, maxLen int) string {
+	all := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(all) > n {
+		all = all[len(all)-n:]
+	}
+	size := 0
+	for i := len(all) - 1; i >= 0; i-- {
+		size += len(all[i]) + 1
+		if size > maxLen {
+			return "... (older lines truncated)\n" + strings.Join(all[i+1:], "\n")
+		}
+	}
+	return strings.Join(all, "\n")
+}
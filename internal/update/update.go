@@ -0,0 +1,167 @@
+// Package update tells the user when a newer gx release exists. The
+// releases API is queried at most once a day in the background, and the
+// answer is shown on a later run, so a check never delays a request.
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultStateFile is the default path for the last check's result.
+	DefaultStateFile = ".gxupdate"
+	// releasesURL is the latest gx release on GitHub.
+	releasesURL = "https://api.github.com/repos/nealhardesty/gx/releases/latest"
+	// checkInterval is how often the releases API is queried, and how
+	// often the notice is repeated.
+	checkInterval = 24 * time.Hour
+	// checkTimeout bounds a check so it can't outlive a short run by much.
+	checkTimeout = 3 * time.Second
+)
+
+// state is the result of the last check.
+type state struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// Latest is the newest release's version, without the "v"
+	Latest     string    `json:"latest,omitempty"`
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+// Checker compares the running version with the latest release.
+type Checker struct {
+	path    string
+	current string
+	url     string
+	client  *http.Client
+}
+
+// New creates a checker for the running version.
+func New(current string) (*Checker, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return &Checker{
+		path:    filepath.Join(homeDir, DefaultStateFile),
+		current: current,
+		url:     releasesURL,
+		client:  &http.Client{Timeout: checkTimeout},
+	}, nil
+}
+
+// Notice returns a one-line notice when the last check found a newer
+// release, or "". The notice is shown at most once per check interval.
+func (c *Checker) Notice() string {
+	s := c.load()
+	if s.Latest == "" || !newer(s.Latest, c.current) || time.Since(s.NotifiedAt) < checkInterval {
+		return ""
+	}
+	s.NotifiedAt = time.Now()
+	c.save(s)
+	return fmt.Sprintf("gx %s is available (you have %s): go install github.com/nealhardesty/gx@latest (GX_NO_UPDATE_CHECK=1 hides this)", s.Latest, c.current)
+}
+
+// Refresh queries the releases API when the last check is older than the
+// check interval. Failures, such as being offline, count as a check so they
+// aren't retried on every run.
+func (c *Checker) Refresh(ctx context.Context) error {
+	s := c.load()
+	if time.Since(s.CheckedAt) < checkInterval {
+		return nil
+	}
+	latest, err := c.latest(ctx)
+	s = c.load()
+	s.CheckedAt = time.Now()
+	if err == nil {
+		s.Latest = latest
+	}
+	c.save(s)
+	return err
+}
+
+// latest fetches the newest release's version.
+func (c *Checker) latest(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to check for updates: %s", resp.Status)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to parse release: %w", err)
+	}
+	return strings.TrimPrefix(release.TagName, "v"), nil
+}
+
+// load reads the state file; a missing or corrupt file is a zero state.
+func (c *Checker) load() state {
+	var s state
+	if data, err := os.ReadFile(c.path); err == nil {
+		json.Unmarshal(data, &s)
+	}
+	return s
+}
+
+// save writes the state file, ignoring errors: the worst case is another
+// check or notice.
+func (c *Checker) save(s state) {
+	if data, err := json.Marshal(s); err == nil {
+		os.WriteFile(c.path, data, 0600)
+	}
+}
+
+// newer reports whether version a is newer than b, comparing MAJOR.MINOR.PATCH
+// numerically. Pre-release and build suffixes are ignored; unparsable
+// versions are never newer.
+func newer(a, b string) bool {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] > vb[i]
+		}
+	}
+	return false
+}
+
+// parseVersion splits "v1.2.3-rc1" into [1 2 3].
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,200 @@
+// Package vcr records Vertex AI HTTP traffic to cassette files and replays
+// it, so gx can run end to end without network access or GCP credentials.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cassette is a recorded sequence of API requests and responses.
+//
+// Replay matches requests by method and URL path, in recorded order; bodies
+// are not compared because the system prompt describes the machine (working
+// directory, shell, environment), which differs between where a cassette is
+// recorded and where it is replayed.
+type Cassette struct {
+	// Project and Location are where the cassette was recorded; replays use
+	// them so request paths match without credentials to resolve a project.
+	Project      string        `json:"project"`
+	Location     string        `json:"location"`
+	Interactions []Interaction `json:"interactions"`
+
+	path      string
+	recording bool
+	mu        sync.Mutex
+	next      int
+}
+
+// Interaction is one request and the response it got.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded part of a request. Headers are left out so
+// credentials never end up in a cassette.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// Record starts a new cassette at path, replacing any existing one once the
+// first interaction is saved. The caller fills in Project and Location.
+func Record(path string) *Cassette {
+	return &Cassette{path: path, recording: true}
+}
+
+// Load reads a cassette for replay.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	c := &Cassette{path: path}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Recording reports whether the cassette records live traffic rather than
+// replaying it.
+func (c *Cassette) Recording() bool {
+	return c.recording
+}
+
+// Transport returns a RoundTripper that records through base, or replays
+// without it.
+func (c *Cassette) Transport(base http.RoundTripper) http.RoundTripper {
+	if c.recording {
+		return &recorder{cassette: c, base: base}
+	}
+	return &player{cassette: c}
+}
+
+// recorder forwards requests and saves each exchange to the cassette.
+type recorder struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c := r.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, Interaction{
+		Request:  Request{Method: req.Method, URL: req.URL.String(), Body: reqBody},
+		Response: Response{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: respBody},
+	})
+	// Saved after every exchange, since gx may exit without closing the client
+	if err := c.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// player answers requests from the cassette in order.
+type player struct {
+	cassette *Cassette
+}
+
+func (p *player) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	c := p.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.next >= len(c.Interactions) {
+		return nil, fmt.Errorf("cassette %s has no interaction left for %s %s (re-record it with GX_CASSETTE_MODE=record)", c.path, req.Method, req.URL.Path)
+	}
+	in := c.Interactions[c.next]
+	recorded, err := http.NewRequest(in.Request.Method, in.Request.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cassette %s: invalid recorded request: %w", c.path, err)
+	}
+	if recorded.Method != req.Method || recorded.URL.Path != req.URL.Path {
+		return nil, fmt.Errorf("cassette %s: request %d is %s %s, but gx sent %s %s (re-record it with GX_CASSETTE_MODE=record)",
+			c.path, c.next+1, recorded.Method, recorded.URL.Path, req.Method, req.URL.Path)
+	}
+	c.next++
+
+	header := http.Header{}
+	if in.Response.ContentType != "" {
+		header.Set("Content-Type", in.Response.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads a request or response body and replaces it with a copy, so
+// it can still be sent or returned.
+func readBody(body *io.ReadCloser) (string, error) {
+	if *body == nil || *body == http.NoBody {
+		return "", nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read body: %w", err)
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return string(data), nil
+}
+
+// save writes the cassette, creating its directory (e.g. testdata) as needed.
+// The caller holds c.mu.
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
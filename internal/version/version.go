@@ -1,5 +1,67 @@
 package version
 
+import (
+	"runtime"
+	"runtime/debug"
+)
+
 // Version is the current semantic version of the application.
 // Follow SemVer: MAJOR.MINOR.PATCH
 const Version = "0.2.0"
+
+// Commit and BuildDate are set at link time by `make build`:
+//
+//	-ldflags "-X github.com/nealhardesty/gx/internal/version.Commit=... -X github.com/nealhardesty/gx/internal/version.BuildDate=..."
+//
+// A plain `go build` in a git checkout leaves them empty, and Build falls
+// back to the VCS stamp Go embeds in the binary (with the commit's time as
+// the date).
+var (
+	Commit    string
+	BuildDate string
+)
+
+// Info describes how the running binary was built, for --version and bug
+// reports. Empty fields are unknown (e.g. `go install` from the module proxy
+// has no VCS stamp).
+type Info struct {
+	Commit string
+	// Modified reports uncommitted changes in the build's checkout
+	Modified  bool
+	BuildDate string
+	GoVersion string
+	// Platform is GOOS/GOARCH
+	Platform string
+}
+
+// Build returns the running binary's build information.
+func Build() Info {
+	info := Info{
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+	if len(info.Commit) > 12 {
+		info.Commit = info.Commit[:12]
+	}
+	return info
+}